@@ -8,7 +8,17 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
 	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/metrics"
+	"github.com/playnet-public/gorcon/pkg/trace"
 
 	"github.com/kolide/kit/version"
 	"github.com/seibert-media/golibs/log"
@@ -25,6 +35,8 @@ var (
 	versionInfo = flag.Bool("version", true, "show version info")
 	dbg         = flag.Bool("debug", false, "enable debug mode")
 	sentryDsn   = flag.String("sentryDsn", "", "sentry dsn key")
+	metricsAddr = flag.String("metrics", "", "listen address for serving prometheus metrics on /metrics")
+	traceTarget = flag.String("trace", "", "export traces to stdout or an OTLP/HTTP collector url, e.g. http://localhost:4318/v1/traces")
 )
 
 func main() {
@@ -53,9 +65,59 @@ func main() {
 	logger := log.New(*sentryDsn, *dbg).WithFields(zapFields...)
 	defer logger.Sync()
 
-	ctx := log.WithLogger(context.Background(), logger)
+	ctx, cancel := context.WithCancel(log.WithLogger(context.Background(), logger))
+	defer cancel()
 
 	log.From(ctx).Info("preparing")
 
+	// serving is set if anything got started which keeps running until being signaled
+	var serving bool
+	var wg sync.WaitGroup
+	switch {
+	case *traceTarget == "stdout":
+		trace.Default.SetExporter(trace.NewStdout(os.Stdout))
+	case strings.HasPrefix(*traceTarget, "http"):
+		exp := trace.NewOTLP(*traceTarget, appKey)
+		trace.Default.SetExporter(exp)
+		serving = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Run flushes the remaining spans once ctx gets closed
+			exp.Run(ctx)
+		}()
+	}
+
+	var srv *http.Server
+	if *metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		srv = &http.Server{Addr: *metricsAddr, Handler: mux}
+		serving = true
+		go func() {
+			log.From(ctx).Info("serving metrics", zap.String("addr", *metricsAddr))
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.From(ctx).Error("serving metrics", zap.Error(err))
+			}
+		}()
+	}
+
+	if serving {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+		log.From(ctx).Info("running")
+		log.From(ctx).Info("stopping", zap.String("signal", (<-sig).String()))
+	}
+
+	if srv != nil {
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.From(ctx).Error("stopping metrics", zap.Error(err))
+		}
+		cancelShutdown()
+	}
+	cancel()
+	wg.Wait()
+
 	log.From(ctx).Info("finished")
 }
@@ -0,0 +1,188 @@
+// Package audit records every command sent to rcon along with its issuer, response and latency
+package audit
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/rcon"
+
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// Kinds of issuers sending commands
+const (
+	CLI     = "cli"
+	Token   = "token"
+	Job     = "job"
+	Script  = "script"
+	Bridge  = "bridge"
+	Unknown = "unknown"
+)
+
+// Issuer of a command
+type Issuer struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// String returns kind:name
+func (i Issuer) String() string {
+	return i.Kind + ":" + i.Name
+}
+
+type issuerKey struct{}
+
+// WithIssuer returns a context marking all commands written with it as issued by kind and name
+func WithIssuer(ctx context.Context, kind, name string) context.Context {
+	return context.WithValue(ctx, issuerKey{}, Issuer{kind, name})
+}
+
+// IssuerFrom returns the issuer set on ctx. Authenticated principals are set as Token issuers by auth.WithPrincipal
+func IssuerFrom(ctx context.Context) Issuer {
+	if i, ok := ctx.Value(issuerKey{}).(Issuer); ok {
+		return i
+	}
+	return Issuer{Kind: Unknown}
+}
+
+// Sensitive commands whose arguments are never recorded
+var Sensitive = []string{"rconpassword", "#login", "#exec", "#beserver"}
+
+// Redact arguments of sensitive commands
+func Redact(cmd string) string {
+	name := rcon.CommandName(cmd)
+	for _, s := range Sensitive {
+		if strings.EqualFold(name, s) {
+			if name == strings.TrimSpace(cmd) {
+				return name
+			}
+			return name + " ***"
+		}
+	}
+	return cmd
+}
+
+// Entry is a single command recorded in the audit log
+type Entry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Server    string    `json:"server"`
+	Issuer    Issuer    `json:"issuer"`
+	Request   string    `json:"request"`
+	Key       uint32    `json:"key"`
+	Response  string    `json:"response,omitempty"`
+	LatencyMS int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	// Decision of an auth.Guard about running the command. Empty for commands sent
+	Decision string `json:"decision,omitempty"`
+}
+
+// Decisions of guards about running commands
+const (
+	Allowed = "allowed"
+	Denied  = "denied"
+)
+
+// Store persists entries and allows querying them
+//go:generate counterfeiter -o ../mocks/audit_store.go --fake-name AuditStore . Store
+type Store interface {
+	Append(Entry) error
+	Query(Query) ([]Entry, error)
+}
+
+// Writer is a rcon.Writer recording every command in Store once its response arrived
+// Placing it in front of an auth.Guard also records commands being denied
+type Writer struct {
+	Writer rcon.Writer
+	Store  Store
+	// Server the writer sends commands to
+	Server string
+	// Timeout for waiting on responses before recording the command as unanswered
+	Timeout time.Duration
+}
+
+// NewWriter recording commands sent to server via w in store
+func NewWriter(w rcon.Writer, store Store, server string) *Writer {
+	return &Writer{
+		Writer:  w,
+		Store:   store,
+		Server:  server,
+		Timeout: 10 * time.Second,
+	}
+}
+
+// Write cmd and record it. The returned transmission signals Done only after the command got recorded
+func (w *Writer) Write(ctx context.Context, cmd string) (rcon.Transmission, error) {
+	e := Entry{
+		Timestamp: time.Now().UTC(),
+		Server:    w.Server,
+		Issuer:    IssuerFrom(ctx),
+		Request:   Redact(cmd),
+	}
+	trm, err := w.Writer.Write(ctx, cmd)
+	if err != nil {
+		e.LatencyMS = latency(e.Timestamp)
+		e.Error = err.Error()
+		w.append(ctx, e)
+		return nil, err
+	}
+	e.Key = trm.Key()
+
+	t := &transmission{Transmission: trm, done: make(chan bool, 1)}
+	go func() {
+		select {
+		case ok := <-trm.Done():
+			e.Response = trm.Response()
+			e.LatencyMS = latency(e.Timestamp)
+			w.append(ctx, e)
+			t.done <- ok
+			return
+		case <-time.After(w.Timeout):
+			e.LatencyMS = latency(e.Timestamp)
+			e.Error = "no response"
+			w.append(ctx, e)
+			t.done <- false
+		}
+		// responses arriving within another Timeout are recorded as late, so they do not get lost
+		select {
+		case <-trm.Done():
+			e.Response = trm.Response()
+			e.LatencyMS = latency(e.Timestamp)
+			e.Error = "late response"
+			w.append(ctx, e)
+		case <-time.After(w.Timeout):
+		}
+	}()
+	return t, nil
+}
+
+func (w *Writer) append(ctx context.Context, e Entry) {
+	log.From(ctx).Info("auditing command",
+		zap.String("server", e.Server),
+		zap.String("issuer", e.Issuer.String()),
+		zap.String("request", e.Request),
+		zap.Uint32("key", e.Key),
+		zap.Int64("latency_ms", e.LatencyMS),
+		zap.String("error", e.Error),
+	)
+	if err := w.Store.Append(e); err != nil {
+		log.From(ctx).Error("writing audit log", zap.Error(err))
+	}
+}
+
+func latency(start time.Time) int64 {
+	return int64(time.Since(start) / time.Millisecond)
+}
+
+// transmission forwards the done signal of the wrapped transmission once the command got recorded
+// Commands without response within the Timeout signal false
+type transmission struct {
+	rcon.Transmission
+	done chan bool
+}
+
+func (t *transmission) Done() <-chan bool {
+	return t.done
+}
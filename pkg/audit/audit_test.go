@@ -0,0 +1,140 @@
+package audit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/audit"
+	"github.com/playnet-public/gorcon/pkg/auth"
+	"github.com/playnet-public/gorcon/pkg/mocks"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+func TestAudit(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Audit Suite")
+}
+
+var _ = Describe("Audit", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	Describe("IssuerFrom", func() {
+		It("does return explicit issuers", func() {
+			ctx = audit.WithIssuer(auth.WithPrincipal(ctx, &auth.Principal{Name: "bot"}), audit.Job, "restart")
+			Expect(audit.IssuerFrom(ctx)).To(Equal(audit.Issuer{Kind: audit.Job, Name: "restart"}))
+		})
+		It("does fall back to the principal", func() {
+			ctx = auth.WithPrincipal(ctx, &auth.Principal{Name: "bot"})
+			Expect(audit.IssuerFrom(ctx).String()).To(Equal("token:bot"))
+		})
+		It("does return unknown issuers", func() {
+			Expect(audit.IssuerFrom(ctx).Kind).To(Equal(audit.Unknown))
+		})
+	})
+
+	Describe("Redact", func() {
+		It("does redact sensitive arguments", func() {
+			Expect(audit.Redact("RConPassword secret")).To(Equal("RConPassword ***"))
+			Expect(audit.Redact("#login secret")).To(Equal("#login ***"))
+		})
+		It("does keep commands without arguments", func() {
+			Expect(audit.Redact("rconpassword")).To(Equal("rconpassword"))
+		})
+		It("does not touch other commands", func() {
+			Expect(audit.Redact("say -1 password")).To(Equal("say -1 password"))
+		})
+	})
+
+	Describe("Writer", func() {
+		var (
+			w     *mocks.RconWriter
+			store *mocks.AuditStore
+			a     *audit.Writer
+			trm   *mocks.RconTransmission
+			done  chan bool
+		)
+
+		BeforeEach(func() {
+			w = &mocks.RconWriter{}
+			store = &mocks.AuditStore{}
+			a = audit.NewWriter(w, store, "eu-1")
+			done = make(chan bool)
+			trm = &mocks.RconTransmission{}
+			trm.KeyReturns(7)
+			trm.DoneReturns(done)
+			trm.ResponseReturns("Players on server")
+			w.WriteReturns(trm, nil)
+		})
+
+		It("does record commands once answered", func() {
+			ctx = audit.WithIssuer(ctx, audit.CLI, "alice")
+			t, err := a.Write(ctx, "players")
+			Expect(err).To(BeNil())
+			Expect(store.AppendCallCount()).To(BeZero())
+			done <- true
+			Expect(<-t.Done()).To(BeTrue())
+			Expect(store.AppendCallCount()).To(Equal(1))
+			e := store.AppendArgsForCall(0)
+			Expect(e.Server).To(Equal("eu-1"))
+			Expect(e.Issuer).To(Equal(audit.Issuer{Kind: audit.CLI, Name: "alice"}))
+			Expect(e.Request).To(Equal("players"))
+			Expect(e.Key).To(BeEquivalentTo(7))
+			Expect(e.Response).To(Equal("Players on server"))
+			Expect(e.Error).To(BeEmpty())
+		})
+		It("does pass through the transmission", func() {
+			t, _ := a.Write(ctx, "players")
+			Expect(t.Key()).To(BeEquivalentTo(7))
+			Expect(t.Response()).To(Equal("Players on server"))
+		})
+		It("does record failed writes", func() {
+			w.WriteReturns(nil, errors.New("test"))
+			_, err := a.Write(ctx, "RConPassword secret")
+			Expect(err).NotTo(BeNil())
+			Expect(store.AppendCallCount()).To(Equal(1))
+			e := store.AppendArgsForCall(0)
+			Expect(e.Request).To(Equal("RConPassword ***"))
+			Expect(e.Error).To(Equal("test"))
+		})
+		It("does record unanswered commands", func() {
+			a.Timeout = 10 * time.Millisecond
+			a.Write(ctx, "players")
+			Eventually(store.AppendCallCount).Should(Equal(1))
+			Expect(store.AppendArgsForCall(0).Error).To(Equal("no response"))
+		})
+		It("does signal unanswered commands as failed", func() {
+			a.Timeout = 10 * time.Millisecond
+			trm, err := a.Write(ctx, "players")
+			Expect(err).To(BeNil())
+			Eventually(trm.Done()).Should(Receive(BeFalse()))
+		})
+		It("does record late responses", func() {
+			a.Timeout = 50 * time.Millisecond
+			a.Write(ctx, "players")
+			Eventually(store.AppendCallCount).Should(Equal(1))
+			done <- true
+			Eventually(store.AppendCallCount).Should(Equal(2))
+			e := store.AppendArgsForCall(1)
+			Expect(e.Error).To(Equal("late response"))
+			Expect(e.Response).To(Equal("Players on server"))
+		})
+		It("does record denied commands in front of a guard", func() {
+			g := auth.NewGuard(w, auth.NewRBAC(), "eu-1")
+			a.Writer = g
+			ctx = auth.WithPrincipal(ctx, &auth.Principal{Name: "bot"})
+			_, err := a.Write(ctx, "#shutdown")
+			Expect(errors.Cause(err)).To(Equal(auth.ErrForbidden))
+			e := store.AppendArgsForCall(0)
+			Expect(e.Issuer.String()).To(Equal("token:bot"))
+			Expect(e.Error).To(ContainSubstring("forbidden"))
+		})
+	})
+})
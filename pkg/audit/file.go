@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Query selects entries from a Store. Zero values match everything
+type Query struct {
+	Since  time.Time
+	Until  time.Time
+	Server string
+	// Issuer matches the issuer name or kind:name
+	Issuer string
+	// Command matches the name of the command case insensitive
+	Command string
+	// Limit returns only the latest Limit entries
+	Limit int
+}
+
+// Match reports whether e is selected by q
+func (q Query) Match(e Entry) bool {
+	switch {
+	case !q.Since.IsZero() && e.Timestamp.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && !e.Timestamp.Before(q.Until):
+		return false
+	case q.Server != "" && e.Server != q.Server:
+		return false
+	case q.Issuer != "" && e.Issuer.Name != q.Issuer && e.Issuer.String() != q.Issuer:
+		return false
+	case q.Command != "" && !strings.EqualFold(strings.SplitN(e.Request, " ", 2)[0], q.Command):
+		return false
+	}
+	return true
+}
+
+// File is an append only Store writing one json encoded entry per line
+type File struct {
+	Path string
+
+	m sync.Mutex
+	f *os.File
+}
+
+// OpenFile store at path creating it if necessary
+func OpenFile(path string) (*File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening audit log")
+	}
+	return &File{Path: path, f: f}, nil
+}
+
+// Append e to the end of the file
+func (f *File) Append(e Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "encoding entry")
+	}
+	f.m.Lock()
+	defer f.m.Unlock()
+	if _, err := f.f.Write(append(b, '\n')); err != nil {
+		return errors.Wrap(err, "writing entry")
+	}
+	return nil
+}
+
+// Query the file for entries matching q in the order they got appended
+func (f *File) Query(q Query) ([]Entry, error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	r, err := os.Open(f.Path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening audit log")
+	}
+	defer r.Close()
+
+	var entries []Entry
+	scn := bufio.NewScanner(r)
+	scn.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scn.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scn.Bytes(), &e); err != nil {
+			return nil, errors.Wrap(err, "decoding entry")
+		}
+		if !q.Match(e) {
+			continue
+		}
+		entries = append(entries, e)
+		if q.Limit > 0 && len(entries) > q.Limit {
+			entries = entries[1:]
+		}
+	}
+	if err := scn.Err(); err != nil {
+		return nil, errors.Wrap(err, "reading audit log")
+	}
+	return entries, nil
+}
+
+// Close the file
+func (f *File) Close() error {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return f.f.Close()
+}
@@ -0,0 +1,83 @@
+package audit_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/audit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("File", func() {
+	var (
+		dir string
+		f   *audit.File
+		t0  time.Time
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "audit")
+		Expect(err).To(BeNil())
+		f, err = audit.OpenFile(filepath.Join(dir, "audit.log"))
+		Expect(err).To(BeNil())
+		t0 = time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC)
+		for i, e := range []audit.Entry{
+			{Timestamp: t0, Server: "eu-1", Issuer: audit.Issuer{Kind: audit.CLI, Name: "alice"}, Request: "players"},
+			{Timestamp: t0.Add(time.Minute), Server: "eu-2", Issuer: audit.Issuer{Kind: audit.Token, Name: "bot"}, Request: "say -1 hi"},
+			{Timestamp: t0.Add(2 * time.Minute), Server: "eu-1", Issuer: audit.Issuer{Kind: audit.Token, Name: "bot"}, Request: "kick 1"},
+		} {
+			e.Key = uint32(i)
+			Expect(f.Append(e)).To(BeNil())
+		}
+	})
+
+	AfterEach(func() {
+		f.Close()
+		os.RemoveAll(dir)
+	})
+
+	It("does return all entries in order", func() {
+		entries, err := f.Query(audit.Query{})
+		Expect(err).To(BeNil())
+		Expect(entries).To(HaveLen(3))
+		Expect(entries[2].Request).To(Equal("kick 1"))
+	})
+	It("does filter by server", func() {
+		entries, _ := f.Query(audit.Query{Server: "eu-1"})
+		Expect(entries).To(HaveLen(2))
+	})
+	It("does filter by issuer name or kind and name", func() {
+		entries, _ := f.Query(audit.Query{Issuer: "bot"})
+		Expect(entries).To(HaveLen(2))
+		entries, _ = f.Query(audit.Query{Issuer: "cli:alice"})
+		Expect(entries).To(HaveLen(1))
+	})
+	It("does filter by command", func() {
+		entries, _ := f.Query(audit.Query{Command: "SAY"})
+		Expect(entries).To(HaveLen(1))
+	})
+	It("does filter by time", func() {
+		entries, _ := f.Query(audit.Query{Since: t0.Add(time.Minute), Until: t0.Add(2 * time.Minute)})
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Server).To(Equal("eu-2"))
+	})
+	It("does return the latest entries on limit", func() {
+		entries, _ := f.Query(audit.Query{Limit: 2})
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].Key).To(BeEquivalentTo(1))
+	})
+	It("does append to existing logs", func() {
+		f.Close()
+		var err error
+		f, err = audit.OpenFile(f.Path)
+		Expect(err).To(BeNil())
+		Expect(f.Append(audit.Entry{Request: "players"})).To(BeNil())
+		entries, _ := f.Query(audit.Query{})
+		Expect(entries).To(HaveLen(4))
+	})
+})
@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// Handler serving entries of store as json list. Entries are selected by the query parameters
+// since, until (RFC3339), server, issuer, command and limit
+func Handler(store Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		q, err := ParseQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		entries, err := store.Query(q)
+		if err != nil {
+			log.From(ctx).Error("querying audit log", zap.Error(err))
+			http.Error(w, "querying audit log failed", http.StatusInternalServerError)
+			return
+		}
+		if entries == nil {
+			entries = []Entry{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+	})
+}
+
+// ParseQuery from url parameters
+func ParseQuery(v url.Values) (Query, error) {
+	q := Query{
+		Server:  v.Get("server"),
+		Issuer:  v.Get("issuer"),
+		Command: v.Get("command"),
+	}
+	var err error
+	if s := v.Get("since"); s != "" {
+		if q.Since, err = time.Parse(time.RFC3339, s); err != nil {
+			return q, errors.Wrap(err, "invalid since")
+		}
+	}
+	if s := v.Get("until"); s != "" {
+		if q.Until, err = time.Parse(time.RFC3339, s); err != nil {
+			return q, errors.Wrap(err, "invalid until")
+		}
+	}
+	if s := v.Get("limit"); s != "" {
+		if q.Limit, err = strconv.Atoi(s); err != nil || q.Limit < 0 {
+			return q, errors.New("invalid limit")
+		}
+	}
+	return q, nil
+}
@@ -0,0 +1,68 @@
+package audit_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/audit"
+	"github.com/playnet-public/gorcon/pkg/mocks"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("Handler", func() {
+	var (
+		store *mocks.AuditStore
+		h     http.Handler
+	)
+
+	BeforeEach(func() {
+		store = &mocks.AuditStore{}
+		h = audit.Handler(store)
+	})
+
+	It("does query the store", func() {
+		store.QueryReturns([]audit.Entry{{Request: "players"}}, nil)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/?server=eu-1&issuer=bot&command=say&limit=5&since=2018-01-01T12:00:00Z", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		q := store.QueryArgsForCall(0)
+		Expect(q).To(Equal(audit.Query{
+			Server:  "eu-1",
+			Issuer:  "bot",
+			Command: "say",
+			Limit:   5,
+			Since:   time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC),
+		}))
+		var entries []audit.Entry
+		Expect(json.NewDecoder(rec.Body).Decode(&entries)).To(BeNil())
+		Expect(entries[0].Request).To(Equal("players"))
+	})
+	It("does return empty lists", func() {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		Expect(rec.Body.String()).To(Equal("[]\n"))
+	})
+	It("does reject invalid queries", func() {
+		for _, q := range []string{"since=yesterday", "until=1", "limit=-1"} {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest("GET", "/?"+q, nil))
+			Expect(rec.Code).To(Equal(http.StatusBadRequest), q)
+		}
+	})
+	It("does fail on store errors", func() {
+		store.QueryReturns(nil, errors.New("test"))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+	})
+	It("does only allow GET", func() {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "/", nil))
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
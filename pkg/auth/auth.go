@@ -0,0 +1,136 @@
+// Package auth authenticates api callers and restricts the rcon commands they may run on which servers
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/playnet-public/gorcon/pkg/audit"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrUnauthenticated is returned when no valid credentials are present
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden is returned when a principal is not allowed to run a command
+	ErrForbidden = errors.New("forbidden")
+	// ErrInvalidToken is returned by authenticators for unknown, malformed or expired tokens
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// Principal is an authenticated caller and the roles assigned to it
+type Principal struct {
+	Name  string   `yaml:"name"`
+	Roles []string `yaml:"roles"`
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying p. Commands written with it are audited as issued by the token of p
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	if p != nil {
+		ctx = audit.WithIssuer(ctx, audit.Token, p.Name)
+	}
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal carried by ctx or nil
+func PrincipalFrom(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Authenticator resolves tokens to principals
+//go:generate counterfeiter -o ../mocks/auth_authenticator.go --fake-name Authenticator . Authenticator
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+}
+
+// Tokens authenticates static api tokens
+type Tokens struct {
+	m      sync.RWMutex
+	tokens map[string]*Principal
+}
+
+// NewTokens without any tokens
+func NewTokens() *Tokens {
+	return &Tokens{tokens: make(map[string]*Principal)}
+}
+
+// Add token authenticating p
+func (t *Tokens) Add(token string, p *Principal) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	t.tokens[token] = p
+}
+
+// Remove token
+func (t *Tokens) Remove(token string) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	delete(t.tokens, token)
+}
+
+// Authenticate token by comparing it to all known tokens in constant time
+func (t *Tokens) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	t.m.RLock()
+	defer t.m.RUnlock()
+	var found *Principal
+	for k, p := range t.tokens {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(token)) == 1 {
+			found = p
+		}
+	}
+	if token == "" || found == nil {
+		return nil, ErrInvalidToken
+	}
+	return found, nil
+}
+
+// Chain tries all authenticators in order returning the first principal found
+type Chain []Authenticator
+
+// Authenticate token with the first authenticator accepting it
+func (c Chain) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	for _, a := range c {
+		if p, err := a.Authenticate(ctx, token); err == nil {
+			return p, nil
+		}
+	}
+	return nil, ErrInvalidToken
+}
+
+// Middleware authenticates bearer tokens of all requests and adds the principal to the request context
+// Requests without valid token are rejected
+func Middleware(a Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		token := BearerToken(r)
+		if token == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		p, err := a.Authenticate(ctx, token)
+		if err != nil {
+			log.From(ctx).Info("rejecting request", zap.String("path", r.URL.Path), zap.Error(err))
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(ctx, p)))
+	})
+}
+
+// BearerToken of r or an empty string if none is present
+func BearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if len(h) < 7 || !strings.EqualFold(h[:7], "bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(h[7:])
+}
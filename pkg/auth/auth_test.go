@@ -0,0 +1,130 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/playnet-public/gorcon/pkg/auth"
+	"github.com/playnet-public/gorcon/pkg/mocks"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestAuth(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Auth Suite")
+}
+
+var _ = Describe("Auth", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	Describe("Principal", func() {
+		It("does round trip through context", func() {
+			p := &auth.Principal{Name: "admin"}
+			Expect(auth.PrincipalFrom(auth.WithPrincipal(ctx, p))).To(Equal(p))
+		})
+		It("does return nil without principal", func() {
+			Expect(auth.PrincipalFrom(ctx)).To(BeNil())
+		})
+	})
+
+	Describe("Tokens", func() {
+		var t *auth.Tokens
+
+		BeforeEach(func() {
+			t = auth.NewTokens()
+			t.Add("secret", &auth.Principal{Name: "bot"})
+		})
+
+		It("does authenticate known tokens", func() {
+			p, err := t.Authenticate(ctx, "secret")
+			Expect(err).To(BeNil())
+			Expect(p.Name).To(Equal("bot"))
+		})
+		It("does reject unknown tokens", func() {
+			_, err := t.Authenticate(ctx, "other")
+			Expect(err).To(Equal(auth.ErrInvalidToken))
+		})
+		It("does reject empty tokens", func() {
+			t.Add("", &auth.Principal{Name: "empty"})
+			_, err := t.Authenticate(ctx, "")
+			Expect(err).To(Equal(auth.ErrInvalidToken))
+		})
+		It("does reject removed tokens", func() {
+			t.Remove("secret")
+			_, err := t.Authenticate(ctx, "secret")
+			Expect(err).To(Equal(auth.ErrInvalidToken))
+		})
+	})
+
+	Describe("Chain", func() {
+		It("does return the first principal found", func() {
+			a1 := &mocks.Authenticator{}
+			a1.AuthenticateReturns(nil, errors.New("test"))
+			a2 := &mocks.Authenticator{}
+			a2.AuthenticateReturns(&auth.Principal{Name: "second"}, nil)
+			p, err := auth.Chain{a1, a2}.Authenticate(ctx, "token")
+			Expect(err).To(BeNil())
+			Expect(p.Name).To(Equal("second"))
+			_, token := a1.AuthenticateArgsForCall(0)
+			Expect(token).To(Equal("token"))
+		})
+		It("does return error if no authenticator accepts the token", func() {
+			a := &mocks.Authenticator{}
+			a.AuthenticateReturns(nil, errors.New("test"))
+			_, err := auth.Chain{a}.Authenticate(ctx, "token")
+			Expect(err).To(Equal(auth.ErrInvalidToken))
+		})
+	})
+
+	Describe("Middleware", func() {
+		var (
+			a       *mocks.Authenticator
+			h       http.Handler
+			current *auth.Principal
+		)
+
+		BeforeEach(func() {
+			a = &mocks.Authenticator{}
+			current = nil
+			h = auth.Middleware(a, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				current = auth.PrincipalFrom(r.Context())
+			}))
+		})
+
+		It("does add the principal to the request", func() {
+			a.AuthenticateReturns(&auth.Principal{Name: "admin"}, nil)
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(current.Name).To(Equal("admin"))
+			_, token := a.AuthenticateArgsForCall(0)
+			Expect(token).To(Equal("secret"))
+		})
+		It("does reject requests without token", func() {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+			Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+			Expect(a.AuthenticateCallCount()).To(BeZero())
+		})
+		It("does reject invalid tokens", func() {
+			a.AuthenticateReturns(nil, auth.ErrInvalidToken)
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Authorization", "bearer invalid")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+			Expect(current).To(BeNil())
+		})
+	})
+})
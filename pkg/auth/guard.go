@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/audit"
+	"github.com/playnet-public/gorcon/pkg/rcon"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// Guard is a rcon.Writer only passing commands the principal found in the context is allowed to run
+type Guard struct {
+	Writer rcon.Writer
+	RBAC   *RBAC
+	// Server the writer sends commands to as matched against role server patterns
+	Server string
+	// Audit receives an entry with the decision for every command checked, e.g. the audit.File of the server
+	Audit audit.Store
+}
+
+// NewGuard in front of w sending commands to server
+func NewGuard(w rcon.Writer, rbac *RBAC, server string) *Guard {
+	return &Guard{
+		Writer: w,
+		RBAC:   rbac,
+		Server: server,
+	}
+}
+
+// Write cmd if the principal in ctx is allowed to. ErrUnauthenticated is returned if ctx carries no principal
+// and ErrForbidden if it lacks permission
+func (g *Guard) Write(ctx context.Context, cmd string) (rcon.Transmission, error) {
+	p := PrincipalFrom(ctx)
+	if p == nil {
+		return nil, ErrUnauthenticated
+	}
+	if !g.RBAC.Allowed(p, g.Server, cmd) {
+		err := errors.Wrapf(ErrForbidden, "%s may not run %s on %s", p.Name, rcon.CommandName(cmd), g.Server)
+		g.audit(ctx, p, cmd, err)
+		return nil, err
+	}
+	g.audit(ctx, p, cmd, nil)
+	return g.Writer.Write(ctx, cmd)
+}
+
+// audit the decision about p running cmd, which got denied with err if not nil
+func (g *Guard) audit(ctx context.Context, p *Principal, cmd string, err error) {
+	e := audit.Entry{
+		Timestamp: time.Now().UTC(),
+		Server:    g.Server,
+		Issuer:    audit.IssuerFrom(ctx),
+		Request:   audit.Redact(cmd),
+		Decision:  audit.Allowed,
+	}
+	l := log.From(ctx).Debug
+	if err != nil {
+		e.Decision, e.Error = audit.Denied, err.Error()
+		l = log.From(ctx).Warn
+	}
+	l("checking command",
+		zap.String("principal", p.Name),
+		zap.String("server", e.Server),
+		zap.String("command", rcon.CommandName(cmd)),
+		zap.String("decision", e.Decision),
+	)
+	if g.Audit == nil {
+		return
+	}
+	if err := g.Audit.Append(e); err != nil {
+		log.From(ctx).Error("writing audit log", zap.Error(err))
+	}
+}
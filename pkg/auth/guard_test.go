@@ -0,0 +1,68 @@
+package auth_test
+
+import (
+	"context"
+
+	"github.com/playnet-public/gorcon/pkg/audit"
+	"github.com/playnet-public/gorcon/pkg/auth"
+	"github.com/playnet-public/gorcon/pkg/mocks"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("Guard", func() {
+	var (
+		ctx   context.Context
+		w     *mocks.RconWriter
+		g     *auth.Guard
+		store *mocks.AuditStore
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		w = &mocks.RconWriter{}
+		store = &mocks.AuditStore{}
+		g = auth.NewGuard(w, auth.NewRBAC(&auth.Role{Name: "moderator", Servers: []string{"*"}, Commands: []string{"say"}}), "eu-1")
+		g.Audit = store
+	})
+
+	It("does pass allowed commands", func() {
+		ctx = auth.WithPrincipal(ctx, &auth.Principal{Name: "bot", Roles: []string{"moderator"}})
+		_, err := g.Write(ctx, "say -1 hello")
+		Expect(err).To(BeNil())
+		Expect(w.WriteCallCount()).To(Equal(1))
+		_, cmd := w.WriteArgsForCall(0)
+		Expect(cmd).To(Equal("say -1 hello"))
+	})
+	It("does reject forbidden commands", func() {
+		ctx = auth.WithPrincipal(ctx, &auth.Principal{Name: "bot", Roles: []string{"moderator"}})
+		_, err := g.Write(ctx, "#shutdown")
+		Expect(errors.Cause(err)).To(Equal(auth.ErrForbidden))
+		Expect(w.WriteCallCount()).To(BeZero())
+	})
+	It("does reject unauthenticated commands", func() {
+		_, err := g.Write(ctx, "say -1 hello")
+		Expect(err).To(Equal(auth.ErrUnauthenticated))
+		Expect(w.WriteCallCount()).To(BeZero())
+	})
+	It("does audit denied commands without sensitive arguments", func() {
+		ctx = auth.WithPrincipal(ctx, &auth.Principal{Name: "bot", Roles: []string{"moderator"}})
+		g.Write(ctx, "RConPassword secret")
+		Expect(store.AppendCallCount()).To(Equal(1))
+		e := store.AppendArgsForCall(0)
+		Expect(e.Issuer).To(Equal(audit.Issuer{Kind: audit.Token, Name: "bot"}))
+		Expect(e.Server).To(Equal("eu-1"))
+		Expect(e.Request).To(Equal("RConPassword ***"))
+		Expect(e.Decision).To(Equal(audit.Denied))
+		Expect(e.Error).To(ContainSubstring("forbidden"))
+	})
+	It("does audit allowed commands", func() {
+		ctx = auth.WithPrincipal(ctx, &auth.Principal{Name: "bot", Roles: []string{"moderator"}})
+		g.Write(ctx, "say -1 hello")
+		Expect(store.AppendCallCount()).To(Equal(1))
+		Expect(store.AppendArgsForCall(0).Decision).To(Equal(audit.Allowed))
+		Expect(store.AppendArgsForCall(0).Request).To(Equal("say -1 hello"))
+	})
+})
@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// JWT authenticates json web tokens signed with HS256 or RS256 as issued by OIDC providers
+// Tokens must carry the configured issuer and audience. The subject becomes the principal name
+// and the roles claim its roles
+type JWT struct {
+	// Secret for verifying and issuing HS256 tokens
+	Secret []byte
+	// PublicKey for verifying RS256 tokens
+	PublicKey *rsa.PublicKey
+	// PrivateKey for issuing RS256 tokens
+	PrivateKey *rsa.PrivateKey
+
+	Issuer   string
+	Audience string
+	// Leeway for clock skew when validating expiry and not before times
+	Leeway time.Duration
+
+	now func() time.Time
+}
+
+// NewHS256 authenticator for tokens signed with secret by issuer
+func NewHS256(secret []byte, issuer, audience string) *JWT {
+	return &JWT{Secret: secret, Issuer: issuer, Audience: audience, Leeway: time.Minute, now: time.Now}
+}
+
+// NewRS256 authenticator for tokens signed with the private key matching key by issuer
+func NewRS256(key *rsa.PublicKey, issuer, audience string) *JWT {
+	return &JWT{PublicKey: key, Issuer: issuer, Audience: audience, Leeway: time.Minute, now: time.Now}
+}
+
+// Claims of the tokens issued and accepted
+type Claims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  Audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat"`
+	Roles     []string `json:"roles,omitempty"`
+}
+
+// Audience of a token. OIDC providers may send a single string or a list
+type Audience []string
+
+// UnmarshalJSON accepts a string or a list of strings
+func (a *Audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = Audience{s}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	*a = Audience(list)
+	return nil
+}
+
+// MarshalJSON encodes single audiences as string
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// Contains reports whether aud is part of the audience
+func (a Audience) Contains(aud string) bool {
+	for _, v := range a {
+		if v == aud {
+			return true
+		}
+	}
+	return false
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+var b64 = base64.RawURLEncoding
+
+func (j *JWT) alg() string {
+	if j.PublicKey != nil || j.PrivateKey != nil {
+		return "RS256"
+	}
+	return "HS256"
+}
+
+// Issue a token for p valid for ttl. This acts as local issuer for tests and internal tools
+func (j *JWT) Issue(p *Principal, ttl time.Duration) (string, error) {
+	now := j.clock()
+	claims := Claims{
+		Issuer:    j.Issuer,
+		Subject:   p.Name,
+		Audience:  Audience{j.Audience},
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+		Roles:     p.Roles,
+	}
+	h, err := json.Marshal(header{Alg: j.alg(), Typ: "JWT"})
+	if err != nil {
+		return "", errors.Wrap(err, "encoding header")
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", errors.Wrap(err, "encoding claims")
+	}
+	signed := b64.EncodeToString(h) + "." + b64.EncodeToString(c)
+	sig, err := j.sign([]byte(signed))
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + b64.EncodeToString(sig), nil
+}
+
+// Authenticate token by verifying its signature and claims
+func (j *JWT) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.Wrap(ErrInvalidToken, "malformed token")
+	}
+	var h header
+	if err := decode(parts[0], &h); err != nil {
+		return nil, err
+	}
+	// only accept the algorithm matching the configured key to prevent algorithm confusion
+	if h.Alg != j.alg() {
+		return nil, errors.Wrapf(ErrInvalidToken, "unexpected algorithm %s", h.Alg)
+	}
+	sig, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidToken, "malformed signature")
+	}
+	if err := j.verify([]byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, err
+	}
+
+	var c Claims
+	if err := decode(parts[1], &c); err != nil {
+		return nil, err
+	}
+	now := j.clock()
+	switch {
+	case c.Issuer != j.Issuer:
+		return nil, errors.Wrapf(ErrInvalidToken, "unexpected issuer %s", c.Issuer)
+	case j.Audience != "" && !c.Audience.Contains(j.Audience):
+		return nil, errors.Wrapf(ErrInvalidToken, "unexpected audience %v", []string(c.Audience))
+	case c.ExpiresAt == 0 || now.Add(-j.Leeway).Unix() >= c.ExpiresAt:
+		return nil, errors.Wrap(ErrInvalidToken, "token expired")
+	case c.NotBefore != 0 && now.Add(j.Leeway).Unix() < c.NotBefore:
+		return nil, errors.Wrap(ErrInvalidToken, "token not valid yet")
+	case c.Subject == "":
+		return nil, errors.Wrap(ErrInvalidToken, "missing subject")
+	}
+	return &Principal{Name: c.Subject, Roles: c.Roles}, nil
+}
+
+func (j *JWT) clock() time.Time {
+	if j.now == nil {
+		return time.Now()
+	}
+	return j.now()
+}
+
+func (j *JWT) sign(data []byte) ([]byte, error) {
+	if j.alg() == "RS256" {
+		if j.PrivateKey == nil {
+			return nil, errors.New("private key required for issuing tokens")
+		}
+		sum := sha256.Sum256(data)
+		return rsa.SignPKCS1v15(rand.Reader, j.PrivateKey, crypto.SHA256, sum[:])
+	}
+	if len(j.Secret) < 1 {
+		return nil, errors.New("secret required for issuing tokens")
+	}
+	mac := hmac.New(sha256.New, j.Secret)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+func (j *JWT) verify(data, sig []byte) error {
+	if j.alg() == "RS256" {
+		key := j.PublicKey
+		if key == nil {
+			key = &j.PrivateKey.PublicKey
+		}
+		sum := sha256.Sum256(data)
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig); err != nil {
+			return errors.Wrap(ErrInvalidToken, "invalid signature")
+		}
+		return nil
+	}
+	if len(j.Secret) < 1 {
+		return errors.Wrap(ErrInvalidToken, "no secret configured")
+	}
+	mac := hmac.New(sha256.New, j.Secret)
+	mac.Write(data)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return errors.Wrap(ErrInvalidToken, "invalid signature")
+	}
+	return nil
+}
+
+func decode(part string, v interface{}) error {
+	b, err := b64.DecodeString(part)
+	if err != nil {
+		return errors.Wrap(ErrInvalidToken, "malformed encoding")
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return errors.Wrap(ErrInvalidToken, "malformed json")
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("JWT", func() {
+	var (
+		ctx context.Context
+		j   *JWT
+		now time.Time
+		p   *Principal
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		now = time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC)
+		j = NewHS256([]byte("secret"), "gorcon", "api")
+		j.now = func() time.Time { return now }
+		p = &Principal{Name: "admin", Roles: []string{"admin"}}
+	})
+
+	It("does authenticate issued tokens", func() {
+		token, err := j.Issue(p, time.Hour)
+		Expect(err).To(BeNil())
+		got, err := j.Authenticate(ctx, token)
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal(p))
+	})
+	It("does reject expired tokens", func() {
+		token, _ := j.Issue(p, time.Hour)
+		now = now.Add(2 * time.Hour)
+		_, err := j.Authenticate(ctx, token)
+		Expect(errors.Cause(err)).To(Equal(ErrInvalidToken))
+	})
+	It("does accept tokens within leeway", func() {
+		token, _ := j.Issue(p, time.Hour)
+		now = now.Add(time.Hour + 30*time.Second)
+		_, err := j.Authenticate(ctx, token)
+		Expect(err).To(BeNil())
+	})
+	It("does reject tokens with other secrets", func() {
+		other := NewHS256([]byte("other"), "gorcon", "api")
+		token, _ := other.Issue(p, time.Hour)
+		_, err := j.Authenticate(ctx, token)
+		Expect(errors.Cause(err)).To(Equal(ErrInvalidToken))
+	})
+	It("does reject tokens of other issuers", func() {
+		other := NewHS256([]byte("secret"), "other", "api")
+		token, _ := other.Issue(p, time.Hour)
+		_, err := j.Authenticate(ctx, token)
+		Expect(err).To(MatchError(ContainSubstring("issuer")))
+	})
+	It("does reject tokens for other audiences", func() {
+		other := NewHS256([]byte("secret"), "gorcon", "other")
+		token, _ := other.Issue(p, time.Hour)
+		_, err := j.Authenticate(ctx, token)
+		Expect(err).To(MatchError(ContainSubstring("audience")))
+	})
+	It("does reject tampered claims", func() {
+		token, _ := j.Issue(p, time.Hour)
+		parts := strings.Split(token, ".")
+		c, _ := json.Marshal(Claims{Issuer: "gorcon", Subject: "admin", Audience: Audience{"api"}, ExpiresAt: now.Add(time.Hour).Unix(), Roles: []string{"root"}})
+		parts[1] = b64.EncodeToString(c)
+		_, err := j.Authenticate(ctx, strings.Join(parts, "."))
+		Expect(err).To(MatchError(ContainSubstring("signature")))
+	})
+	It("does reject unexpected algorithms", func() {
+		h := b64.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+		c, _ := json.Marshal(Claims{Issuer: "gorcon", Subject: "admin", ExpiresAt: now.Add(time.Hour).Unix()})
+		_, err := j.Authenticate(ctx, h+"."+b64.EncodeToString(c)+".")
+		Expect(err).To(MatchError(ContainSubstring("algorithm")))
+	})
+	It("does reject malformed tokens", func() {
+		for _, token := range []string{"", "a.b", "a.b.c", "!.!.!"} {
+			_, err := j.Authenticate(ctx, token)
+			Expect(errors.Cause(err)).To(Equal(ErrInvalidToken), token)
+		}
+	})
+	It("does accept audience lists", func() {
+		var a Audience
+		Expect(json.Unmarshal([]byte(`["web","api"]`), &a)).To(BeNil())
+		Expect(a.Contains("api")).To(BeTrue())
+		Expect(json.Unmarshal([]byte(`"api"`), &a)).To(BeNil())
+		Expect(a).To(Equal(Audience{"api"}))
+	})
+
+	Context("with RS256", func() {
+		var key *rsa.PrivateKey
+
+		BeforeEach(func() {
+			var err error
+			key, err = rsa.GenerateKey(rand.Reader, 1024)
+			Expect(err).To(BeNil())
+		})
+
+		It("does authenticate tokens signed by the private key", func() {
+			issuer := &JWT{PrivateKey: key, Issuer: "gorcon", Audience: "api"}
+			token, err := issuer.Issue(p, time.Hour)
+			Expect(err).To(BeNil())
+			got, err := NewRS256(&key.PublicKey, "gorcon", "api").Authenticate(ctx, token)
+			Expect(err).To(BeNil())
+			Expect(got.Name).To(Equal("admin"))
+		})
+		It("does reject HS256 tokens signed with the public key", func() {
+			token, _ := NewHS256(key.PublicKey.N.Bytes(), "gorcon", "api").Issue(p, time.Hour)
+			_, err := NewRS256(&key.PublicKey, "gorcon", "api").Authenticate(ctx, token)
+			Expect(err).To(MatchError(ContainSubstring("algorithm")))
+		})
+		It("does not issue tokens without private key", func() {
+			_, err := NewRS256(&key.PublicKey, "gorcon", "api").Issue(p, time.Hour)
+			Expect(err).NotTo(BeNil())
+		})
+	})
+})
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/playnet-public/gorcon/pkg/rcon"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Role grants access to commands on servers
+// Servers and Commands are glob patterns as understood by path.Match. Commands are matched against the
+// command name only, e.g. "say", "#shutdown" or "ban*", ignoring case. A pattern of "*" matches everything
+type Role struct {
+	Name     string   `yaml:"name"`
+	Servers  []string `yaml:"servers"`
+	Commands []string `yaml:"commands"`
+}
+
+// Allows reports whether the role grants running cmd on server
+func (r *Role) Allows(server, cmd string) bool {
+	return matchAny(r.Servers, server) && matchAny(r.Commands, strings.ToLower(rcon.CommandName(cmd)))
+}
+
+func matchAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if ok, err := path.Match(strings.ToLower(p), s); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// RBAC decides which principals may run which commands based on their roles
+type RBAC struct {
+	m     sync.RWMutex
+	roles map[string]*Role
+}
+
+// NewRBAC with roles
+func NewRBAC(roles ...*Role) *RBAC {
+	r := &RBAC{roles: make(map[string]*Role)}
+	for _, role := range roles {
+		r.Add(role)
+	}
+	return r
+}
+
+// Add or replace role
+func (r *RBAC) Add(role *Role) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.roles[role.Name] = role
+}
+
+// Role by name or nil if unknown
+func (r *RBAC) Role(name string) *Role {
+	r.m.RLock()
+	defer r.m.RUnlock()
+	return r.roles[name]
+}
+
+// Allowed reports whether any role of p grants running cmd on server. Unknown roles grant nothing
+func (r *RBAC) Allowed(p *Principal, server, cmd string) bool {
+	if p == nil {
+		return false
+	}
+	r.m.RLock()
+	defer r.m.RUnlock()
+	for _, name := range p.Roles {
+		if role, ok := r.roles[name]; ok && role.Allows(server, cmd) {
+			return true
+		}
+	}
+	return false
+}
+
+// Config defines roles and the static tokens assigned to principals
+//
+//	roles:
+//	- name: moderator
+//	  servers: ["*"]
+//	  commands: [say, players, kick]
+//	tokens:
+//	- token: secret
+//	  name: discord-bot
+//	  roles: [moderator]
+type Config struct {
+	Roles  []*Role `yaml:"roles"`
+	Tokens []struct {
+		Token     string `yaml:"token"`
+		Principal `yaml:",inline"`
+	} `yaml:"tokens"`
+}
+
+// ReadConfig from r returning the configured roles and tokens
+func ReadConfig(r io.Reader) (*RBAC, *Tokens, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "reading config")
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return nil, nil, errors.Wrap(err, "parsing config")
+	}
+	tokens := NewTokens()
+	for _, t := range cfg.Tokens {
+		if t.Token == "" || t.Name == "" {
+			return nil, nil, errors.New("tokens require token and name")
+		}
+		p := t.Principal
+		tokens.Add(t.Token, &p)
+	}
+	return NewRBAC(cfg.Roles...), tokens, nil
+}
+
+// LoadConfig from the file at path
+func LoadConfig(path string) (*RBAC, *Tokens, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "opening config")
+	}
+	defer f.Close()
+	return ReadConfig(f)
+}
@@ -0,0 +1,81 @@
+package auth_test
+
+import (
+	"context"
+	"strings"
+
+	"github.com/playnet-public/gorcon/pkg/auth"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RBAC", func() {
+	var r *auth.RBAC
+
+	BeforeEach(func() {
+		r = auth.NewRBAC(
+			&auth.Role{Name: "admin", Servers: []string{"*"}, Commands: []string{"*"}},
+			&auth.Role{Name: "moderator", Servers: []string{"eu-*"}, Commands: []string{"say", "players", "kick"}},
+		)
+	})
+
+	It("does allow all commands for wildcards", func() {
+		Expect(r.Allowed(&auth.Principal{Roles: []string{"admin"}}, "us-1", "#shutdown")).To(BeTrue())
+	})
+	It("does match command names", func() {
+		p := &auth.Principal{Roles: []string{"moderator"}}
+		Expect(r.Allowed(p, "eu-1", "say -1 hello")).To(BeTrue())
+		Expect(r.Allowed(p, "eu-1", "KICK 3 spam")).To(BeTrue())
+		Expect(r.Allowed(p, "eu-1", "sayings")).To(BeFalse())
+		Expect(r.Allowed(p, "eu-1", "#shutdown")).To(BeFalse())
+	})
+	It("does match servers", func() {
+		p := &auth.Principal{Roles: []string{"moderator"}}
+		Expect(r.Allowed(p, "us-1", "say -1 hello")).To(BeFalse())
+	})
+	It("does combine roles", func() {
+		p := &auth.Principal{Roles: []string{"unknown", "moderator"}}
+		Expect(r.Allowed(p, "eu-1", "players")).To(BeTrue())
+	})
+	It("does deny nil principals and unknown roles", func() {
+		Expect(r.Allowed(nil, "eu-1", "players")).To(BeFalse())
+		Expect(r.Allowed(&auth.Principal{Roles: []string{"unknown"}}, "eu-1", "players")).To(BeFalse())
+	})
+	It("does support glob command patterns", func() {
+		r.Add(&auth.Role{Name: "bans", Servers: []string{"*"}, Commands: []string{"*ban*"}})
+		p := &auth.Principal{Roles: []string{"bans"}}
+		Expect(r.Allowed(p, "eu-1", "addBan abc 0 cheating")).To(BeTrue())
+		Expect(r.Allowed(p, "eu-1", "removeBan 1")).To(BeTrue())
+		Expect(r.Allowed(p, "eu-1", "kick 1")).To(BeFalse())
+	})
+
+	Describe("ReadConfig", func() {
+		It("does read roles and tokens", func() {
+			rbac, tokens, err := auth.ReadConfig(strings.NewReader(`
+roles:
+- name: moderator
+  servers: ["*"]
+  commands: [say, players]
+tokens:
+- token: secret
+  name: discord-bot
+  roles: [moderator]
+`))
+			Expect(err).To(BeNil())
+			p, err := tokens.Authenticate(context.Background(), "secret")
+			Expect(err).To(BeNil())
+			Expect(p).To(Equal(&auth.Principal{Name: "discord-bot", Roles: []string{"moderator"}}))
+			Expect(rbac.Allowed(p, "eu-1", "players")).To(BeTrue())
+			Expect(rbac.Allowed(p, "eu-1", "kick 1")).To(BeFalse())
+		})
+		It("does reject tokens without name", func() {
+			_, _, err := auth.ReadConfig(strings.NewReader("tokens:\n- token: secret\n"))
+			Expect(err).NotTo(BeNil())
+		})
+		It("does reject invalid yaml", func() {
+			_, _, err := auth.ReadConfig(strings.NewReader("roles: ["))
+			Expect(err).NotTo(BeNil())
+		})
+	})
+})
@@ -0,0 +1,222 @@
+// Package bridge connects event brokers and rcon connections of distributed gorcon daemons through NATS or MQTT
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/audit"
+	"github.com/playnet-public/gorcon/pkg/auth"
+	"github.com/playnet-public/gorcon/pkg/event"
+	"github.com/playnet-public/gorcon/pkg/rcon"
+	"github.com/playnet-public/gorcon/pkg/trace"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// Request to execute a command received from the command subject
+type Request struct {
+	ID      string `json:"id,omitempty"`
+	Command string `json:"command"`
+	// Reply subject for the response. Defaults to the reply of the message or the bridge's response subject
+	Reply string `json:"reply,omitempty"`
+	// Token authenticating the caller. Its principal is recorded as issuer in the audit log
+	Token string `json:"token,omitempty"`
+}
+
+// Response to a Request
+type Response struct {
+	ID       string `json:"id,omitempty"`
+	Server   string `json:"server"`
+	Command  string `json:"command"`
+	Response string `json:"response,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Bridge publishes events to <Prefix>.<Server>.<kind> and executes commands received on <Prefix>.<Server>.commands
+// Responses are published to the reply subject of the request or <Prefix>.<Server>.responses
+// Commands are only executed for requests carrying a token accepted by Auth. Writer should be an auth.Guard
+// to restrict which commands the principal of the token may run
+type Bridge struct {
+	Transport Transport
+	// Server identifies the daemon in subjects
+	Server string
+	// Prefix of all subjects
+	Prefix string
+	// Writer executing commands. No commands are accepted if nil
+	Writer rcon.Writer
+	// Auth authenticates the tokens of requests. All requests are rejected if nil
+	Auth auth.Authenticator
+	// Timeout for waiting on command responses
+	Timeout time.Duration
+}
+
+// NewBridge for server connected through t executing commands authenticated by a via w
+func NewBridge(t Transport, server string, w rcon.Writer, a auth.Authenticator) *Bridge {
+	return &Bridge{
+		Transport: t,
+		Server:    server,
+		Prefix:    "server",
+		Writer:    w,
+		Auth:      a,
+		Timeout:   10 * time.Second,
+	}
+}
+
+// Subject of the bridge followed by tokens
+func (b *Bridge) Subject(tokens ...string) string {
+	s := b.Prefix + "." + Token(b.Server)
+	for _, t := range tokens {
+		s += "." + t
+	}
+	return s
+}
+
+// Run the bridge publishing events from in and serving commands until ctx or in gets closed
+func (b *Bridge) Run(ctx context.Context, in <-chan event.Event) error {
+	if b.Writer != nil {
+		if err := b.Transport.Subscribe(ctx, b.Subject("commands"), b.handle(ctx)); err != nil {
+			return errors.Wrap(err, "subscribing to commands")
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			log.From(ctx).Info("stopping bridge", zap.Error(ctx.Err()))
+			return ctx.Err()
+		case e, ok := <-in:
+			if !ok {
+				log.From(ctx).Info("stopping bridge")
+				return event.ErrInputClosed
+			}
+			if err := b.Publish(ctx, e); err != nil {
+				log.From(ctx).Error("publishing event", zap.String("server", b.Server), zap.Error(err))
+			}
+		}
+	}
+}
+
+// Publish e as json encoded event.Record to the subject of its kind
+func (b *Bridge) Publish(ctx context.Context, e event.Event) error {
+	data, err := json.Marshal(event.FromEvent(e))
+	if err != nil {
+		return errors.Wrap(err, "encoding event")
+	}
+	if err := b.Transport.Publish(ctx, Message{Subject: b.Subject(Token(e.Kind())), Data: data}); err != nil {
+		bridgeErrors.With(b.Server).Inc()
+		return errors.Wrap(err, "publishing event")
+	}
+	bridgePublished.With(b.Server).Inc()
+	return nil
+}
+
+// handle command requests without blocking the transport
+func (b *Bridge) handle(ctx context.Context) Handler {
+	return func(m Message) {
+		go func() {
+			var req Request
+			if err := json.Unmarshal(m.Data, &req); err != nil {
+				log.From(ctx).Warn("invalid command request", zap.String("subject", m.Subject), zap.Error(err))
+				bridgeCommands.With(b.Server, "invalid").Inc()
+				return
+			}
+			reply := req.Reply
+			if reply == "" {
+				reply = m.Reply
+			}
+			if reply == "" {
+				reply = b.Subject("responses")
+			}
+
+			data, _ := json.Marshal(b.Execute(ctx, req))
+			if err := b.Transport.Publish(ctx, Message{Subject: reply, Data: data}); err != nil {
+				log.From(ctx).Error("publishing response", zap.String("subject", reply), zap.Error(err))
+			}
+		}()
+	}
+}
+
+// Execute req via the bridge's Writer waiting for the response
+// Requests without a token accepted by Auth are rejected with ErrUnauthenticated
+func (b *Bridge) Execute(ctx context.Context, req Request) Response {
+	ctx, span := trace.Start(ctx, "bridge.Execute")
+	defer span.End()
+	span.SetAttribute("rcon.server", b.Server)
+
+	res := Response{ID: req.ID, Server: b.Server, Command: audit.Redact(req.Command)}
+	p, err := b.authenticate(ctx, req.Token)
+	if err != nil {
+		log.From(ctx).Info("rejecting command request", zap.String("server", b.Server), zap.Error(err))
+		span.RecordError(err)
+		bridgeCommands.With(b.Server, "unauthenticated").Inc()
+		res.Error = auth.ErrUnauthenticated.Error()
+		return res
+	}
+	ctx = audit.WithIssuer(auth.WithPrincipal(ctx, p), audit.Bridge, p.Name)
+
+	trm, err := b.Writer.Write(ctx, req.Command)
+	if err != nil {
+		span.RecordError(err)
+		bridgeCommands.With(b.Server, "error").Inc()
+		res.Error = err.Error()
+		return res
+	}
+	select {
+	case <-trm.Done():
+		res.Response = trm.Response()
+		bridgeCommands.With(b.Server, "ok").Inc()
+	case <-time.After(b.Timeout):
+		bridgeCommands.With(b.Server, "timeout").Inc()
+		res.Error = "no response"
+	case <-ctx.Done():
+		res.Error = ctx.Err().Error()
+	}
+	return res
+}
+
+func (b *Bridge) authenticate(ctx context.Context, token string) (*auth.Principal, error) {
+	if b.Auth == nil || token == "" {
+		return nil, auth.ErrUnauthenticated
+	}
+	return b.Auth.Authenticate(ctx, token)
+}
+
+// Call sends cmd authenticated by token to the bridge at subject and waits for its response
+// The response is received on a unique inbox subject, so Call works with all transports
+func Call(ctx context.Context, t Transport, subject, cmd, token string) (Response, error) {
+	id := event.NewID()
+	inbox := "_INBOX." + id
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	responses := make(chan Response, 1)
+	err := t.Subscribe(ctx, inbox, func(m Message) {
+		var res Response
+		if json.Unmarshal(m.Data, &res) == nil && res.ID == id {
+			select {
+			case responses <- res:
+			default:
+			}
+		}
+	})
+	if err != nil {
+		return Response{}, errors.Wrap(err, "subscribing to inbox")
+	}
+
+	data, _ := json.Marshal(Request{ID: id, Command: cmd, Reply: inbox, Token: token})
+	if err := t.Publish(ctx, Message{Subject: subject, Data: data}); err != nil {
+		return Response{}, errors.Wrap(err, "publishing request")
+	}
+	select {
+	case res := <-responses:
+		if res.Error != "" {
+			return res, errors.New(res.Error)
+		}
+		return res, nil
+	case <-ctx.Done():
+		return Response{}, ctx.Err()
+	}
+}
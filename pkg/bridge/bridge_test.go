@@ -0,0 +1,184 @@
+package bridge_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/audit"
+	"github.com/playnet-public/gorcon/pkg/auth"
+	"github.com/playnet-public/gorcon/pkg/bridge"
+	"github.com/playnet-public/gorcon/pkg/event"
+	"github.com/playnet-public/gorcon/pkg/mocks"
+	"github.com/playnet-public/gorcon/pkg/rcon"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/seibert-media/golibs/log"
+)
+
+var _ = Describe("Bridge", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		t      *bridge.Memory
+		w      *mocks.RconWriter
+		trm    *mocks.RconTransmission
+		b      *bridge.Bridge
+		in     chan event.Event
+	)
+
+	BeforeEach(func() {
+		ctx = log.WithLogger(context.Background(), log.NewNop())
+		ctx, cancel = context.WithCancel(ctx)
+		t = bridge.NewMemory()
+		w = &mocks.RconWriter{}
+		trm = &mocks.RconTransmission{}
+		trm.DoneStub = func() <-chan bool {
+			done := make(chan bool, 1)
+			done <- true
+			return done
+		}
+		trm.ResponseReturns("Players on server: 0")
+		w.WriteReturns(trm, nil)
+		tokens := auth.NewTokens()
+		tokens.Add("secret", &auth.Principal{Name: "central"})
+		b = bridge.NewBridge(t, "eu.1", w, tokens)
+		in = make(chan event.Event)
+	})
+	AfterEach(func() {
+		cancel()
+	})
+
+	subscribe := func(subject string) <-chan bridge.Message {
+		c := make(chan bridge.Message, 10)
+		Expect(t.Subscribe(ctx, subject, func(m bridge.Message) { c <- m })).To(BeNil())
+		return c
+	}
+
+	Describe("Subject", func() {
+		It("does escape the server id", func() {
+			Expect(b.Subject("chat")).To(Equal("server.eu_1.chat"))
+		})
+	})
+
+	Describe("Run", func() {
+		It("does publish events by kind", func() {
+			chat := subscribe("server.*.chat")
+			go b.Run(ctx, in)
+			in <- rcon.NewServerEvent("127.0.0.1:2302", rcon.TypeChat, "(Global) Test: hi")
+
+			var m bridge.Message
+			Eventually(chat).Should(Receive(&m))
+			Expect(m.Subject).To(Equal("server.eu_1.chat"))
+			var r event.Record
+			Expect(json.Unmarshal(m.Data, &r)).To(BeNil())
+			Expect(r.Payload).To(Equal("(Global) Test: hi"))
+			Expect(r.Source.Server).To(Equal("127.0.0.1:2302"))
+		})
+		It("does return on closed input", func() {
+			close(in)
+			Expect(b.Run(ctx, in)).To(Equal(event.ErrInputClosed))
+		})
+		It("does return error if subscribing to commands fails", func() {
+			tr := &mocks.BridgeTransport{}
+			tr.SubscribeReturns(errors.New("test"))
+			b.Transport = tr
+			Expect(b.Run(ctx, in)).NotTo(BeNil())
+		})
+		It("does not subscribe to commands without writer", func() {
+			tr := &mocks.BridgeTransport{}
+			b.Transport = tr
+			b.Writer = nil
+			close(in)
+			b.Run(ctx, in)
+			Expect(tr.SubscribeCallCount()).To(BeZero())
+		})
+		It("does execute command requests", func() {
+			responses := subscribe("server.eu_1.responses")
+			go b.Run(ctx, in)
+			// publish until the bridge subscribed
+			var m bridge.Message
+			Eventually(func() bool {
+				t.Publish(ctx, bridge.Message{Subject: "server.eu_1.commands", Data: []byte(`{"id":"1","command":"players","token":"secret"}`)})
+				select {
+				case m = <-responses:
+					return true
+				case <-time.After(10 * time.Millisecond):
+					return false
+				}
+			}).Should(BeTrue())
+			var res bridge.Response
+			Expect(json.Unmarshal(m.Data, &res)).To(BeNil())
+			Expect(res).To(Equal(bridge.Response{ID: "1", Server: "eu.1", Command: "players", Response: "Players on server: 0"}))
+			_, cmd := w.WriteArgsForCall(0)
+			Expect(cmd).To(Equal("players"))
+		})
+	})
+
+	Describe("Execute", func() {
+		It("does record the principal of the token as issuer", func() {
+			b.Execute(ctx, bridge.Request{Command: "players", Token: "secret"})
+			wctx, _ := w.WriteArgsForCall(0)
+			Expect(audit.IssuerFrom(wctx)).To(Equal(audit.Issuer{Kind: audit.Bridge, Name: "central"}))
+			Expect(auth.PrincipalFrom(wctx).Name).To(Equal("central"))
+		})
+		It("does reject requests without token", func() {
+			res := b.Execute(ctx, bridge.Request{Command: "players"})
+			Expect(res.Error).To(Equal(auth.ErrUnauthenticated.Error()))
+			Expect(w.WriteCallCount()).To(BeZero())
+		})
+		It("does reject requests with invalid token", func() {
+			res := b.Execute(ctx, bridge.Request{Command: "players", Token: "guess"})
+			Expect(res.Error).To(Equal(auth.ErrUnauthenticated.Error()))
+			Expect(w.WriteCallCount()).To(BeZero())
+		})
+		It("does reject all requests without authenticator", func() {
+			b.Auth = nil
+			res := b.Execute(ctx, bridge.Request{Command: "players", Token: "secret"})
+			Expect(res.Error).To(Equal(auth.ErrUnauthenticated.Error()))
+			Expect(w.WriteCallCount()).To(BeZero())
+		})
+		It("does return write errors", func() {
+			w.WriteReturns(nil, errors.New("test"))
+			Expect(b.Execute(ctx, bridge.Request{Command: "players", Token: "secret"}).Error).To(Equal("test"))
+		})
+		It("does time out without response", func() {
+			trm.DoneStub = nil
+			trm.DoneReturns(make(chan bool))
+			b.Timeout = time.Millisecond
+			Expect(b.Execute(ctx, bridge.Request{Command: "players", Token: "secret"}).Error).To(Equal("no response"))
+		})
+		It("does redact sensitive commands", func() {
+			Expect(b.Execute(ctx, bridge.Request{Command: "#login secret", Token: "secret"}).Command).To(Equal("#login ***"))
+		})
+	})
+
+	Describe("Call", func() {
+		It("does wait for the response", func() {
+			go b.Run(ctx, in)
+			Eventually(func() error {
+				cctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+				defer cancel()
+				_, err := bridge.Call(cctx, t, b.Subject("commands"), "players", "secret")
+				return err
+			}).Should(BeNil())
+			cctx, cancel := context.WithTimeout(ctx, time.Second)
+			defer cancel()
+			res, err := bridge.Call(cctx, t, b.Subject("commands"), "players", "secret")
+			Expect(err).To(BeNil())
+			Expect(res.Response).To(Equal("Players on server: 0"))
+		})
+		It("does return errors of the response", func() {
+			w.WriteReturns(nil, errors.New("test"))
+			go b.Run(ctx, in)
+			Eventually(func() error {
+				cctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+				defer cancel()
+				_, err := bridge.Call(cctx, t, b.Subject("commands"), "players", "secret")
+				return err
+			}).Should(MatchError("test"))
+		})
+	})
+})
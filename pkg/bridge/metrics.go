@@ -0,0 +1,11 @@
+package bridge
+
+import (
+	"github.com/playnet-public/gorcon/pkg/metrics"
+)
+
+var (
+	bridgePublished = metrics.NewCounterVec("gorcon_bridge_published_events_total", "Events published to the message bus.", "server")
+	bridgeErrors    = metrics.NewCounterVec("gorcon_bridge_publish_errors_total", "Events failing to publish to the message bus.", "server")
+	bridgeCommands  = metrics.NewCounterVec("gorcon_bridge_commands_total", "Command requests received from the message bus.", "server", "result")
+)
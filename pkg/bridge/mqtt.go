@@ -0,0 +1,311 @@
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MQTT packet types
+const (
+	mqttConnect     = 1
+	mqttConnAck     = 2
+	mqttPublish     = 3
+	mqttSubscribe   = 8
+	mqttSubAck      = 9
+	mqttUnsubscribe = 10
+	mqttPingReq     = 12
+	mqttPingResp    = 13
+	mqttDisconnect  = 14
+)
+
+// MQTT is a Transport speaking MQTT 3.1.1 with QoS 0
+// Subjects are mapped to topics by replacing dots with slashes and the wildcards * and > with + and #
+// Reply subjects are not supported and dropped when publishing
+type MQTT struct {
+	keepAlive time.Duration
+
+	conn net.Conn
+	r    *bufio.Reader
+
+	wm sync.Mutex
+
+	m      sync.Mutex
+	subs   map[uint16]*mqttSub
+	nextID uint16
+	err    error
+	done   chan struct{}
+}
+
+type mqttSub struct {
+	subject string
+	h       Handler
+}
+
+// DialMQTT connects to the MQTT server at addr (host:port or tcp://[user:pass@]host:port) as clientID
+func DialMQTT(ctx context.Context, addr, clientID string) (*MQTT, error) {
+	var user, pass string
+	addr = strings.TrimPrefix(strings.TrimPrefix(addr, "tcp://"), "mqtt://")
+	if i := strings.LastIndex(addr, "@"); i >= 0 {
+		creds := addr[:i]
+		addr = addr[i+1:]
+		user = creds
+		if j := strings.Index(creds, ":"); j >= 0 {
+			user, pass = creds[:j], creds[j+1:]
+		}
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "dialing mqtt")
+	}
+	t := newMQTT(conn)
+	if err := t.connect(ctx, clientID, user, pass); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go t.read()
+	go t.ping()
+	return t, nil
+}
+
+func newMQTT(conn net.Conn) *MQTT {
+	return &MQTT{
+		keepAlive: 30 * time.Second,
+		conn:      conn,
+		r:         bufio.NewReader(conn),
+		subs:      make(map[uint16]*mqttSub),
+		done:      make(chan struct{}),
+	}
+}
+
+func (t *MQTT) connect(ctx context.Context, clientID, user, pass string) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		t.conn.SetDeadline(deadline)
+		defer t.conn.SetDeadline(time.Time{})
+	}
+	var flags byte = 0x02 // clean session
+	if user != "" {
+		flags |= 0x80
+	}
+	if pass != "" {
+		flags |= 0x40
+	}
+	b := mqttString(nil, "MQTT")
+	b = append(b, 4, flags)
+	b = mqttUint16(b, uint16(t.keepAlive/time.Second))
+	b = mqttString(b, clientID)
+	if user != "" {
+		b = mqttString(b, user)
+	}
+	if pass != "" {
+		b = mqttString(b, pass)
+	}
+	if err := t.send(mqttConnect<<4, b); err != nil {
+		return errors.Wrap(err, "connecting to mqtt")
+	}
+
+	typ, body, err := t.packet()
+	if err != nil {
+		return errors.Wrap(err, "connecting to mqtt")
+	}
+	if typ>>4 != mqttConnAck || len(body) != 2 {
+		return errors.Errorf("connecting to mqtt: unexpected packet type %d", typ>>4)
+	}
+	if body[1] != 0 {
+		return errors.Errorf("connecting to mqtt: refused with code %d", body[1])
+	}
+	return nil
+}
+
+// Publish m as topic
+func (t *MQTT) Publish(ctx context.Context, m Message) error {
+	if err := t.closed(); err != nil {
+		return err
+	}
+	b := mqttString(nil, Topic(m.Subject))
+	return t.send(mqttPublish<<4, append(b, m.Data...))
+}
+
+// Subscribe h to messages matching subject until ctx is closed
+func (t *MQTT) Subscribe(ctx context.Context, subject string, h Handler) error {
+	if err := t.closed(); err != nil {
+		return err
+	}
+	topic := Topic(subject)
+	t.m.Lock()
+	t.nextID++
+	if t.nextID == 0 {
+		t.nextID++
+	}
+	id := t.nextID
+	t.subs[id] = &mqttSub{subject, h}
+	t.m.Unlock()
+
+	b := mqttUint16(nil, id)
+	b = append(mqttString(b, topic), 0)
+	if err := t.send(mqttSubscribe<<4|0x02, b); err != nil {
+		return err
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-t.done:
+			return
+		}
+		t.m.Lock()
+		delete(t.subs, id)
+		t.m.Unlock()
+		b := mqttUint16(nil, id)
+		t.send(mqttUnsubscribe<<4|0x02, mqttString(b, topic))
+	}()
+	return nil
+}
+
+// Close the connection after sending DISCONNECT
+func (t *MQTT) Close() error {
+	t.send(mqttDisconnect<<4, nil)
+	return t.conn.Close()
+}
+
+// Done is closed once the connection got lost or closed
+func (t *MQTT) Done() <-chan struct{} {
+	return t.done
+}
+
+// Err returns the reason the connection got lost
+func (t *MQTT) Err() error {
+	t.m.Lock()
+	defer t.m.Unlock()
+	return t.err
+}
+
+func (t *MQTT) closed() error {
+	select {
+	case <-t.done:
+		return errors.Wrap(ErrClosed, errString(t.Err()))
+	default:
+		return nil
+	}
+}
+
+// send a packet with fixed header byte typ
+func (t *MQTT) send(typ byte, body []byte) error {
+	b := make([]byte, 1+binary.MaxVarintLen64)
+	b[0] = typ
+	b = b[:1+binary.PutUvarint(b[1:], uint64(len(body)))]
+	t.wm.Lock()
+	defer t.wm.Unlock()
+	_, err := t.conn.Write(append(b, body...))
+	return errors.Wrap(err, "writing to mqtt")
+}
+
+// packet reads the next packet returning its fixed header byte and body
+func (t *MQTT) packet() (byte, []byte, error) {
+	typ, err := t.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	// the remaining length uses the same encoding as unsigned varints
+	l, err := binary.ReadUvarint(t.r)
+	if err != nil {
+		return 0, nil, err
+	}
+	body := make([]byte, l)
+	if _, err := io.ReadFull(t.r, body); err != nil {
+		return 0, nil, err
+	}
+	return typ, body, nil
+}
+
+func (t *MQTT) read() {
+	err := t.loop()
+	t.m.Lock()
+	t.err = err
+	t.m.Unlock()
+	close(t.done)
+}
+
+func (t *MQTT) loop() error {
+	for {
+		typ, body, err := t.packet()
+		if err != nil {
+			return err
+		}
+		if typ>>4 != mqttPublish {
+			continue
+		}
+		if len(body) < 2 {
+			return errors.New("invalid mqtt publish")
+		}
+		l := int(binary.BigEndian.Uint16(body))
+		if len(body) < 2+l {
+			return errors.New("invalid mqtt publish")
+		}
+		subject := Subject(string(body[2 : 2+l]))
+		data := body[2+l:]
+		if qos := (typ >> 1) & 0x03; qos > 0 {
+			// skip the packet id of messages sent with a higher qos than requested
+			if len(data) < 2 {
+				return errors.New("invalid mqtt publish")
+			}
+			data = data[2:]
+		}
+
+		t.m.Lock()
+		var handlers []Handler
+		for _, s := range t.subs {
+			if Match(s.subject, subject) {
+				handlers = append(handlers, s.h)
+			}
+		}
+		t.m.Unlock()
+		for _, h := range handlers {
+			h(Message{Subject: subject, Data: data})
+		}
+	}
+}
+
+// ping the server to keep the connection alive
+func (t *MQTT) ping() {
+	if t.keepAlive <= 0 {
+		return
+	}
+	tick := time.NewTicker(t.keepAlive / 2)
+	defer tick.Stop()
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-tick.C:
+			t.send(mqttPingReq<<4, nil)
+		}
+	}
+}
+
+func mqttString(b []byte, s string) []byte {
+	b = mqttUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func mqttUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+// Topic converts subject to an MQTT topic
+func Topic(subject string) string {
+	return strings.NewReplacer(".", "/", "*", "+", ">", "#").Replace(subject)
+}
+
+// Subject converts an MQTT topic to a subject
+func Subject(topic string) string {
+	return strings.NewReplacer("/", ".", "+", "*", "#", ">").Replace(topic)
+}
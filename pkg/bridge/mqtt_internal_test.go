@@ -0,0 +1,109 @@
+package bridge
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MQTT", func() {
+	var (
+		ctx    context.Context
+		client *MQTT
+		// server reuses the packet framing of the client for the other end of the connection
+		server *MQTT
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c, s := net.Pipe()
+		client = newMQTT(c)
+		server = newMQTT(s)
+	})
+	AfterEach(func() {
+		client.conn.Close()
+		server.conn.Close()
+	})
+
+	// packet returns the next packet sent by the client or zero values once the connection closed
+	packet := func() (byte, []byte) {
+		typ, body, _ := server.packet()
+		return typ, body
+	}
+	accept := func(code byte) []byte {
+		_, body := packet()
+		server.send(mqttConnAck<<4, []byte{0, code})
+		return body
+	}
+	start := func() {
+		go accept(0)
+		Expect(client.connect(ctx, "gorcon", "", "")).To(BeNil())
+		go client.read()
+	}
+
+	Describe("connect", func() {
+		It("does send CONNECT", func() {
+			body := make(chan []byte, 1)
+			go func() { body <- accept(0) }()
+			Expect(client.connect(ctx, "gorcon", "u", "p")).To(BeNil())
+			b := <-body
+			Expect(b[:6]).To(Equal(mqttString(nil, "MQTT")))
+			Expect(b[6]).To(BeEquivalentTo(4))
+			Expect(b[7]).To(BeEquivalentTo(0x80 | 0x40 | 0x02))
+			Expect(binary.BigEndian.Uint16(b[8:])).To(BeEquivalentTo(30))
+			Expect(b[10:]).To(Equal(mqttString(mqttString(mqttString(nil, "gorcon"), "u"), "p")))
+		})
+		It("does return error if refused", func() {
+			go accept(5)
+			Expect(client.connect(ctx, "gorcon", "", "")).To(MatchError(ContainSubstring("code 5")))
+		})
+	})
+
+	Describe("Publish", func() {
+		It("does send PUBLISH to the topic of the subject", func() {
+			start()
+			go client.Publish(ctx, Message{Subject: "server.a.chat", Data: []byte("hi")})
+			typ, body := packet()
+			Expect(typ >> 4).To(BeEquivalentTo(mqttPublish))
+			Expect(body).To(Equal(append(mqttString(nil, "server/a/chat"), "hi"...)))
+		})
+	})
+
+	Describe("Subscribe", func() {
+		It("does dispatch messages", func() {
+			start()
+			received := make(chan Message, 1)
+			go client.Subscribe(ctx, "server.*.chat", func(m Message) { received <- m })
+			typ, body := packet()
+			Expect(typ).To(BeEquivalentTo(mqttSubscribe<<4 | 0x02))
+			Expect(body[2:]).To(Equal(append(mqttString(nil, "server/+/chat"), 0)))
+
+			server.send(mqttPublish<<4, append(mqttString(nil, "server/a/chat"), "hello"...))
+			Eventually(received).Should(Receive(Equal(Message{Subject: "server.a.chat", Data: []byte("hello")})))
+		})
+		It("does unsubscribe on closed context", func() {
+			start()
+			sub, cancel := context.WithCancel(ctx)
+			go client.Subscribe(sub, "a", func(Message) {})
+			packet()
+			cancel()
+			typ, body := packet()
+			Expect(typ).To(BeEquivalentTo(mqttUnsubscribe<<4 | 0x02))
+			Expect(body[2:]).To(Equal(mqttString(nil, "a")))
+		})
+	})
+
+	Describe("ping", func() {
+		It("does send PINGREQ", func() {
+			start()
+			client.keepAlive = 20 * time.Millisecond
+			go client.ping()
+			typ, _ := packet()
+			Expect(typ >> 4).To(BeEquivalentTo(mqttPingReq))
+		})
+	})
+})
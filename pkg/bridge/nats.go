@@ -0,0 +1,267 @@
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NATS is a Transport speaking the NATS client protocol
+type NATS struct {
+	conn net.Conn
+	r    *bufio.Reader
+
+	wm sync.Mutex
+	w  *bufio.Writer
+
+	m    sync.Mutex
+	subs map[int]Handler
+	sid  int
+	err  error
+	done chan struct{}
+}
+
+type natsConnect struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	Version  string `json:"version"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+	Token    string `json:"auth_token,omitempty"`
+}
+
+// DialNATS connects to the NATS server at addr (host:port or nats://[user:pass@]host:port)
+func DialNATS(ctx context.Context, addr string) (*NATS, error) {
+	opts := natsConnect{Name: "gorcon", Lang: "go", Version: "1.0.0"}
+	if strings.HasPrefix(addr, "nats://") {
+		addr = strings.TrimPrefix(addr, "nats://")
+		if i := strings.LastIndex(addr, "@"); i >= 0 {
+			creds := addr[:i]
+			addr = addr[i+1:]
+			if j := strings.Index(creds, ":"); j >= 0 {
+				opts.User, opts.Pass = creds[:j], creds[j+1:]
+			} else {
+				opts.Token = creds
+			}
+		}
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "dialing nats")
+	}
+	n := newNATS(conn)
+	if err := n.handshake(ctx, opts); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go n.read()
+	return n, nil
+}
+
+func newNATS(conn net.Conn) *NATS {
+	return &NATS{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		w:    bufio.NewWriter(conn),
+		subs: make(map[int]Handler),
+		done: make(chan struct{}),
+	}
+}
+
+// handshake reads the server's INFO and sends CONNECT followed by a PING the server has to answer
+func (n *NATS) handshake(ctx context.Context, opts natsConnect) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		n.conn.SetDeadline(deadline)
+		defer n.conn.SetDeadline(time.Time{})
+	}
+	line, err := n.line()
+	if err != nil {
+		return errors.Wrap(err, "reading nats info")
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return errors.Errorf("unexpected nats greeting %q", line)
+	}
+
+	b, _ := json.Marshal(opts)
+	if err := n.write(func(w *bufio.Writer) {
+		fmt.Fprintf(w, "CONNECT %s\r\nPING\r\n", b)
+	}); err != nil {
+		return errors.Wrap(err, "connecting to nats")
+	}
+	for {
+		line, err := n.line()
+		if err != nil {
+			return errors.Wrap(err, "connecting to nats")
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.Errorf("connecting to nats: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+// Publish m
+func (n *NATS) Publish(ctx context.Context, m Message) error {
+	if err := n.closed(); err != nil {
+		return err
+	}
+	return n.write(func(w *bufio.Writer) {
+		if m.Reply != "" {
+			fmt.Fprintf(w, "PUB %s %s %d\r\n", m.Subject, m.Reply, len(m.Data))
+		} else {
+			fmt.Fprintf(w, "PUB %s %d\r\n", m.Subject, len(m.Data))
+		}
+		w.Write(m.Data)
+		w.WriteString("\r\n")
+	})
+}
+
+// Subscribe h to messages matching subject until ctx is closed
+func (n *NATS) Subscribe(ctx context.Context, subject string, h Handler) error {
+	if err := n.closed(); err != nil {
+		return err
+	}
+	n.m.Lock()
+	n.sid++
+	sid := n.sid
+	n.subs[sid] = h
+	n.m.Unlock()
+
+	if err := n.write(func(w *bufio.Writer) { fmt.Fprintf(w, "SUB %s %d\r\n", subject, sid) }); err != nil {
+		return err
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-n.done:
+			return
+		}
+		n.m.Lock()
+		delete(n.subs, sid)
+		n.m.Unlock()
+		n.write(func(w *bufio.Writer) { fmt.Fprintf(w, "UNSUB %d\r\n", sid) })
+	}()
+	return nil
+}
+
+// Close the connection
+func (n *NATS) Close() error {
+	return n.conn.Close()
+}
+
+// Done is closed once the connection got lost or closed
+func (n *NATS) Done() <-chan struct{} {
+	return n.done
+}
+
+// Err returns the reason the connection got lost
+func (n *NATS) Err() error {
+	n.m.Lock()
+	defer n.m.Unlock()
+	return n.err
+}
+
+func (n *NATS) closed() error {
+	select {
+	case <-n.done:
+		return errors.Wrap(ErrClosed, errString(n.Err()))
+	default:
+		return nil
+	}
+}
+
+func (n *NATS) write(fn func(*bufio.Writer)) error {
+	n.wm.Lock()
+	defer n.wm.Unlock()
+	fn(n.w)
+	return errors.Wrap(n.w.Flush(), "writing to nats")
+}
+
+func (n *NATS) line() (string, error) {
+	line, err := n.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// read and dispatch messages until the connection closes
+func (n *NATS) read() {
+	err := n.loop()
+	n.m.Lock()
+	n.err = err
+	n.m.Unlock()
+	close(n.done)
+}
+
+func (n *NATS) loop() error {
+	for {
+		line, err := n.line()
+		if err != nil {
+			return err
+		}
+		switch {
+		case strings.HasPrefix(line, "MSG "):
+			if err := n.msg(strings.Fields(line)[1:]); err != nil {
+				return err
+			}
+		case line == "PING":
+			n.write(func(w *bufio.Writer) { w.WriteString("PONG\r\n") })
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.Errorf("nats: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+// msg handles MSG <subject> <sid> [reply] <size> followed by the payload
+func (n *NATS) msg(args []string) error {
+	if len(args) < 3 || len(args) > 4 {
+		return errors.Errorf("invalid nats message %v", args)
+	}
+	sid, err := strconv.Atoi(args[1])
+	if err != nil {
+		return errors.Wrap(err, "invalid nats sid")
+	}
+	size, err := strconv.Atoi(args[len(args)-1])
+	if err != nil || size < 0 {
+		return errors.Errorf("invalid nats message size %q", args[len(args)-1])
+	}
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(n.r, data); err != nil {
+		return err
+	}
+	m := Message{Subject: args[0], Data: data[:size]}
+	if len(args) == 4 {
+		m.Reply = args[2]
+	}
+
+	n.m.Lock()
+	h := n.subs[sid]
+	n.m.Unlock()
+	if h != nil {
+		h(m)
+	}
+	return nil
+}
+
+func errString(err error) string {
+	if err == nil {
+		return "closed"
+	}
+	return err.Error()
+}
@@ -0,0 +1,154 @@
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// natsServer is a scripted stand-in for the server side of a nats connection
+type natsServer struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// line returns the next line sent by the client or an empty string once the connection closed
+func (s *natsServer) line() string {
+	l, _ := s.r.ReadString('\n')
+	return strings.TrimRight(l, "\r\n")
+}
+
+// send l ignoring errors as the server often outlives the test
+func (s *natsServer) send(l string) {
+	io.WriteString(s.conn, l+"\r\n")
+}
+
+// handshake answers the handshake of a client returning its CONNECT line
+func (s *natsServer) handshake() string {
+	s.send(`INFO {"server_id":"test"}`)
+	connect := s.line()
+	Expect(s.line()).To(Equal("PING"))
+	s.send("PONG")
+	return connect
+}
+
+var _ = Describe("NATS", func() {
+	var (
+		ctx    context.Context
+		client *NATS
+		server *natsServer
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c, s := net.Pipe()
+		client = newNATS(c)
+		server = &natsServer{conn: s, r: bufio.NewReader(s)}
+	})
+	AfterEach(func() {
+		client.Close()
+		server.conn.Close()
+	})
+
+	start := func() {
+		go server.handshake()
+		Expect(client.handshake(ctx, natsConnect{Name: "gorcon"})).To(BeNil())
+		go client.read()
+	}
+
+	Describe("handshake", func() {
+		It("does send CONNECT", func() {
+			connect := make(chan string, 1)
+			go func() { connect <- server.handshake() }()
+			Expect(client.handshake(ctx, natsConnect{Name: "gorcon", User: "u", Pass: "p"})).To(BeNil())
+			Expect(<-connect).To(And(HavePrefix("CONNECT {"), ContainSubstring(`"user":"u"`), ContainSubstring(`"pass":"p"`)))
+		})
+		It("does return server errors", func() {
+			go func() {
+				server.send(`INFO {}`)
+				server.line()
+				server.line()
+				server.send("-ERR 'Authorization Violation'")
+			}()
+			Expect(client.handshake(ctx, natsConnect{})).To(MatchError(ContainSubstring("Authorization Violation")))
+		})
+		It("does reject other greetings", func() {
+			go server.send("HELLO")
+			Expect(client.handshake(ctx, natsConnect{})).NotTo(BeNil())
+		})
+	})
+
+	Describe("Publish", func() {
+		It("does send PUB", func() {
+			start()
+			go client.Publish(ctx, Message{Subject: "server.a.chat", Data: []byte("hi")})
+			Expect(server.line()).To(Equal("PUB server.a.chat 2"))
+			Expect(server.line()).To(Equal("hi"))
+		})
+		It("does send the reply subject", func() {
+			start()
+			go client.Publish(ctx, Message{Subject: "server.a.commands", Reply: "_INBOX.1", Data: []byte("{}")})
+			Expect(server.line()).To(Equal("PUB server.a.commands _INBOX.1 2"))
+		})
+		It("does return error once closed", func() {
+			start()
+			server.conn.Close()
+			Eventually(client.Done()).Should(BeClosed())
+			Expect(client.Publish(ctx, Message{Subject: "a"})).NotTo(BeNil())
+		})
+	})
+
+	Describe("Subscribe", func() {
+		It("does dispatch messages", func() {
+			start()
+			received := make(chan Message, 1)
+			go client.Subscribe(ctx, "server.*.chat", func(m Message) { received <- m })
+			Expect(server.line()).To(Equal("SUB server.*.chat 1"))
+			server.send("MSG server.a.chat 1 _INBOX.1 5")
+			server.send("hello")
+			Eventually(received).Should(Receive(Equal(Message{Subject: "server.a.chat", Reply: "_INBOX.1", Data: []byte("hello")})))
+		})
+		It("does unsubscribe on closed context", func() {
+			start()
+			sub, cancel := context.WithCancel(ctx)
+			go client.Subscribe(sub, "a", func(Message) {})
+			Expect(server.line()).To(Equal("SUB a 1"))
+			cancel()
+			Expect(server.line()).To(Equal("UNSUB 1"))
+		})
+		It("does answer PING", func() {
+			start()
+			server.send("PING")
+			Expect(server.line()).To(Equal("PONG"))
+		})
+	})
+
+	Describe("DialNATS", func() {
+		It("does connect with credentials from the url", func() {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).To(BeNil())
+			defer l.Close()
+			connect := make(chan string, 1)
+			go func() {
+				c, err := l.Accept()
+				if err != nil {
+					return
+				}
+				s := &natsServer{conn: c, r: bufio.NewReader(c)}
+				connect <- s.handshake()
+			}()
+			dctx, cancel := context.WithTimeout(ctx, time.Second)
+			defer cancel()
+			n, err := DialNATS(dctx, "nats://secret@"+l.Addr().String())
+			Expect(err).To(BeNil())
+			defer n.Close()
+			Expect(<-connect).To(ContainSubstring(`"auth_token":"secret"`))
+		})
+	})
+})
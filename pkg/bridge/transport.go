@@ -0,0 +1,129 @@
+package bridge
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrClosed is returned when using a closed transport
+var ErrClosed = errors.New("transport closed")
+
+// Message sent over a Transport
+type Message struct {
+	// Subject of the message using dots to separate tokens
+	Subject string
+	// Reply subject for responses. Only supported by NATS
+	Reply string
+	Data  []byte
+}
+
+// Handler receives messages of a subscription
+type Handler func(Message)
+
+// Transport connects the bridge to a message bus
+// Subjects use NATS syntax where * matches a single token and > matches all remaining tokens
+//go:generate counterfeiter -o ../mocks/bridge_transport.go --fake-name BridgeTransport . Transport
+type Transport interface {
+	// Publish m
+	Publish(ctx context.Context, m Message) error
+	// Subscribe h to messages matching subject until ctx is closed
+	Subscribe(ctx context.Context, subject string, h Handler) error
+	// Close the transport
+	Close() error
+}
+
+// Match reports whether subject is matched by pattern
+func Match(pattern, subject string) bool {
+	p, s := strings.Split(pattern, "."), strings.Split(subject, ".")
+	for i, t := range p {
+		if t == ">" {
+			return len(s) > i
+		}
+		if i >= len(s) || (t != "*" && t != s[i]) {
+			return false
+		}
+	}
+	return len(p) == len(s)
+}
+
+// Token escapes s for use as a single subject token
+func Token(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', '/', '+', '#', ' ', '\t':
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// Memory is an in process Transport delivering messages synchronously to all matching subscriptions
+// It is meant for tests and single process deployments
+type Memory struct {
+	m      sync.Mutex
+	subs   map[int]*memorySub
+	next   int
+	closed bool
+}
+
+type memorySub struct {
+	subject string
+	h       Handler
+}
+
+// NewMemory transport
+func NewMemory() *Memory {
+	return &Memory{subs: make(map[int]*memorySub)}
+}
+
+// Publish m to all matching subscriptions
+func (t *Memory) Publish(ctx context.Context, m Message) error {
+	t.m.Lock()
+	if t.closed {
+		t.m.Unlock()
+		return ErrClosed
+	}
+	var handlers []Handler
+	for _, s := range t.subs {
+		if Match(s.subject, m.Subject) {
+			handlers = append(handlers, s.h)
+		}
+	}
+	t.m.Unlock()
+
+	for _, h := range handlers {
+		h(m)
+	}
+	return nil
+}
+
+// Subscribe h to messages matching subject until ctx is closed
+func (t *Memory) Subscribe(ctx context.Context, subject string, h Handler) error {
+	t.m.Lock()
+	defer t.m.Unlock()
+	if t.closed {
+		return ErrClosed
+	}
+	id := t.next
+	t.next++
+	t.subs[id] = &memorySub{subject, h}
+	go func() {
+		<-ctx.Done()
+		t.m.Lock()
+		defer t.m.Unlock()
+		delete(t.subs, id)
+	}()
+	return nil
+}
+
+// Close the transport removing all subscriptions
+func (t *Memory) Close() error {
+	t.m.Lock()
+	defer t.m.Unlock()
+	t.closed = true
+	t.subs = make(map[int]*memorySub)
+	return nil
+}
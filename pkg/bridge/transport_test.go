@@ -0,0 +1,82 @@
+package bridge_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/playnet-public/gorcon/pkg/bridge"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestBridge(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Bridge Suite")
+}
+
+var _ = Describe("Match", func() {
+	It("does match literal subjects", func() {
+		Expect(bridge.Match("server.a.chat", "server.a.chat")).To(BeTrue())
+		Expect(bridge.Match("server.a.chat", "server.b.chat")).To(BeFalse())
+		Expect(bridge.Match("server.a", "server.a.chat")).To(BeFalse())
+	})
+	It("does match single token wildcards", func() {
+		Expect(bridge.Match("server.*.chat", "server.a.chat")).To(BeTrue())
+		Expect(bridge.Match("server.*.chat", "server.a.player")).To(BeFalse())
+		Expect(bridge.Match("server.*", "server.a.chat")).To(BeFalse())
+	})
+	It("does match trailing wildcards", func() {
+		Expect(bridge.Match("server.>", "server.a.chat")).To(BeTrue())
+		Expect(bridge.Match("server.>", "server")).To(BeFalse())
+	})
+})
+
+var _ = Describe("Token", func() {
+	It("does escape separators and wildcards", func() {
+		Expect(bridge.Token("127.0.0.1:2302")).To(Equal("127_0_0_1:2302"))
+		Expect(bridge.Token("a*b>c/d+e#f g")).To(Equal("a_b_c_d_e_f_g"))
+	})
+})
+
+var _ = Describe("Topic", func() {
+	It("does convert subjects to mqtt topics and back", func() {
+		Expect(bridge.Topic("server.*.>")).To(Equal("server/+/#"))
+		Expect(bridge.Subject("server/+/#")).To(Equal("server.*.>"))
+	})
+})
+
+var _ = Describe("Memory", func() {
+	var (
+		ctx context.Context
+		t   *bridge.Memory
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		t = bridge.NewMemory()
+	})
+
+	It("does deliver messages to matching subscriptions", func() {
+		var got []string
+		Expect(t.Subscribe(ctx, "server.*.chat", func(m bridge.Message) { got = append(got, string(m.Data)) })).To(BeNil())
+		t.Publish(ctx, bridge.Message{Subject: "server.a.chat", Data: []byte("hi")})
+		t.Publish(ctx, bridge.Message{Subject: "server.a.player", Data: []byte("join")})
+		Expect(got).To(Equal([]string{"hi"}))
+	})
+	It("does unsubscribe on closed context", func() {
+		sub, cancel := context.WithCancel(ctx)
+		n := 0
+		t.Subscribe(sub, ">", func(bridge.Message) { n++ })
+		cancel()
+		Eventually(func() bool {
+			before := n
+			t.Publish(ctx, bridge.Message{Subject: "a"})
+			return n == before
+		}).Should(BeTrue())
+	})
+	It("does return error when closed", func() {
+		Expect(t.Close()).To(BeNil())
+		Expect(t.Publish(ctx, bridge.Message{Subject: "a"})).To(Equal(bridge.ErrClosed))
+		Expect(t.Subscribe(ctx, "a", func(bridge.Message) {})).To(Equal(bridge.ErrClosed))
+	})
+})
@@ -0,0 +1,388 @@
+// Package deploy stages mod sets and missions into versioned releases which get activated on restarts of the server
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/fileutil"
+	"github.com/playnet-public/gorcon/pkg/watcher"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// ErrNoPrevious is returned when rolling back without a previous release
+var ErrNoPrevious = errors.New("no previous release")
+
+// Release staged in its own directory below Root/releases
+// Directories starting with @ are mods, missions are kept in mpmissions
+type Release struct {
+	Version string
+	Dir     string
+	Mods    []string
+}
+
+// Deployer stages releases and activates them by swapping the Root/current symlink
+// Activation is deferred to the next start of the process, see Prepare
+type Deployer struct {
+	// Root containing the releases, the current symlink and the deployment state
+	Root string
+	// Required files relative to the release, e.g. "mpmissions/altis_life.Altis.pbo"
+	Required []string
+	// RequireKeys ensures every mod contains a bikey for signature verification
+	RequireKeys bool
+	// ServerMods are loaded using -serverMod instead of -mod
+	ServerMods []string
+	// Process receives the -mod and -serverMod args of the current release on every start
+	Process *watcher.OSProcess
+
+	m sync.Mutex
+}
+
+// state of the deployment persisted in Root/deploy.json
+type state struct {
+	Previous string    `json:"previous,omitempty"`
+	Pending  string    `json:"pending,omitempty"`
+	Updated  time.Time `json:"updated"`
+}
+
+// New Deployer keeping releases in root
+func New(root string, p *watcher.OSProcess) *Deployer {
+	return &Deployer{Root: root, Process: p}
+}
+
+// Current returns the version of the active release or an empty string if none got activated yet
+func (d *Deployer) Current() string {
+	target, err := os.Readlink(d.current())
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(target)
+}
+
+// Pending returns the version activated on the next start or an empty string
+func (d *Deployer) Pending() string {
+	d.m.Lock()
+	defer d.m.Unlock()
+	s, _ := d.load()
+	return s.Pending
+}
+
+// Release returns the staged release of version
+func (d *Deployer) Release(version string) (*Release, error) {
+	if err := checkVersion(version); err != nil {
+		return nil, err
+	}
+	dir := d.release(version)
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading release")
+	}
+	r := &Release{Version: version, Dir: dir}
+	for _, f := range files {
+		if f.IsDir() && strings.HasPrefix(f.Name(), "@") {
+			r.Mods = append(r.Mods, f.Name())
+		}
+	}
+	return r, nil
+}
+
+// Releases returns the versions of all staged releases
+func (d *Deployer) Releases() ([]string, error) {
+	files, err := ioutil.ReadDir(filepath.Join(d.Root, "releases"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading releases")
+	}
+	var versions []string
+	for _, f := range files {
+		if f.IsDir() && !strings.HasPrefix(f.Name(), ".") {
+			versions = append(versions, f.Name())
+		}
+	}
+	sort.Strings(versions)
+	return versions, nil
+}
+
+// Stage a copy of src as version after validating it
+func (d *Deployer) Stage(ctx context.Context, version, src string) (*Release, error) {
+	return d.stage(ctx, version, func(dir string) error {
+		return fileutil.CopyTree(src, dir, fileutil.Options{Symlinks: true})
+	})
+}
+
+// StageMission stages version as the current release with the mission pbo added to mpmissions
+// Files of the current release are hard linked where possible, so only the mission takes up space
+func (d *Deployer) StageMission(ctx context.Context, version, pbo string) (*Release, error) {
+	current := d.Current()
+	if current == "" {
+		return nil, errors.New("no current release to add the mission to")
+	}
+	return d.stage(ctx, version, func(dir string) error {
+		if err := fileutil.CopyTree(d.release(current), dir, fileutil.Options{Link: true, Symlinks: true}); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Join(dir, "mpmissions"), 0755); err != nil {
+			return errors.Wrap(err, "creating mpmissions")
+		}
+		// replace older versions of the mission without touching the linked file of the current release
+		target := filepath.Join(dir, "mpmissions", filepath.Base(pbo))
+		os.Remove(target)
+		return fileutil.CopyFile(pbo, target, fileutil.Options{})
+	})
+}
+
+// stage version by filling a temporary directory using fill and renaming it once valid
+func (d *Deployer) stage(ctx context.Context, version string, fill func(dir string) error) (*Release, error) {
+	if err := checkVersion(version); err != nil {
+		return nil, err
+	}
+	dir := d.release(version)
+	if _, err := os.Stat(dir); err == nil {
+		return nil, errors.Errorf("release %s already exists", version)
+	}
+	tmp := filepath.Join(d.Root, "releases", "."+version+".staging")
+	os.RemoveAll(tmp)
+	if err := fill(tmp); err != nil {
+		os.RemoveAll(tmp)
+		deployStaged.With("error").Inc()
+		return nil, errors.Wrap(err, "staging release")
+	}
+	if err := d.Validate(tmp); err != nil {
+		os.RemoveAll(tmp)
+		deployStaged.With("invalid").Inc()
+		return nil, err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		os.RemoveAll(tmp)
+		deployStaged.With("error").Inc()
+		return nil, errors.Wrap(err, "staging release")
+	}
+	deployStaged.With("ok").Inc()
+	log.From(ctx).Info("staged release", zap.String("version", version), zap.String("dir", dir))
+	return d.Release(version)
+}
+
+// Validate the release in dir, checking for required files, mod addons and keys
+func (d *Deployer) Validate(dir string) error {
+	var problems []string
+	for _, r := range d.Required {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(r))); err != nil {
+			problems = append(problems, "missing "+r)
+		}
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return errors.Wrap(err, "reading release")
+	}
+	for _, f := range files {
+		if !f.IsDir() || !strings.HasPrefix(f.Name(), "@") {
+			continue
+		}
+		mod := filepath.Join(dir, f.Name())
+		if !contains(mod, []string{"addons"}, ".pbo") {
+			problems = append(problems, f.Name()+" has no addons")
+		}
+		if d.RequireKeys && !contains(mod, []string{"keys", "key"}, ".bikey") {
+			problems = append(problems, f.Name()+" has no key")
+		}
+	}
+	if len(problems) > 0 {
+		return errors.Errorf("invalid release: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// Schedule version to be activated on the next start of the process
+func (d *Deployer) Schedule(ctx context.Context, version string) error {
+	if _, err := d.Release(version); err != nil {
+		return err
+	}
+	d.m.Lock()
+	defer d.m.Unlock()
+	s, err := d.load()
+	if err != nil {
+		return err
+	}
+	s.Pending = version
+	log.From(ctx).Info("scheduled release", zap.String("version", version))
+	return d.save(s)
+}
+
+// Rollback schedules the previously active release for the next start of the process
+func (d *Deployer) Rollback(ctx context.Context) error {
+	d.m.Lock()
+	s, err := d.load()
+	d.m.Unlock()
+	if err != nil {
+		return err
+	}
+	if s.Previous == "" {
+		return ErrNoPrevious
+	}
+	return d.Schedule(ctx, s.Previous)
+}
+
+// Prepare activates the scheduled release and sets the mod args of Process. It is meant to be used as Watcher.Prepare
+func (d *Deployer) Prepare(ctx context.Context) error {
+	d.m.Lock()
+	defer d.m.Unlock()
+	s, err := d.load()
+	if err != nil {
+		return err
+	}
+	if s.Pending != "" {
+		current := d.Current()
+		if err := d.activate(s.Pending); err != nil {
+			deployActivations.With("error").Inc()
+			return errors.Wrapf(err, "activating release %s", s.Pending)
+		}
+		deployActivations.With("ok").Inc()
+		log.From(ctx).Info("activated release", zap.String("version", s.Pending), zap.String("previous", current))
+		if current != s.Pending {
+			s.Previous = current
+		}
+		s.Pending = ""
+		if err := d.save(s); err != nil {
+			return err
+		}
+	}
+
+	if d.Process == nil || d.Current() == "" {
+		return nil
+	}
+	r, err := d.Release(d.Current())
+	if err != nil {
+		return err
+	}
+	d.Process.Cmd.Args = d.Args(d.Process.Cmd.Args, r)
+	return nil
+}
+
+// Args returns args with the -mod and -serverMod args replaced by the mods of r loaded through the current symlink
+func (d *Deployer) Args(args []string, r *Release) []string {
+	var mods, serverMods []string
+	for _, m := range r.Mods {
+		path := filepath.Join(d.current(), m)
+		if containsString(d.ServerMods, m) {
+			serverMods = append(serverMods, path)
+		} else {
+			mods = append(mods, path)
+		}
+	}
+
+	var out []string
+	for _, a := range args {
+		l := strings.ToLower(a)
+		if strings.HasPrefix(l, "-mod=") || strings.HasPrefix(l, "-servermod=") {
+			continue
+		}
+		out = append(out, a)
+	}
+	if len(mods) > 0 {
+		out = append(out, "-mod="+strings.Join(mods, ";"))
+	}
+	if len(serverMods) > 0 {
+		out = append(out, "-serverMod="+strings.Join(serverMods, ";"))
+	}
+	return out
+}
+
+// activate version by atomically replacing the current symlink. The caller has to hold the lock
+func (d *Deployer) activate(version string) error {
+	if _, err := os.Stat(d.release(version)); err != nil {
+		return err
+	}
+	tmp := d.current() + ".tmp"
+	os.Remove(tmp)
+	if err := os.Symlink(filepath.Join("releases", version), tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, d.current())
+}
+
+func (d *Deployer) current() string {
+	return filepath.Join(d.Root, "current")
+}
+
+func (d *Deployer) release(version string) string {
+	return filepath.Join(d.Root, "releases", version)
+}
+
+// load the state. The caller has to hold the lock
+func (d *Deployer) load() (state, error) {
+	var s state
+	data, err := ioutil.ReadFile(filepath.Join(d.Root, "deploy.json"))
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, errors.Wrap(err, "reading state")
+	}
+	return s, errors.Wrap(json.Unmarshal(data, &s), "decoding state")
+}
+
+// save the state. The caller has to hold the lock
+func (d *Deployer) save(s state) error {
+	s.Updated = time.Now().UTC()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "encoding state")
+	}
+	tmp := filepath.Join(d.Root, ".deploy.json.tmp")
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return errors.Wrap(err, "writing state")
+	}
+	return errors.Wrap(os.Rename(tmp, filepath.Join(d.Root, "deploy.json")), "writing state")
+}
+
+func checkVersion(version string) error {
+	if version == "" || version == "." || version == ".." || strings.HasPrefix(version, ".") || strings.ContainsAny(version, `/\`) {
+		return errors.Errorf("invalid version %q", version)
+	}
+	return nil
+}
+
+// contains reports whether one of the subdirectories of dir, compared case insensitive, holds a file with ext
+func contains(dir string, subdirs []string, ext string) bool {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, f := range files {
+		if !f.IsDir() || !containsString(subdirs, strings.ToLower(f.Name())) {
+			continue
+		}
+		entries, err := ioutil.ReadDir(filepath.Join(dir, f.Name()))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if strings.EqualFold(filepath.Ext(e.Name()), ext) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
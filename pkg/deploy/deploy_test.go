@@ -0,0 +1,178 @@
+package deploy_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/playnet-public/gorcon/pkg/deploy"
+	"github.com/playnet-public/gorcon/pkg/watcher"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/seibert-media/golibs/log"
+)
+
+func TestDeploy(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Deploy Suite")
+}
+
+var _ = Describe("Deployer", func() {
+	var (
+		ctx  context.Context
+		tmp  string
+		root string
+		p    *watcher.OSProcess
+		d    *deploy.Deployer
+	)
+
+	write := func(path, data string) {
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(BeNil())
+		Expect(ioutil.WriteFile(path, []byte(data), 0644)).To(BeNil())
+	}
+	// source creates a release source with mods and a mission
+	source := func(name string, mods ...string) string {
+		src := filepath.Join(tmp, name)
+		for _, m := range mods {
+			write(filepath.Join(src, m, "addons", "main.pbo"), m)
+			write(filepath.Join(src, m, "Keys", m+".bikey"), m)
+		}
+		write(filepath.Join(src, "mpmissions", "life.Altis.pbo"), name)
+		return src
+	}
+	read := func(path string) string {
+		data, err := ioutil.ReadFile(path)
+		Expect(err).To(BeNil())
+		return string(data)
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "deploy")
+		Expect(err).To(BeNil())
+		root = filepath.Join(tmp, "root")
+		Expect(os.MkdirAll(root, 0755)).To(BeNil())
+		ctx = log.WithLogger(context.Background(), log.NewNop())
+		p = watcher.NewOSProcess("arma3server", "-port=2302", "-mod=old")
+		d = deploy.New(root, p)
+		d.RequireKeys = true
+	})
+	AfterEach(func() {
+		os.RemoveAll(tmp)
+	})
+
+	Describe("Stage", func() {
+		It("does copy the release", func() {
+			r, err := d.Stage(ctx, "v1", source("src", "@cba", "@ace"))
+			Expect(err).To(BeNil())
+			Expect(r.Version).To(Equal("v1"))
+			Expect(r.Mods).To(Equal([]string{"@ace", "@cba"}))
+			Expect(read(filepath.Join(root, "releases", "v1", "mpmissions", "life.Altis.pbo"))).To(Equal("src"))
+			Expect(d.Releases()).To(Equal([]string{"v1"}))
+		})
+		It("does reject invalid releases", func() {
+			src := source("src", "@cba")
+			Expect(os.RemoveAll(filepath.Join(src, "@cba", "Keys"))).To(BeNil())
+			write(filepath.Join(src, "@empty", "readme.txt"), "")
+			d.Required = []string{"mpmissions/other.Altis.pbo"}
+
+			_, err := d.Stage(ctx, "v1", src)
+			Expect(err).NotTo(BeNil())
+			Expect(err.Error()).To(ContainSubstring("missing mpmissions/other.Altis.pbo"))
+			Expect(err.Error()).To(ContainSubstring("@cba has no key"))
+			Expect(err.Error()).To(ContainSubstring("@empty has no addons"))
+			Expect(d.Releases()).To(BeEmpty())
+		})
+		It("does reject existing and invalid versions", func() {
+			_, err := d.Stage(ctx, "v1", source("src", "@cba"))
+			Expect(err).To(BeNil())
+			_, err = d.Stage(ctx, "v1", source("src", "@cba"))
+			Expect(err).NotTo(BeNil())
+			_, err = d.Stage(ctx, "../v2", source("src", "@cba"))
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
+	Describe("Prepare", func() {
+		It("does activate scheduled releases and set mod args", func() {
+			d.ServerMods = []string{"@server"}
+			_, err := d.Stage(ctx, "v1", source("src", "@cba", "@server"))
+			Expect(err).To(BeNil())
+			Expect(d.Schedule(ctx, "v1")).To(BeNil())
+			Expect(d.Pending()).To(Equal("v1"))
+			Expect(d.Current()).To(BeEmpty())
+
+			Expect(d.Prepare(ctx)).To(BeNil())
+			Expect(d.Current()).To(Equal("v1"))
+			Expect(d.Pending()).To(BeEmpty())
+			Expect(read(filepath.Join(root, "current", "mpmissions", "life.Altis.pbo"))).To(Equal("src"))
+			Expect(p.Cmd.Args).To(Equal([]string{
+				"arma3server", "-port=2302",
+				"-mod=" + filepath.Join(root, "current", "@cba"),
+				"-serverMod=" + filepath.Join(root, "current", "@server"),
+			}))
+		})
+		It("does keep the current release without schedule", func() {
+			Expect(d.Prepare(ctx)).To(BeNil())
+			Expect(d.Current()).To(BeEmpty())
+			Expect(p.Cmd.Args).To(Equal([]string{"arma3server", "-port=2302", "-mod=old"}))
+		})
+		It("does run with the watcher", func() {
+			_, err := d.Stage(ctx, "v1", source("src", "@cba"))
+			Expect(err).To(BeNil())
+			Expect(d.Schedule(ctx, "v1")).To(BeNil())
+			p = watcher.NewOSProcess("true")
+			d.Process = p
+			w := watcher.NewWatcher(ctx, "server")
+			w.Process = p
+			w.Prepare = d.Prepare
+
+			Expect(w.Start(ctx)).To(BeNil())
+			Expect(d.Current()).To(Equal("v1"))
+			Expect(p.Cmd.Args).To(ContainElement("-mod=" + filepath.Join(root, "current", "@cba")))
+		})
+	})
+
+	Describe("Rollback", func() {
+		It("does schedule the previous release", func() {
+			Expect(d.Rollback(ctx)).To(Equal(deploy.ErrNoPrevious))
+
+			_, err := d.Stage(ctx, "v1", source("v1src", "@cba"))
+			Expect(err).To(BeNil())
+			_, err = d.Stage(ctx, "v2", source("v2src", "@cba", "@ace"))
+			Expect(err).To(BeNil())
+			Expect(d.Schedule(ctx, "v1")).To(BeNil())
+			Expect(d.Prepare(ctx)).To(BeNil())
+			Expect(d.Schedule(ctx, "v2")).To(BeNil())
+			Expect(d.Prepare(ctx)).To(BeNil())
+			Expect(d.Current()).To(Equal("v2"))
+
+			Expect(d.Rollback(ctx)).To(BeNil())
+			Expect(d.Pending()).To(Equal("v1"))
+			Expect(d.Prepare(ctx)).To(BeNil())
+			Expect(d.Current()).To(Equal("v1"))
+			Expect(p.Cmd.Args).To(Equal([]string{"arma3server", "-port=2302", "-mod=" + filepath.Join(root, "current", "@cba")}))
+		})
+	})
+
+	Describe("StageMission", func() {
+		It("does add the mission to a copy of the current release", func() {
+			_, err := d.Stage(ctx, "v1", source("src", "@cba"))
+			Expect(err).To(BeNil())
+			_, err = d.StageMission(ctx, "v2", filepath.Join(tmp, "life.Altis.pbo"))
+			Expect(err).NotTo(BeNil())
+
+			Expect(d.Schedule(ctx, "v1")).To(BeNil())
+			Expect(d.Prepare(ctx)).To(BeNil())
+			write(filepath.Join(tmp, "life.Altis.pbo"), "updated")
+			r, err := d.StageMission(ctx, "v2", filepath.Join(tmp, "life.Altis.pbo"))
+			Expect(err).To(BeNil())
+			Expect(r.Mods).To(Equal([]string{"@cba"}))
+			Expect(read(filepath.Join(root, "releases", "v2", "mpmissions", "life.Altis.pbo"))).To(Equal("updated"))
+			Expect(read(filepath.Join(root, "releases", "v1", "mpmissions", "life.Altis.pbo"))).To(Equal("src"))
+		})
+	})
+})
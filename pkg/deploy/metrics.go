@@ -0,0 +1,10 @@
+package deploy
+
+import (
+	"github.com/playnet-public/gorcon/pkg/metrics"
+)
+
+var (
+	deployStaged      = metrics.NewCounterVec("gorcon_deploy_staged_total", "Releases staged by result.", "result")
+	deployActivations = metrics.NewCounterVec("gorcon_deploy_activations_total", "Releases activated on process starts by result.", "result")
+)
@@ -3,6 +3,10 @@ package event
 import (
 	"context"
 	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/trace"
 
 	"github.com/seibert-media/golibs/log"
 	"go.uber.org/zap"
@@ -13,35 +17,93 @@ var (
 	ErrInputClosed = errors.New("input channel closed")
 )
 
+// Overflow policy applied when the queue of a subscription is full
+type Overflow int
+
+const (
+	// DropOldest removes the oldest queued event to make room for the new one
+	DropOldest Overflow = iota
+	// DropNewest discards the new event
+	DropNewest
+	// Block the broker until the subscription has room or BlockTimeout passed, then discard the new event
+	Block
+	// Disconnect closes the subscription
+	Disconnect
+)
+
+// String returns the name of the policy
+func (o Overflow) String() string {
+	switch o {
+	case DropOldest:
+		return "drop_oldest"
+	case DropNewest:
+		return "drop_newest"
+	case Block:
+		return "block"
+	case Disconnect:
+		return "disconnect"
+	}
+	return "unknown"
+}
+
+// Reasons for dropping events
+const (
+	reasonNoSubscribers = "no_subscribers"
+	reasonOverflow      = "overflow"
+	reasonDisconnected  = "disconnected"
+)
+
 // Broker for subscribing to an eventsource with multiple subscriptions automatically canceled on ctx.Close
+// Every subscription has a bounded queue delivering events in the order they were received
 type Broker struct {
-	new    chan chan<- Event
-	active map[chan<- Event]struct{}
+	// Name identifies the broker in metrics
+	Name string
+	// Buffer is the number of events queued per subscription
+	Buffer int
+	// Overflow policy for subscriptions not keeping up
+	Overflow Overflow
+	// BlockTimeout is the maximum time the Block policy waits for a subscription
+	BlockTimeout time.Duration
+	// Log records all events for replaying them to subscriptions. Events are published as Record when set
+	Log Log
+
+	new    chan *subscription
+	active map[chan<- Event]*subscription
 	closed chan chan<- Event
 
-	in <-chan Event
+	in      <-chan Event
+	dropped uint64
 }
 
 // NewBroker with the provided input channel as event source
 // A running broker will handle all incoming events by sending them to all active subscriptions
 func NewBroker(ctx context.Context, in <-chan Event) *Broker {
 	return &Broker{
-		new:    make(chan chan<- Event),
-		active: make(map[chan<- Event]struct{}),
+		Buffer:       64,
+		Overflow:     DropOldest,
+		BlockTimeout: 1 * time.Second,
+
+		new:    make(chan *subscription),
+		active: make(map[chan<- Event]*subscription),
 		closed: make(chan chan<- Event),
 
 		in: in,
 	}
 }
 
+// Dropped returns the number of events not delivered to a subscription
+func (b *Broker) Dropped() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
+
 // Run the broker and listen for new subscriptions, events and unsubscribes
 // The broker will run until either it's parent context closes or the incoming event channel gets closed
 func (b *Broker) Run(ctx context.Context) error {
 	defer func() {
-		for s := range b.active {
-			delete(b.active, s)
-			close(s)
+		for out := range b.active {
+			b.remove(out)
 		}
+		brokerSubscribers.With(b.Name).Set(0)
 	}()
 	for {
 		select {
@@ -50,12 +112,11 @@ func (b *Broker) Run(ctx context.Context) error {
 			return ctx.Err()
 
 		case s := <-b.new:
-			b.active[s] = struct{}{}
+			b.add(s)
 			log.From(ctx).Debug("subscribing", zap.Int("count", len(b.active)))
 
-		case s := <-b.closed:
-			delete(b.active, s)
-			close(s)
+		case out := <-b.closed:
+			b.remove(out)
 			log.From(ctx).Debug("unsubscribing", zap.Int("count", len(b.active)))
 
 		case event, ok := <-b.in:
@@ -63,12 +124,120 @@ func (b *Broker) Run(ctx context.Context) error {
 				log.From(ctx).Info("stopping background loop")
 				return ErrInputClosed
 			}
+			b.publish(ctx, event)
+		}
+	}
+}
 
-			for s := range b.active {
-				log.From(ctx).Debug("handling event", zap.String("data", event.Data()))
-				// TODO(kwiesmueller): make sure we don't leak here and need an unsubscribing timeout
-				go func(s chan<- Event) { s <- event }(s)
-			}
+func (b *Broker) publish(ctx context.Context, event Event) {
+	_, span := trace.Start(ctx, "broker.Publish")
+	defer span.End()
+	span.SetAttribute("broker.name", b.Name)
+	span.SetAttribute("broker.subscribers", len(b.active))
+	brokerEvents.With(b.Name).Inc()
+	if b.Log != nil {
+		r := FromEvent(event)
+		if err := b.Log.Append(r); err != nil {
+			span.RecordError(err)
+			log.From(ctx).Error("logging event", zap.String("broker", b.Name), zap.Error(err))
 		}
+		span.SetAttribute("broker.offset", int(r.Offset))
+		event = r
+	}
+	if len(b.active) < 1 {
+		b.drop(reasonNoSubscribers, 1)
+		return
+	}
+
+	log.From(ctx).Debug("handling event", zap.String("data", event.Data()))
+	for out, s := range b.active {
+		if !s.filter.Match(event) {
+			continue
+		}
+		if !b.enqueue(ctx, s, event) {
+			log.From(ctx).Warn("disconnecting subscription", zap.String("broker", b.Name), zap.String("reason", "queue full"))
+			b.remove(out)
+		}
+	}
+}
+
+// enqueue event for s applying the overflow policy. Returns false if s has to be disconnected
+func (b *Broker) enqueue(ctx context.Context, s *subscription, event Event) bool {
+	select {
+	case s.queue <- event:
+		return true
+	default:
+	}
+
+	switch b.Overflow {
+	case DropOldest:
+		// only the broker sends to the queue, so after taking one there is room
+		select {
+		case <-s.queue:
+			b.drop(reasonOverflow, 1)
+		default:
+		}
+		select {
+		case s.queue <- event:
+		default:
+			b.drop(reasonOverflow, 1)
+		}
+	case Block:
+		t := time.NewTimer(b.BlockTimeout)
+		defer t.Stop()
+		select {
+		case s.queue <- event:
+		case <-t.C:
+			b.drop(reasonOverflow, 1)
+		case <-ctx.Done():
+			b.drop(reasonOverflow, 1)
+		}
+	case Disconnect:
+		b.drop(reasonOverflow, 1)
+		return false
+	default:
+		b.drop(reasonOverflow, 1)
+	}
+	return true
+}
+
+func (b *Broker) add(s *subscription) {
+	if _, ok := b.active[s.out]; ok {
+		return
+	}
+	size := b.Buffer
+	if size < 1 {
+		size = 1
+	}
+	s.queue = make(chan Event, size)
+	s.stop = make(chan struct{})
+	s.broker = b.Name
+	if s.from != nil && b.Log != nil {
+		// everything published from now on gets queued
+		s.log = b.Log
+		s.until = b.Log.Next()
+	}
+	b.active[s.out] = s
+	brokerSubscribers.With(b.Name).Set(float64(len(b.active)))
+	go s.forward()
+}
+
+// remove the subscription of out, closing out once its forwarder stopped
+func (b *Broker) remove(out chan<- Event) {
+	s, ok := b.active[out]
+	if !ok {
+		return
+	}
+	delete(b.active, out)
+	close(s.stop)
+	b.drop(reasonDisconnected, len(s.queue))
+	brokerSubscribers.With(b.Name).Set(float64(len(b.active)))
+}
+
+func (b *Broker) drop(reason string, n int) {
+	if n < 1 {
+		return
 	}
+	atomic.AddUint64(&b.dropped, uint64(n))
+	brokerDropped.With(b.Name, reason).Add(float64(n))
 }
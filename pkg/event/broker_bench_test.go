@@ -0,0 +1,76 @@
+package event_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+
+	"github.com/seibert-media/golibs/log"
+)
+
+// legacyFanout sends every event from in to subs spawning one goroutine per event and subscriber
+// like the broker did before using bounded queues
+func legacyFanout(in <-chan event.Event, subs []chan event.Event) {
+	for e := range in {
+		for _, s := range subs {
+			go func(s chan<- event.Event) { s <- e }(s)
+		}
+	}
+}
+
+func benchmarkFanout(b *testing.B, subscribers int, run func(ctx context.Context, in chan event.Event, subs []chan event.Event)) {
+	ctx, cancel := context.WithCancel(log.WithLogger(context.Background(), log.NewNop()))
+	defer cancel()
+	in := make(chan event.Event)
+	subs := make([]chan event.Event, subscribers)
+	for i := range subs {
+		subs[i] = make(chan event.Event)
+	}
+	run(ctx, in, subs)
+
+	var wg sync.WaitGroup
+	wg.Add(subscribers)
+	for _, s := range subs {
+		go func(s <-chan event.Event) {
+			defer wg.Done()
+			for i := 0; i < b.N; i++ {
+				<-s
+			}
+		}(s)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		in <- seqEvent{i}
+	}
+	wg.Wait()
+}
+
+func BenchmarkBroker(b *testing.B) {
+	for _, n := range []int{1, 10, 100} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			benchmarkFanout(b, n, func(ctx context.Context, in chan event.Event, subs []chan event.Event) {
+				br := event.NewBroker(ctx, in)
+				br.Overflow = event.Block
+				go br.Run(ctx)
+				for _, s := range subs {
+					br.Subscribe(ctx, s)
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkLegacyBroker(b *testing.B) {
+	for _, n := range []int{1, 10, 100} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			benchmarkFanout(b, n, func(ctx context.Context, in chan event.Event, subs []chan event.Event) {
+				go legacyFanout(in, subs)
+			})
+		})
+	}
+}
@@ -2,6 +2,7 @@ package event_test
 
 import (
 	"context"
+	"strconv"
 	"testing"
 	"time"
 
@@ -28,6 +29,12 @@ func (f *fakeEvent) Timestamp() time.Time {
 func (f *fakeEvent) Kind() string { return "fake" }
 func (f *fakeEvent) Data() string { return "fake" }
 
+type seqEvent struct{ n int }
+
+func (s seqEvent) Timestamp() time.Time { return time.Time{} }
+func (s seqEvent) Kind() string         { return "seq" }
+func (s seqEvent) Data() string         { return strconv.Itoa(s.n) }
+
 var _ = Describe("Event", func() {
 
 	setup := func() (ctx context.Context, in chan event.Event, b *event.Broker) {
@@ -142,4 +149,113 @@ var _ = Describe("Event", func() {
 			}
 		})
 	})
+
+	Describe("Overflow", func() {
+		publish := func(in chan<- event.Event, n int) {
+			for i := 0; i < n; i++ {
+				in <- seqEvent{i}
+			}
+		}
+		// sync waits until the broker handled all events sent before
+		sync := func(ctx context.Context, b *event.Broker) {
+			b.Subscribe(ctx, make(chan event.Event))
+		}
+		received := func(c <-chan event.Event) (n []int) {
+			for {
+				select {
+				case e := <-c:
+					i, _ := strconv.Atoi(e.Data())
+					n = append(n, i)
+				case <-time.After(10 * time.Millisecond):
+					return
+				}
+			}
+		}
+
+		It("delivers events in order", func() {
+			ctx, in, b := setup()
+			b.Buffer = 100
+			go b.Run(ctx)
+			c := make(chan event.Event)
+			b.Subscribe(ctx, c)
+			publish(in, 100)
+			n := received(c)
+			Expect(n).To(HaveLen(100))
+			for i := range n {
+				Expect(n[i]).To(Equal(i))
+			}
+			Expect(b.Dropped()).To(BeZero())
+		})
+		It("drops the oldest events", func() {
+			ctx, in, b := setup()
+			b.Buffer = 2
+			b.Overflow = event.DropOldest
+			go b.Run(ctx)
+			c := make(chan event.Event)
+			b.Subscribe(ctx, c)
+			publish(in, 5)
+			sync(ctx, b)
+			n := received(c)
+			// the forwarder might already hold the first event
+			Expect(n[len(n)-2:]).To(Equal([]int{3, 4}))
+			Expect(b.Dropped()).To(BeEquivalentTo(5 - len(n)))
+		})
+		It("drops the newest events", func() {
+			ctx, in, b := setup()
+			b.Buffer = 2
+			b.Overflow = event.DropNewest
+			go b.Run(ctx)
+			c := make(chan event.Event)
+			b.Subscribe(ctx, c)
+			publish(in, 5)
+			sync(ctx, b)
+			n := received(c)
+			Expect(n[:2]).To(Equal([]int{0, 1}))
+			Expect(b.Dropped()).To(BeEquivalentTo(5 - len(n)))
+		})
+		It("blocks until the subscription has room", func() {
+			ctx, in, b := setup()
+			b.Buffer = 1
+			b.Overflow = event.Block
+			b.BlockTimeout = time.Second
+			go b.Run(ctx)
+			c := make(chan event.Event)
+			b.Subscribe(ctx, c)
+			go publish(in, 5)
+			time.Sleep(5 * time.Millisecond)
+			Expect(received(c)).To(Equal([]int{0, 1, 2, 3, 4}))
+			Expect(b.Dropped()).To(BeZero())
+		})
+		It("drops events after blocking for BlockTimeout", func() {
+			ctx, in, b := setup()
+			b.Buffer = 1
+			b.Overflow = event.Block
+			b.BlockTimeout = time.Millisecond
+			go b.Run(ctx)
+			c := make(chan event.Event)
+			b.Subscribe(ctx, c)
+			publish(in, 5)
+			sync(ctx, b)
+			Expect(b.Dropped()).NotTo(BeZero())
+		})
+		It("disconnects subscriptions not keeping up", func() {
+			ctx, in, b := setup()
+			b.Buffer = 2
+			b.Overflow = event.Disconnect
+			go b.Run(ctx)
+			slow := make(chan event.Event)
+			b.Subscribe(ctx, slow)
+			fast := make(chan event.Event)
+			b.Subscribe(ctx, fast)
+			for i := 0; i < 10; i++ {
+				in <- seqEvent{i}
+				Expect(<-fast).To(Equal(seqEvent{i}))
+			}
+			Expect(b.Dropped()).NotTo(BeZero())
+			Eventually(func() bool {
+				_, ok := <-slow
+				return ok
+			}).Should(BeFalse())
+		})
+	})
 })
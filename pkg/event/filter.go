@@ -0,0 +1,60 @@
+package event
+
+import (
+	"regexp"
+)
+
+// Sourced is implemented by events knowing the server they originate from
+type Sourced interface {
+	Server() string
+}
+
+// Filter selects events for a subscription. Empty fields match every event and all set fields have to match
+type Filter struct {
+	// Kinds of events to receive
+	Kinds []Kind
+	// Servers the events originate from. Events not implementing Sourced never match
+	Servers []string
+	// Data matches the payload of events
+	Data *regexp.Regexp
+	// Predicate for everything not covered by the other fields
+	Predicate func(Event) bool
+}
+
+// Match reports whether e is selected by f
+func (f Filter) Match(e Event) bool {
+	if len(f.Kinds) > 0 && !containsKind(f.Kinds, Kind(e.Kind())) {
+		return false
+	}
+	if len(f.Servers) > 0 {
+		s, ok := e.(Sourced)
+		if !ok || !contains(f.Servers, s.Server()) {
+			return false
+		}
+	}
+	if f.Data != nil && !f.Data.MatchString(e.Data()) {
+		return false
+	}
+	if f.Predicate != nil && !f.Predicate(e) {
+		return false
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsKind(list []Kind, k Kind) bool {
+	for _, v := range list {
+		if v == k {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package event_test
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/seibert-media/golibs/log"
+)
+
+type serverEvent struct {
+	server, kind, data string
+}
+
+func (s serverEvent) Timestamp() time.Time { return time.Time{} }
+func (s serverEvent) Kind() string         { return s.kind }
+func (s serverEvent) Data() string         { return s.data }
+func (s serverEvent) Server() string       { return s.server }
+
+var _ = Describe("Filter", func() {
+	e := serverEvent{"a", "chat", "(Global) Test: hi"}
+
+	Describe("Match", func() {
+		It("does match everything when empty", func() {
+			Expect(event.Filter{}.Match(e)).To(BeTrue())
+			Expect(event.Filter{}.Match(&fakeEvent{})).To(BeTrue())
+		})
+		It("does match kinds", func() {
+			Expect(event.Filter{Kinds: []event.Kind{"player", "chat"}}.Match(e)).To(BeTrue())
+			Expect(event.Filter{Kinds: []event.Kind{"player"}}.Match(e)).To(BeFalse())
+		})
+		It("does match servers", func() {
+			Expect(event.Filter{Servers: []string{"a"}}.Match(e)).To(BeTrue())
+			Expect(event.Filter{Servers: []string{"b"}}.Match(e)).To(BeFalse())
+		})
+		It("does not match servers of events without source", func() {
+			Expect(event.Filter{Servers: []string{"a"}}.Match(&fakeEvent{})).To(BeFalse())
+		})
+		It("does match data", func() {
+			Expect(event.Filter{Data: regexp.MustCompile(`^\(Global\)`)}.Match(e)).To(BeTrue())
+			Expect(event.Filter{Data: regexp.MustCompile(`^\(Side\)`)}.Match(e)).To(BeFalse())
+		})
+		It("does match predicates", func() {
+			Expect(event.Filter{Predicate: func(event.Event) bool { return true }}.Match(e)).To(BeTrue())
+			Expect(event.Filter{Predicate: func(event.Event) bool { return false }}.Match(e)).To(BeFalse())
+		})
+		It("does require all fields to match", func() {
+			Expect(event.Filter{Kinds: []event.Kind{"chat"}, Servers: []string{"b"}}.Match(e)).To(BeFalse())
+		})
+	})
+
+	Describe("SubscribeFilter", func() {
+		It("does only forward matching events", func() {
+			ctx := log.WithLogger(context.Background(), log.New("", debug))
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			in := make(chan event.Event)
+			b := event.NewBroker(ctx, in)
+			go b.Run(ctx)
+
+			chat := make(chan event.Event, 10)
+			b.SubscribeFilter(ctx, chat, event.Filter{Kinds: []event.Kind{"chat"}})
+			all := make(chan event.Event, 10)
+			b.Subscribe(ctx, all)
+
+			in <- serverEvent{"a", "StdOut", "line"}
+			in <- e
+			Eventually(all).Should(HaveLen(2))
+			Expect(<-chat).To(Equal(e))
+			Consistently(chat, 10*time.Millisecond).ShouldNot(Receive())
+			Expect(b.Dropped()).To(BeZero())
+		})
+	})
+})
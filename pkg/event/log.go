@@ -0,0 +1,107 @@
+package event
+
+import (
+	"sync"
+	"time"
+)
+
+// Log stores events for replaying them to late subscribers
+// Offsets are assigned in the order records get appended starting at 1
+//go:generate counterfeiter -o ../mocks/event_log.go --fake-name EventLog . Log
+type Log interface {
+	// Append r assigning its offset
+	Append(r *Record) error
+	// Next returns the offset the next appended record gets assigned
+	Next() uint64
+	// Read retained records with offsets in [from, until) in order, stopping early once fn returns false
+	Read(from, until uint64, fn func(*Record) bool) error
+}
+
+// Retention limits the records kept by a Log. Zero values retain everything
+type Retention struct {
+	// MaxCount of records
+	MaxCount int
+	// MaxAge of records
+	MaxAge time.Duration
+}
+
+// Position in a Log to start a subscription from
+type Position struct {
+	// Offset of the first record to replay
+	Offset uint64
+	// Since skips records older than Since
+	Since time.Time
+}
+
+// Ring is an in memory Log
+type Ring struct {
+	Retention Retention
+
+	m sync.Mutex
+	// records from start on are retained, the ones before got dropped
+	records []*Record
+	start   int
+	next    uint64
+}
+
+// NewRing returns an empty in memory Log retaining records according to r
+func NewRing(r Retention) *Ring {
+	return &Ring{Retention: r, next: 1}
+}
+
+// Append r to the ring dropping records exceeding the retention
+func (l *Ring) Append(r *Record) error {
+	l.m.Lock()
+	defer l.m.Unlock()
+	r.Offset = l.next
+	l.next++
+	l.records = append(l.records, r)
+
+	for l.Retention.MaxCount > 0 && len(l.records)-l.start > l.Retention.MaxCount {
+		l.drop()
+	}
+	if l.Retention.MaxAge > 0 {
+		cutoff := time.Now().Add(-l.Retention.MaxAge)
+		for l.start < len(l.records) && l.records[l.start].Time.Before(cutoff) {
+			l.drop()
+		}
+	}
+	if l.start > len(l.records)-l.start {
+		// only copy once more records got dropped than retained, so appending stays constant on average
+		l.records = append([]*Record(nil), l.records[l.start:]...)
+		l.start = 0
+	}
+	return nil
+}
+
+// drop the oldest retained record letting it get collected
+func (l *Ring) drop() {
+	l.records[l.start] = nil
+	l.start++
+}
+
+// Next returns the offset the next appended record gets assigned
+func (l *Ring) Next() uint64 {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.next
+}
+
+// Read retained records with offsets in [from, until)
+func (l *Ring) Read(from, until uint64, fn func(*Record) bool) error {
+	l.m.Lock()
+	var records []*Record
+	for _, r := range l.records[l.start:] {
+		if r.Offset >= from && r.Offset < until {
+			records = append(records, r)
+		}
+	}
+	l.m.Unlock()
+
+	for _, r := range records {
+		if !fn(r) {
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,333 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+	"github.com/playnet-public/gorcon/pkg/mocks"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/seibert-media/golibs/log"
+)
+
+func record(data string) *event.Record {
+	return event.New(event.Source{Server: "a"}, event.KindChat, data)
+}
+
+func read(l event.Log, from, until uint64) (data []string) {
+	Expect(l.Read(from, until, func(r *event.Record) bool {
+		data = append(data, r.Payload)
+		return true
+	})).To(BeNil())
+	return
+}
+
+var _ = Describe("Ring", func() {
+	It("does assign offsets starting at 1", func() {
+		l := event.NewRing(event.Retention{})
+		r := record("a")
+		Expect(l.Next()).To(BeEquivalentTo(1))
+		Expect(l.Append(r)).To(BeNil())
+		Expect(r.Offset).To(BeEquivalentTo(1))
+		Expect(l.Next()).To(BeEquivalentTo(2))
+	})
+	It("does read ranges", func() {
+		l := event.NewRing(event.Retention{})
+		for _, d := range []string{"a", "b", "c", "d"} {
+			l.Append(record(d))
+		}
+		Expect(read(l, 2, 4)).To(Equal([]string{"b", "c"}))
+		Expect(read(l, 0, l.Next())).To(Equal([]string{"a", "b", "c", "d"}))
+	})
+	It("does stop reading early", func() {
+		l := event.NewRing(event.Retention{})
+		l.Append(record("a"))
+		l.Append(record("b"))
+		n := 0
+		l.Read(0, 10, func(*event.Record) bool { n++; return false })
+		Expect(n).To(Equal(1))
+	})
+	It("does retain MaxCount records", func() {
+		l := event.NewRing(event.Retention{MaxCount: 2})
+		for _, d := range []string{"a", "b", "c"} {
+			l.Append(record(d))
+		}
+		Expect(read(l, 0, 10)).To(Equal([]string{"b", "c"}))
+	})
+	It("does keep retaining MaxCount records after many appends", func() {
+		l := event.NewRing(event.Retention{MaxCount: 3})
+		for i := 0; i < 100; i++ {
+			l.Append(record(fmt.Sprint(i)))
+		}
+		Expect(read(l, 0, 200)).To(Equal([]string{"97", "98", "99"}))
+		// offsets start at 1
+		Expect(read(l, 99, 100)).To(Equal([]string{"98"}))
+	})
+	It("does drop records older than MaxAge", func() {
+		l := event.NewRing(event.Retention{MaxAge: time.Minute})
+		old := record("a")
+		old.Time = time.Now().Add(-time.Hour)
+		l.Append(old)
+		l.Append(record("b"))
+		Expect(read(l, 0, 10)).To(Equal([]string{"b"}))
+	})
+})
+
+var _ = Describe("Segments", func() {
+	var dir string
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "segments")
+		Expect(err).To(BeNil())
+	})
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	open := func(r event.Retention, size int) *event.Segments {
+		l, err := event.OpenSegments(dir, r)
+		Expect(err).To(BeNil())
+		l.SegmentSize = size
+		return l
+	}
+
+	It("does read appended records", func() {
+		l := open(event.Retention{}, 2)
+		defer l.Close()
+		for _, d := range []string{"a", "b", "c", "d", "e"} {
+			Expect(l.Append(record(d))).To(BeNil())
+		}
+		Expect(read(l, 0, l.Next())).To(Equal([]string{"a", "b", "c", "d", "e"}))
+		Expect(read(l, 2, 5)).To(Equal([]string{"b", "c", "d"}))
+		files, _ := filepath.Glob(filepath.Join(dir, "*.seg"))
+		Expect(files).To(HaveLen(3))
+	})
+	It("does keep records and offsets across restarts", func() {
+		l := open(event.Retention{}, 10)
+		l.Append(record("a"))
+		l.Append(record("b"))
+		Expect(l.Close()).To(BeNil())
+
+		l = open(event.Retention{}, 10)
+		defer l.Close()
+		Expect(l.Next()).To(BeEquivalentTo(3))
+		r := record("c")
+		Expect(l.Append(r)).To(BeNil())
+		Expect(r.Offset).To(BeEquivalentTo(3))
+		Expect(read(l, 0, 10)).To(Equal([]string{"a", "b", "c"}))
+	})
+	It("does restore offsets of read records", func() {
+		l := open(event.Retention{}, 10)
+		defer l.Close()
+		l.Append(record("a"))
+		l.Append(record("b"))
+		var offsets []uint64
+		l.Read(0, 10, func(r *event.Record) bool {
+			offsets = append(offsets, r.Offset)
+			return true
+		})
+		Expect(offsets).To(Equal([]uint64{1, 2}))
+	})
+	It("does drop partially written records when opening", func() {
+		l := open(event.Retention{}, 10)
+		l.Append(record("a"))
+		l.Append(record("b"))
+		l.Close()
+		files, _ := filepath.Glob(filepath.Join(dir, "*.seg"))
+		info, _ := os.Stat(files[0])
+		Expect(os.Truncate(files[0], info.Size()-2)).To(BeNil())
+
+		l = open(event.Retention{}, 10)
+		defer l.Close()
+		Expect(l.Next()).To(BeEquivalentTo(2))
+		l.Append(record("c"))
+		Expect(read(l, 0, 10)).To(Equal([]string{"a", "c"}))
+	})
+	It("does buffer appended records until flushed", func() {
+		l := open(event.Retention{}, 10)
+		defer l.Close()
+		l.Append(record("a"))
+		files, _ := filepath.Glob(filepath.Join(dir, "*.seg"))
+		info, _ := os.Stat(files[0])
+		Expect(info.Size()).To(BeZero())
+		Expect(l.Flush()).To(BeNil())
+		info, _ = os.Stat(files[0])
+		Expect(info.Size()).NotTo(BeZero())
+	})
+	It("does flush periodically while running", func() {
+		l := open(event.Retention{}, 10)
+		defer l.Close()
+		l.FlushInterval = 10 * time.Millisecond
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go l.Run(ctx)
+		l.Append(record("a"))
+		files, _ := filepath.Glob(filepath.Join(dir, "*.seg"))
+		Eventually(func() int64 {
+			info, _ := os.Stat(files[0])
+			return info.Size()
+		}).ShouldNot(BeZero())
+	})
+	It("does remove segments exceeding MaxCount", func() {
+		l := open(event.Retention{MaxCount: 2}, 2)
+		defer l.Close()
+		for _, d := range []string{"a", "b", "c", "d", "e"} {
+			l.Append(record(d))
+		}
+		Expect(read(l, 0, 10)).To(Equal([]string{"c", "d", "e"}))
+	})
+	It("does remove segments older than MaxAge", func() {
+		l := open(event.Retention{MaxAge: time.Minute}, 1)
+		defer l.Close()
+		old := record("a")
+		old.Time = time.Now().Add(-time.Hour)
+		l.Append(old)
+		l.Append(record("b"))
+		Expect(read(l, 0, 10)).To(Equal([]string{"b"}))
+	})
+})
+
+var _ = Describe("Replay", func() {
+	setup := func(l event.Log) (context.Context, context.CancelFunc, chan event.Event, *event.Broker) {
+		ctx := log.WithLogger(context.Background(), log.New("", debug))
+		ctx, cancel := context.WithCancel(ctx)
+		in := make(chan event.Event)
+		b := event.NewBroker(ctx, in)
+		b.Log = l
+		// a single slot overflows as soon as a subscriber falls behind
+		b.Buffer = 1
+		go b.Run(ctx)
+		return ctx, cancel, in, b
+	}
+	data := func(c <-chan event.Event, n int) (d []string) {
+		for i := 0; i < n; i++ {
+			d = append(d, (<-c).Data())
+		}
+		return
+	}
+
+	It("does replay missed events before live ones", func() {
+		ctx, cancel, in, b := setup(event.NewRing(event.Retention{}))
+		defer cancel()
+		in <- record("a")
+		in <- serverEvent{"a", "chat", "b"}
+
+		c := make(chan event.Event)
+		b.SubscribeFrom(ctx, c, event.Filter{}, event.Position{})
+		go func() { in <- record("c") }()
+		Expect(data(c, 3)).To(Equal([]string{"a", "b", "c"}))
+	})
+	It("does not drop live events while replaying if blocking", func() {
+		ctx, cancel, in, b := setup(event.NewRing(event.Retention{}))
+		defer cancel()
+		b.Overflow = event.Block
+		b.BlockTimeout = time.Minute
+		var want []string
+		for i := 0; i < 100; i++ {
+			want = append(want, fmt.Sprint(i))
+		}
+		for _, d := range want[:50] {
+			in <- record(d)
+		}
+
+		c := make(chan event.Event)
+		b.SubscribeFrom(ctx, c, event.Filter{}, event.Position{})
+		go func() {
+			for _, d := range want[50:] {
+				in <- record(d)
+			}
+		}()
+		Expect(data(c, 100)).To(Equal(want))
+		// only the events published before subscribing
+		Expect(b.Dropped()).To(BeEquivalentTo(50))
+	})
+	It("does apply the overflow policy to live events while replaying", func() {
+		ctx, cancel, in, b := setup(event.NewRing(event.Retention{}))
+		defer cancel()
+		in <- record("replayed")
+
+		c := make(chan event.Event)
+		b.SubscribeFrom(ctx, c, event.Filter{}, event.Position{})
+		// published slowly enough for the subscription to drain its queue if its tail had room
+		for i := 0; i < 50; i++ {
+			in <- record(fmt.Sprint(i))
+			time.Sleep(time.Millisecond)
+		}
+		// the replayed event is not received yet, so at most the tail and the queue hold one event each
+		Eventually(b.Dropped).Should(BeNumerically(">=", 1+48))
+		Expect(data(c, 1)).To(Equal([]string{"replayed"}))
+		live := 0
+		for {
+			select {
+			case <-c:
+				live++
+				continue
+			case <-time.After(100 * time.Millisecond):
+			}
+			break
+		}
+		Expect(live).To(BeNumerically("<=", 2))
+	})
+	It("does replay from offset", func() {
+		ctx, cancel, in, b := setup(event.NewRing(event.Retention{}))
+		defer cancel()
+		in <- record("a")
+		in <- record("b")
+
+		c := make(chan event.Event, 1)
+		b.SubscribeFrom(ctx, c, event.Filter{}, event.Position{Offset: 2})
+		e := (<-c).(*event.Record)
+		Expect(e.Payload).To(Equal("b"))
+		Expect(e.Offset).To(BeEquivalentTo(2))
+	})
+	It("does replay since timestamp", func() {
+		ctx, cancel, in, b := setup(event.NewRing(event.Retention{}))
+		defer cancel()
+		old := record("a")
+		old.Time = time.Now().Add(-time.Hour)
+		in <- old
+		in <- record("b")
+
+		c := make(chan event.Event, 1)
+		b.SubscribeFrom(ctx, c, event.Filter{}, event.Position{Since: time.Now().Add(-time.Minute)})
+		Expect((<-c).Data()).To(Equal("b"))
+	})
+	It("does apply filters to replayed events", func() {
+		ctx, cancel, in, b := setup(event.NewRing(event.Retention{}))
+		defer cancel()
+		in <- event.New(event.Source{}, event.KindStdOut, "a")
+		in <- record("b")
+
+		c := make(chan event.Event, 1)
+		b.SubscribeFrom(ctx, c, event.Filter{Kinds: []event.Kind{event.KindChat}}, event.Position{})
+		Expect((<-c).Data()).To(Equal("b"))
+	})
+	It("does only deliver live events without log", func() {
+		ctx, cancel, in, b := setup(nil)
+		defer cancel()
+		in <- record("a")
+
+		c := make(chan event.Event, 1)
+		b.SubscribeFrom(ctx, c, event.Filter{}, event.Position{})
+		in <- record("b")
+		Expect((<-c).Data()).To(Equal("b"))
+	})
+	It("does deliver events the log failed to store", func() {
+		l := &mocks.EventLog{}
+		l.AppendReturns(errors.New("test"))
+		ctx, cancel, in, b := setup(l)
+		defer cancel()
+
+		c := make(chan event.Event, 1)
+		b.Subscribe(ctx, c)
+		in <- record("a")
+		Expect((<-c).Data()).To(Equal("a"))
+	})
+})
@@ -0,0 +1,12 @@
+package event
+
+import (
+	"github.com/playnet-public/gorcon/pkg/metrics"
+)
+
+var (
+	brokerSubscribers = metrics.NewGaugeVec("gorcon_broker_subscribers", "Active subscriptions of the broker.", "broker")
+	brokerEvents      = metrics.NewCounterVec("gorcon_broker_events_total", "Events received by the broker.", "broker")
+	brokerReplayed    = metrics.NewCounterVec("gorcon_broker_replayed_events_total", "Events replayed from the broker's log to late subscriptions.", "broker")
+	brokerDropped     = metrics.NewCounterVec("gorcon_broker_dropped_events_total", "Events the broker did not deliver to a subscriber.", "broker", "reason")
+)
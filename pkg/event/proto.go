@@ -0,0 +1,174 @@
+package event
+
+import (
+	"encoding/binary"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// ErrInvalidProto is returned when decoding malformed protobuf data
+var ErrInvalidProto = errors.New("invalid protobuf record")
+
+// MarshalProto encodes r as protobuf message as described in record.proto
+func (r *Record) MarshalProto() []byte {
+	var b []byte
+	b = appendString(b, 1, r.ID)
+	if !r.Time.IsZero() {
+		b = appendVarint(b, 2, uint64(r.Time.UnixNano()))
+	}
+	var src []byte
+	src = appendString(src, 1, r.Source.Server)
+	src = appendString(src, 2, r.Source.Component)
+	if len(src) > 0 {
+		b = appendBytes(b, 3, src)
+	}
+	b = appendString(b, 4, string(r.Type))
+	b = appendString(b, 5, r.Payload)
+
+	keys := make([]string, 0, len(r.Attributes))
+	for k := range r.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var entry []byte
+		entry = appendString(entry, 1, k)
+		entry = appendString(entry, 2, r.Attributes[k])
+		b = appendBytes(b, 6, entry)
+	}
+	if r.Offset > 0 {
+		b = appendVarint(b, 7, r.Offset)
+	}
+	return b
+}
+
+// UnmarshalProto decodes a protobuf message into r. Unknown fields are skipped
+func (r *Record) UnmarshalProto(b []byte) error {
+	*r = Record{}
+	return decode(b, func(field int, v uint64, data []byte) error {
+		switch field {
+		case 1:
+			r.ID = string(data)
+		case 2:
+			r.Time = time.Unix(0, int64(v)).UTC()
+		case 3:
+			return decode(data, func(field int, _ uint64, data []byte) error {
+				switch field {
+				case 1:
+					r.Source.Server = string(data)
+				case 2:
+					r.Source.Component = string(data)
+				}
+				return nil
+			})
+		case 4:
+			r.Type = Kind(data)
+		case 5:
+			r.Payload = string(data)
+		case 6:
+			var k, val string
+			err := decode(data, func(field int, _ uint64, data []byte) error {
+				switch field {
+				case 1:
+					k = string(data)
+				case 2:
+					val = string(data)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			r.Set(k, val)
+		case 7:
+			r.Offset = v
+		}
+		return nil
+	})
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+func appendTag(b []byte, field, wire int) []byte {
+	return appendUvarint(b, uint64(field)<<3|uint64(wire))
+}
+
+func appendVarint(b []byte, field int, v uint64) []byte {
+	b = appendTag(b, field, wireVarint)
+	return appendUvarint(b, v)
+}
+
+func appendBytes(b []byte, field int, v []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = appendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendString(b []byte, field int, v string) []byte {
+	if v == "" {
+		return b
+	}
+	return appendBytes(b, field, []byte(v))
+}
+
+// decode calls fn for every field in b passing varints as v and length delimited fields as data
+func decode(b []byte, fn func(field int, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return ErrInvalidProto
+		}
+		b = b[n:]
+		field, wire := int(tag>>3), int(tag&7)
+
+		var v uint64
+		var data []byte
+		switch wire {
+		case wireVarint:
+			v, n = binary.Uvarint(b)
+			if n <= 0 {
+				return ErrInvalidProto
+			}
+			b = b[n:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return ErrInvalidProto
+			}
+			data = b[n : n+int(l)]
+			b = b[n+int(l):]
+		case wireFixed64:
+			if len(b) < 8 {
+				return ErrInvalidProto
+			}
+			b = b[8:]
+			continue
+		case wireFixed32:
+			if len(b) < 4 {
+				return ErrInvalidProto
+			}
+			b = b[4:]
+			continue
+		default:
+			return errors.Wrapf(ErrInvalidProto, "unsupported wire type %d", wire)
+		}
+		if err := fn(field, v, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package event
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+)
+
+// Kind of an event
+type Kind string
+
+// Kinds of events emitted by gorcon
+const (
+	// KindEvent identifies server messages not covered by other kinds
+	KindEvent Kind = "event"
+	// KindChat identifies chat messages
+	KindChat Kind = "chat"
+	// KindPlayer identifies player events like connects, guid verifications and kicks
+	KindPlayer Kind = "player"
+	// KindStdOut identifies lines written to stdout by a process
+	KindStdOut Kind = "StdOut"
+	// KindStdErr identifies lines written to stderr by a process
+	KindStdErr Kind = "StdErr"
+	// KindCrash identifies a process exiting unexpectedly
+	KindCrash Kind = "Crash"
+	// KindRestart identifies a process being revived
+	KindRestart Kind = "Restart"
+	// KindLog identifies lines of server log files
+	KindLog Kind = "log"
+	// KindScriptError identifies script errors reported in server log files
+	KindScriptError Kind = "script_error"
+	// KindMission identifies missions starting or ending
+	KindMission Kind = "mission"
+	// KindUsage identifies periodic resource usage samples of a process
+	KindUsage Kind = "usage"
+	// KindAlert identifies exceeded thresholds
+	KindAlert Kind = "alert"
+	// KindHang identifies processes being killed for not showing any sign of life
+	KindHang Kind = "hang"
+	// KindReady identifies processes being up, e.g. a server accepting rcon connections
+	KindReady Kind = "ready"
+	// KindShutdown identifies the steps of a graceful shutdown
+	KindShutdown Kind = "shutdown"
+	// KindCrashBundle identifies bundles of evidence collected after a crash
+	KindCrashBundle Kind = "crash_bundle"
+)
+
+// Components emitting events
+const (
+	ComponentRcon    = "rcon"
+	ComponentWatcher = "watcher"
+)
+
+// Source of an event
+type Source struct {
+	// Server identifies the game server the event belongs to
+	Server string `json:"server,omitempty"`
+	// Component that emitted the event
+	Component string `json:"component,omitempty"`
+}
+
+// Record is the structured event emitted by all gorcon components
+// Offset is assigned once the record gets appended to a Log
+type Record struct {
+	ID         string            `json:"id"`
+	Offset     uint64            `json:"offset,omitempty"`
+	Time       time.Time         `json:"time"`
+	Source     Source            `json:"source"`
+	Type       Kind              `json:"kind"`
+	Payload    string            `json:"data"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// New record of kind with data originating from src
+func New(src Source, kind Kind, data string) *Record {
+	return &Record{
+		ID:      NewID(),
+		Time:    time.Now().UTC(),
+		Source:  src,
+		Type:    kind,
+		Payload: data,
+	}
+}
+
+// FromEvent converts e into a Record. Records are returned unchanged
+func FromEvent(e Event) *Record {
+	if r, ok := e.(*Record); ok {
+		return r
+	}
+	r := &Record{
+		ID:      NewID(),
+		Time:    e.Timestamp(),
+		Type:    Kind(e.Kind()),
+		Payload: e.Data(),
+	}
+	if s, ok := e.(Sourced); ok {
+		r.Source.Server = s.Server()
+	}
+	return r
+}
+
+// Set attribute key to value returning the record for chaining
+func (r *Record) Set(key, value string) *Record {
+	if r.Attributes == nil {
+		r.Attributes = make(map[string]string)
+	}
+	r.Attributes[key] = value
+	return r
+}
+
+// Attribute returns the value of key or an empty string
+func (r *Record) Attribute(key string) string {
+	return r.Attributes[key]
+}
+
+// Timestamp when the event occurred
+func (r *Record) Timestamp() time.Time { return r.Time }
+
+// Kind of the event
+func (r *Record) Kind() string { return string(r.Type) }
+
+// Data of the event
+func (r *Record) Data() string { return r.Payload }
+
+// Server the event originates from
+func (r *Record) Server() string { return r.Source.Server }
+
+// NewID returns a unique id sorting in the order the ids got created
+func NewID() string {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(time.Now().UnixNano()))
+	rand.Read(b[8:])
+	return hex.EncodeToString(b[:])
+}
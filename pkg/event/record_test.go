@@ -0,0 +1,89 @@
+package event_test
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Record", func() {
+	src := event.Source{Server: "127.0.0.1:2302", Component: event.ComponentRcon}
+
+	Describe("New", func() {
+		It("does implement Event", func() {
+			r := event.New(src, event.KindChat, "(Global) Test: hi")
+			Expect(r.Kind()).To(Equal("chat"))
+			Expect(r.Data()).To(Equal("(Global) Test: hi"))
+			Expect(r.Server()).To(Equal("127.0.0.1:2302"))
+			Expect(r.Timestamp()).NotTo(BeZero())
+		})
+		It("does assign unique ids", func() {
+			a, b := event.New(src, event.KindChat, ""), event.New(src, event.KindChat, "")
+			Expect(a.ID).To(HaveLen(32))
+			Expect(a.ID).NotTo(Equal(b.ID))
+			Expect(a.ID < b.ID).To(BeTrue())
+		})
+	})
+
+	Describe("FromEvent", func() {
+		It("does return records unchanged", func() {
+			r := event.New(src, event.KindChat, "")
+			Expect(event.FromEvent(r)).To(BeIdenticalTo(r))
+		})
+		It("does convert other events", func() {
+			r := event.FromEvent(serverEvent{"a", "chat", "hi"})
+			Expect(r.ID).NotTo(BeEmpty())
+			Expect(r.Type).To(Equal(event.KindChat))
+			Expect(r.Payload).To(Equal("hi"))
+			Expect(r.Source.Server).To(Equal("a"))
+		})
+	})
+
+	Describe("Encoding", func() {
+		var r *event.Record
+		BeforeEach(func() {
+			r = event.New(src, event.KindPlayer, "Player #1 Test disconnected")
+			r.Time = time.Date(2018, 1, 2, 3, 4, 5, 6, time.UTC)
+			r.Set("player.id", "1").Set("player.name", "Test")
+		})
+
+		It("does encode json", func() {
+			b, err := json.Marshal(r)
+			Expect(err).To(BeNil())
+			Expect(b).To(MatchJSON(`{
+				"id": "` + r.ID + `",
+				"time": "2018-01-02T03:04:05.000000006Z",
+				"source": {"server": "127.0.0.1:2302", "component": "rcon"},
+				"kind": "player",
+				"data": "Player #1 Test disconnected",
+				"attributes": {"player.id": "1", "player.name": "Test"}
+			}`))
+			var d event.Record
+			Expect(json.Unmarshal(b, &d)).To(BeNil())
+			Expect(&d).To(Equal(r))
+		})
+		It("does encode protobuf", func() {
+			var d event.Record
+			Expect(d.UnmarshalProto(r.MarshalProto())).To(BeNil())
+			Expect(&d).To(Equal(r))
+		})
+		It("does encode protobuf deterministically", func() {
+			Expect(r.MarshalProto()).To(Equal(r.MarshalProto()))
+		})
+		It("does skip unknown protobuf fields", func() {
+			// field 15 varint 1 followed by field 5 "x"
+			var d event.Record
+			Expect(d.UnmarshalProto([]byte{15 << 3, 1, 5<<3 | 2, 1, 'x'})).To(BeNil())
+			Expect(d.Payload).To(Equal("x"))
+		})
+		It("does return error on truncated protobuf", func() {
+			var d event.Record
+			b := r.MarshalProto()
+			Expect(d.UnmarshalProto(b[:len(b)-1])).NotTo(BeNil())
+		})
+	})
+})
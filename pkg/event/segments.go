@@ -0,0 +1,323 @@
+package event
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// Segments is a Log persisting records in a directory of append only segment files
+// Retention is applied to whole segments, so up to SegmentSize additional records might be kept
+// Appended records are buffered in memory to keep disk writes out of the broker, Run flushes them periodically
+type Segments struct {
+	Dir       string
+	Retention Retention
+	// SegmentSize is the number of records written to a segment before starting a new one
+	SegmentSize int
+	// FlushInterval between writing buffered records to disk while running
+	FlushInterval time.Duration
+
+	m        sync.Mutex
+	segments []*segment
+	active   *os.File
+	w        *bufio.Writer
+	next     uint64
+}
+
+type segment struct {
+	path  string
+	first uint64
+	count int
+	last  time.Time
+}
+
+const (
+	segmentExt = ".seg"
+	// segmentBuffer is the size of the buffer for records not yet written to the active segment
+	segmentBuffer = 64 * 1024
+)
+
+// OpenSegments log in dir creating it if necessary. Records of previous runs are kept according to r
+func OpenSegments(dir string, r Retention) (*Segments, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "creating event log")
+	}
+	l := &Segments{Dir: dir, Retention: r, SegmentSize: 10000, FlushInterval: time.Second, next: 1}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		return nil, errors.Wrap(err, "listing segments")
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		s, err := scanSegment(path)
+		if err != nil {
+			return nil, err
+		}
+		l.segments = append(l.segments, s)
+		l.next = s.first + uint64(s.count)
+	}
+	l.prune()
+	return l, nil
+}
+
+// scanSegment reads the metadata of the segment at path, truncating a partially written record at its end
+func scanSegment(path string) (*segment, error) {
+	var first uint64
+	if _, err := fmt.Sscanf(filepath.Base(path), "%020d"+segmentExt, &first); err != nil {
+		return nil, errors.Wrapf(err, "invalid segment name %s", path)
+	}
+	s := &segment{path: path, first: first}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening segment")
+	}
+	defer f.Close()
+	var valid int64
+	err = readRecords(f, func(r *Record, n int64) bool {
+		s.count++
+		s.last = r.Time
+		valid += n
+		return true
+	})
+	if err == io.ErrUnexpectedEOF || err == ErrInvalidProto {
+		if err := os.Truncate(path, valid); err != nil {
+			return nil, errors.Wrap(err, "truncating segment")
+		}
+		err = nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading segment %s", path)
+	}
+	return s, nil
+}
+
+// readRecords from r passing each record along with its encoded size to fn
+func readRecords(r io.Reader, fn func(*Record, int64) bool) error {
+	br := bufio.NewReader(r)
+	for {
+		l, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		b := make([]byte, l)
+		if _, err := io.ReadFull(br, b); err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		rec := &Record{}
+		if err := rec.UnmarshalProto(b); err != nil {
+			return err
+		}
+		if !fn(rec, int64(len(appendUvarint(nil, l)))+int64(l)) {
+			return nil
+		}
+	}
+}
+
+// Append r to the active segment starting a new one if it is full
+func (l *Segments) Append(r *Record) error {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.active == nil || l.segments[len(l.segments)-1].count >= l.SegmentSize {
+		if err := l.roll(); err != nil {
+			return err
+		}
+	}
+
+	r.Offset = l.next
+	b := r.MarshalProto()
+	buf := appendUvarint(make([]byte, 0, len(b)+binary.MaxVarintLen64), uint64(len(b)))
+	if _, err := l.w.Write(append(buf, b...)); err != nil {
+		return errors.Wrap(err, "writing event")
+	}
+	l.next++
+	s := l.segments[len(l.segments)-1]
+	s.count++
+	s.last = r.Time
+	l.prune()
+	return nil
+}
+
+// roll over to a new segment. The caller must hold the lock
+func (l *Segments) roll() error {
+	if l.active != nil {
+		if err := l.close(); err != nil {
+			return err
+		}
+	}
+	// continue the last segment after a restart if it has room
+	if n := len(l.segments); n > 0 && l.segments[n-1].count < l.SegmentSize {
+		f, err := os.OpenFile(l.segments[n-1].path, os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return errors.Wrap(err, "opening segment")
+		}
+		l.active, l.w = f, bufio.NewWriterSize(f, segmentBuffer)
+		return nil
+	}
+
+	path := filepath.Join(l.Dir, fmt.Sprintf("%020d"+segmentExt, l.next))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrap(err, "creating segment")
+	}
+	l.active, l.w = f, bufio.NewWriterSize(f, segmentBuffer)
+	l.segments = append(l.segments, &segment{path: path, first: l.next})
+	return nil
+}
+
+// prune segments exceeding the retention, always keeping the newest one. The caller must hold the lock
+func (l *Segments) prune() {
+	total := 0
+	for _, s := range l.segments {
+		total += s.count
+	}
+	cutoff := time.Now().Add(-l.Retention.MaxAge)
+	for len(l.segments) > 1 {
+		s := l.segments[0]
+		expired := l.Retention.MaxAge > 0 && s.last.Before(cutoff)
+		exceeded := l.Retention.MaxCount > 0 && total-s.count >= l.Retention.MaxCount
+		if !expired && !exceeded {
+			return
+		}
+		os.Remove(s.path)
+		total -= s.count
+		l.segments = l.segments[1:]
+	}
+}
+
+// Next returns the offset the next appended record gets assigned
+func (l *Segments) Next() uint64 {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.next
+}
+
+// Read retained records with offsets in [from, until)
+func (l *Segments) Read(from, until uint64, fn func(*Record) bool) error {
+	l.m.Lock()
+	if err := l.flush(); err != nil {
+		l.m.Unlock()
+		return err
+	}
+	var segments []segment
+	for _, s := range l.segments {
+		if s.first+uint64(s.count) > from && s.first < until {
+			segments = append(segments, *s)
+		}
+	}
+	l.m.Unlock()
+
+	for _, s := range segments {
+		done, err := readSegment(s, from, until, fn)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+	return nil
+}
+
+// readSegment returns true once fn stopped reading or until got reached
+func readSegment(s segment, from, until uint64, fn func(*Record) bool) (bool, error) {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		// removed by retention while reading
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "opening segment")
+	}
+	defer f.Close()
+
+	done := false
+	offset := s.first
+	err = readRecords(f, func(r *Record, _ int64) bool {
+		defer func() { offset++ }()
+		if offset >= until {
+			done = true
+			return false
+		}
+		if offset < from {
+			return true
+		}
+		r.Offset = offset
+		if !fn(r) {
+			done = true
+			return false
+		}
+		return true
+	})
+	if err != nil && offset < until {
+		return false, errors.Wrapf(err, "reading segment %s", s.path)
+	}
+	return done, nil
+}
+
+// Run flushing buffered records every FlushInterval until ctx is closed
+func (l *Segments) Run(ctx context.Context) error {
+	t := time.NewTicker(l.FlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.From(ctx).Info("stopping event log", zap.Error(ctx.Err()))
+			return l.Flush()
+		case <-t.C:
+			if err := l.Flush(); err != nil {
+				log.From(ctx).Error("flushing event log", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Flush buffered records to the active segment
+func (l *Segments) Flush() error {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.flush()
+}
+
+// flush buffered records. The caller must hold the lock
+func (l *Segments) flush() error {
+	if l.w == nil {
+		return nil
+	}
+	return errors.Wrap(l.w.Flush(), "writing events")
+}
+
+// Close the active segment after flushing buffered records
+func (l *Segments) Close() error {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.active == nil {
+		return nil
+	}
+	return l.close()
+}
+
+// close the active segment. The caller must hold the lock
+func (l *Segments) close() error {
+	err := l.flush()
+	if cerr := l.active.Close(); err == nil && cerr != nil {
+		err = errors.Wrap(cerr, "closing segment")
+	}
+	l.active, l.w = nil, nil
+	return err
+}
@@ -9,9 +9,118 @@ import (
 	"go.uber.org/zap"
 )
 
+// subscription queues events for a single subscriber
+type subscription struct {
+	ctx    context.Context
+	out    chan<- Event
+	filter Filter
+	from   *Position
+
+	queue chan Event
+	stop  chan struct{}
+	// replay records from log before the first queued event
+	log    Log
+	until  uint64
+	broker string
+}
+
+// forward replayed and queued events to out in order until stopped, then close out
+// Events still queued when stopping are discarded
+func (s *subscription) forward() {
+	defer close(s.out)
+	if s.log != nil && !s.catchUp() {
+		return
+	}
+	for {
+		select {
+		case <-s.stop:
+			return
+		case e := <-s.queue:
+			select {
+			case s.out <- e:
+			case <-s.stop:
+				return
+			}
+		}
+	}
+}
+
+// catchUp replays logged records and the live events received meanwhile returning false if stopped
+// The queue keeps getting drained into a separate tail holding up to as many events as the queue. Once it is full
+// the queue is left alone, so the broker applies its Overflow policy like for any subscription not keeping up
+func (s *subscription) catchUp() bool {
+	replayed := make(chan bool, 1)
+	go func() { replayed <- s.replay() }()
+	var tail []Event
+	for replayed != nil || len(tail) > 0 {
+		var out chan<- Event
+		var next Event
+		if replayed == nil {
+			out, next = s.out, tail[0]
+		}
+		queue := s.queue
+		if len(tail) >= cap(s.queue) {
+			queue = nil
+		}
+		select {
+		case ok := <-replayed:
+			if !ok {
+				return false
+			}
+			replayed = nil
+		case e := <-queue:
+			tail = append(tail, e)
+		case out <- next:
+			tail = tail[1:]
+		case <-s.stop:
+			return false
+		}
+	}
+	return true
+}
+
+// replay logged records up to the first queued event returning false if stopped
+func (s *subscription) replay() bool {
+	stopped := false
+	err := s.log.Read(s.from.Offset, s.until, func(r *Record) bool {
+		if r.Time.Before(s.from.Since) || !s.filter.Match(r) {
+			return true
+		}
+		select {
+		case s.out <- r:
+			brokerReplayed.With(s.broker).Inc()
+			return true
+		case <-s.stop:
+			stopped = true
+			return false
+		}
+	})
+	if err != nil {
+		log.From(s.ctx).Error("replaying events", zap.String("broker", s.broker), zap.Error(err))
+	}
+	return !stopped
+}
+
 // Subscribe adds a new channel as receiver for events and unsubscribes on a closed ctx
 func (b *Broker) Subscribe(ctx context.Context, out chan<- Event) {
-	b.new <- out
+	b.SubscribeFilter(ctx, out, Filter{})
+}
+
+// SubscribeFilter adds a new channel as receiver for events matching f and unsubscribes on a closed ctx
+// The filter is evaluated by the broker, so events not matching never reach the subscription's queue
+func (b *Broker) SubscribeFilter(ctx context.Context, out chan<- Event, f Filter) {
+	b.subscribe(ctx, &subscription{ctx: ctx, out: out, filter: f})
+}
+
+// SubscribeFrom adds a new channel as receiver for events matching f, first replaying the events retained by
+// the broker's Log starting at from. Without Log it behaves like SubscribeFilter
+func (b *Broker) SubscribeFrom(ctx context.Context, out chan<- Event, f Filter, from Position) {
+	b.subscribe(ctx, &subscription{ctx: ctx, out: out, filter: f, from: &from})
+}
+
+func (b *Broker) subscribe(ctx context.Context, s *subscription) {
+	out := s.out
+	b.new <- s
 	go func() {
 		<-ctx.Done()
 		select {
@@ -0,0 +1,81 @@
+// Package fileutil copies files and directory trees for deployments and mod installations
+package fileutil
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// Options for copying files
+type Options struct {
+	// Rename maps paths relative to the copied tree to the ones created. Paths are kept if nil
+	Rename func(string) string
+	// Link files instead of copying them if possible
+	Link bool
+	// Replace existing files instead of failing
+	Replace bool
+	// Symlinks are recreated if set. Otherwise they get skipped like all other files which are not regular
+	Symlinks bool
+}
+
+// CopyTree copies the directory src to dst
+func CopyTree(src, dst string, o Options) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if o.Rename != nil {
+			rel = o.Rename(rel)
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case info.IsDir():
+			return errors.Wrap(os.MkdirAll(target, info.Mode().Perm()|0700), "creating directory")
+		case info.Mode()&os.ModeSymlink != 0 && o.Symlinks:
+			l, err := os.Readlink(path)
+			if err != nil {
+				return errors.Wrap(err, "reading symlink")
+			}
+			return errors.Wrap(os.Symlink(l, target), "creating symlink")
+		case !info.Mode().IsRegular():
+			return nil
+		}
+		return CopyFile(path, target, o)
+	})
+}
+
+// CopyFile src to dst or hard link it if requested and possible
+func CopyFile(src, dst string, o Options) error {
+	if o.Link && os.Link(src, dst) == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return errors.Wrap(err, "opening file")
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return errors.Wrap(err, "reading file")
+	}
+	flags := os.O_CREATE | os.O_EXCL | os.O_WRONLY
+	if o.Replace {
+		flags = os.O_CREATE | os.O_TRUNC | os.O_WRONLY
+	}
+	out, err := os.OpenFile(dst, flags, info.Mode().Perm())
+	if err != nil {
+		return errors.Wrap(err, "creating file")
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return errors.Wrap(err, "copying file")
+	}
+	return errors.Wrap(out.Close(), "copying file")
+}
@@ -0,0 +1,87 @@
+package fileutil_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/playnet-public/gorcon/pkg/fileutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestFileutil(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Fileutil Suite")
+}
+
+var _ = Describe("Copy", func() {
+	var src, dst string
+
+	BeforeEach(func() {
+		var err error
+		src, err = ioutil.TempDir("", "src")
+		Expect(err).To(BeNil())
+		dst, err = ioutil.TempDir("", "dst")
+		Expect(err).To(BeNil())
+		Expect(os.MkdirAll(filepath.Join(src, "Addons"), 0755)).To(BeNil())
+		Expect(ioutil.WriteFile(filepath.Join(src, "Addons", "Mod.pbo"), []byte("pbo"), 0644)).To(BeNil())
+		Expect(os.Symlink("Addons", filepath.Join(src, "link"))).To(BeNil())
+	})
+	AfterEach(func() {
+		os.RemoveAll(src)
+		os.RemoveAll(dst)
+	})
+
+	content := func(path string) string {
+		b, err := ioutil.ReadFile(path)
+		Expect(err).To(BeNil())
+		return string(b)
+	}
+
+	Describe("CopyTree", func() {
+		It("does copy files and recreate symlinks", func() {
+			Expect(fileutil.CopyTree(src, filepath.Join(dst, "tree"), fileutil.Options{Symlinks: true})).To(BeNil())
+			Expect(content(filepath.Join(dst, "tree", "Addons", "Mod.pbo"))).To(Equal("pbo"))
+			l, err := os.Readlink(filepath.Join(dst, "tree", "link"))
+			Expect(err).To(BeNil())
+			Expect(l).To(Equal("Addons"))
+		})
+		It("does skip symlinks unless requested", func() {
+			Expect(fileutil.CopyTree(src, dst, fileutil.Options{})).To(BeNil())
+			_, err := os.Lstat(filepath.Join(dst, "link"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+		It("does rename paths", func() {
+			Expect(fileutil.CopyTree(src, dst, fileutil.Options{Rename: strings.ToLower})).To(BeNil())
+			Expect(content(filepath.Join(dst, "addons", "mod.pbo"))).To(Equal("pbo"))
+		})
+	})
+
+	Describe("CopyFile", func() {
+		var target string
+		BeforeEach(func() {
+			target = filepath.Join(dst, "Mod.pbo")
+			Expect(ioutil.WriteFile(target, []byte("previous version"), 0644)).To(BeNil())
+		})
+
+		It("does not overwrite existing files", func() {
+			Expect(fileutil.CopyFile(filepath.Join(src, "Addons", "Mod.pbo"), target, fileutil.Options{})).NotTo(BeNil())
+			Expect(content(target)).To(Equal("previous version"))
+		})
+		It("does replace existing files if requested", func() {
+			Expect(fileutil.CopyFile(filepath.Join(src, "Addons", "Mod.pbo"), target, fileutil.Options{Replace: true})).To(BeNil())
+			Expect(content(target)).To(Equal("pbo"))
+		})
+		It("does hard link files if requested", func() {
+			target = filepath.Join(dst, "linked.pbo")
+			Expect(fileutil.CopyFile(filepath.Join(src, "Addons", "Mod.pbo"), target, fileutil.Options{Link: true})).To(BeNil())
+			a, _ := os.Stat(filepath.Join(src, "Addons", "Mod.pbo"))
+			b, _ := os.Stat(target)
+			Expect(os.SameFile(a, b)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,103 @@
+package filterlog
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNoEntry is returned when parsing a line not being a filter log entry
+var ErrNoEntry = errors.New("no filter log entry")
+
+// ErrNoRestriction is returned when parsing a kick reason not caused by a filter
+var ErrNoRestriction = errors.New("no filter restriction")
+
+// Entry written to a BattlEye filter log once a filter line matched
+type Entry struct {
+	Time time.Time `json:"time"`
+	// Filter the entry got logged by, e.g. scripts or createvehicle
+	Filter string `json:"filter"`
+	// Line of the filter that matched, starting at zero for the first filter
+	Line int    `json:"line"`
+	Name string `json:"name"`
+	Addr string `json:"addr"`
+	GUID string `json:"guid,omitempty"`
+	// Data logged by BattlEye containing the matched snippet
+	Data string `json:"data"`
+}
+
+var entryPattern = regexp.MustCompile(`^(?:(\d{1,2})\.(\d{1,2})\.(\d{4}) )?(\d{1,2}):(\d{2}):(\d{2}): (.+) \(([^()]*:\d+)\) ([0-9a-fA-F]{32}|-) - #(\d+) ?(.*)$`)
+
+// ParseEntry of filter from a log line. Lines without date are assumed to be from today
+func ParseEntry(filter, line string) (*Entry, error) {
+	m := entryPattern.FindStringSubmatch(strings.TrimRight(line, "\r"))
+	if m == nil {
+		return nil, ErrNoEntry
+	}
+	now := time.Now()
+	year, month, day := now.Date()
+	if m[3] != "" {
+		year, month, day = atoi(m[3]), time.Month(atoi(m[2])), atoi(m[1])
+	}
+	e := &Entry{
+		Time:   time.Date(year, month, day, atoi(m[4]), atoi(m[5]), atoi(m[6]), 0, now.Location()),
+		Filter: filter,
+		Line:   atoi(m[10]),
+		Name:   m[7],
+		Addr:   m[8],
+		Data:   m[11],
+	}
+	if m[9] != "-" {
+		e.GUID = strings.ToLower(m[9])
+	}
+	return e, nil
+}
+
+// Snippet returns the part of Data the filter matched
+// Quoted script code is returned without quotes, the variable name for quoted variables and the first word otherwise
+func (e *Entry) Snippet() string {
+	d := strings.TrimSpace(e.Data)
+	switch {
+	case len(d) > 1 && strings.HasPrefix(d, `"`) && strings.HasSuffix(d, `"`):
+		return d[1 : len(d)-1]
+	case strings.HasPrefix(d, `"`):
+		if i := strings.Index(d[1:], `"`); i >= 0 {
+			return d[1 : i+1]
+		}
+		return d[1:]
+	}
+	if i := strings.IndexAny(d, " \t"); i >= 0 {
+		return d[:i]
+	}
+	return d
+}
+
+// Exception returns a filter exception allowing the snippet of e
+// It is meant to be appended to the filter line and should be reviewed before, as it allows exactly what got kicked
+func (e *Entry) Exception() string {
+	return `!="` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(e.Snippet()) + `"`
+}
+
+// Restriction parses a kick reason like "Script Restriction #12" returning the filter name and line
+func Restriction(reason string) (string, int, error) {
+	m := restrictionPattern.FindStringSubmatch(strings.TrimSpace(reason))
+	if m == nil {
+		return "", 0, ErrNoRestriction
+	}
+	name := strings.ToLower(strings.Replace(m[1], " ", "", -1))
+	name = strings.Replace(name, "value", "val", 1)
+	if name == "script" {
+		name = "scripts"
+	}
+	return name, atoi(m[2]), nil
+}
+
+var restrictionPattern = regexp.MustCompile(`^(\w+(?: Value)?) Restriction #(\d+)`)
+
+func atoi(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
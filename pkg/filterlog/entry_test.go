@@ -0,0 +1,82 @@
+package filterlog_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/filterlog"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestFilterlog(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Filterlog Suite")
+}
+
+const guid = "0123456789abcdef0123456789abcdef"
+
+var _ = Describe("Entry", func() {
+	Describe("ParseEntry", func() {
+		It("does parse entries with date", func() {
+			e, err := filterlog.ParseEntry("scripts", `01.03.2024 13:04:05: Some (Name) (1.2.3.4:2304) `+guid+` - #12 "_x = player setDamage 1;"`)
+			Expect(err).To(BeNil())
+			Expect(e.Time).To(Equal(time.Date(2024, 3, 1, 13, 4, 5, 0, time.Local)))
+			Expect(e.Filter).To(Equal("scripts"))
+			Expect(e.Line).To(Equal(12))
+			Expect(e.Name).To(Equal("Some (Name)"))
+			Expect(e.Addr).To(Equal("1.2.3.4:2304"))
+			Expect(e.GUID).To(Equal(guid))
+			Expect(e.Data).To(Equal(`"_x = player setDamage 1;"`))
+		})
+		It("does parse entries without date and guid", func() {
+			e, err := filterlog.ParseEntry("createvehicle", "13:04:05: Name (1.2.3.4:2304) - - #0 B_Heli_Light_01_F @012345")
+			Expect(err).To(BeNil())
+			Expect(e.Time.Hour()).To(Equal(13))
+			Expect(e.GUID).To(BeEmpty())
+			Expect(e.Data).To(Equal("B_Heli_Light_01_F @012345"))
+		})
+		It("does return ErrNoEntry on other lines", func() {
+			_, err := filterlog.ParseEntry("scripts", "13:04:05: something else")
+			Expect(err).To(Equal(filterlog.ErrNoEntry))
+		})
+	})
+
+	Describe("Exception", func() {
+		It("does use quoted script code", func() {
+			e := &filterlog.Entry{Data: `"hint \"hi\";"`}
+			Expect(e.Snippet()).To(Equal(`hint \"hi\";`))
+			Expect(e.Exception()).To(Equal(`!="hint \\\"hi\\\";"`))
+		})
+		It("does use quoted variable names", func() {
+			e := &filterlog.Entry{Data: `"life_cash" = 100`}
+			Expect(e.Exception()).To(Equal(`!="life_cash"`))
+		})
+		It("does use the first word of unquoted data", func() {
+			e := &filterlog.Entry{Data: "B_Heli_Light_01_F @012345"}
+			Expect(e.Exception()).To(Equal(`!="B_Heli_Light_01_F"`))
+		})
+	})
+
+	Describe("Restriction", func() {
+		It("does map kick reasons to filters", func() {
+			for reason, filter := range map[string]string{
+				"Script Restriction #12":              "scripts",
+				"CreateVehicle Restriction #0":        "createvehicle",
+				"PublicVariable Value Restriction #3": "publicvariableval",
+				"RemoteExec Restriction #7":           "remoteexec",
+			} {
+				f, _, err := filterlog.Restriction(reason)
+				Expect(err).To(BeNil())
+				Expect(f).To(Equal(filter), reason)
+			}
+			_, line, _ := filterlog.Restriction("Script Restriction #12")
+			Expect(line).To(Equal(12))
+		})
+		It("does return ErrNoRestriction for other reasons", func() {
+			_, _, err := filterlog.Restriction("Client not responding")
+			Expect(err).To(Equal(filterlog.ErrNoRestriction))
+		})
+	})
+})
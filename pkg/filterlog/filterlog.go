@@ -0,0 +1,316 @@
+// Package filterlog explains BattlEye filter kicks by joining them with the entries of the filter logs
+package filterlog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+	"github.com/playnet-public/gorcon/pkg/rcon"
+	"github.com/playnet-public/gorcon/pkg/rcon/battleye"
+	"github.com/playnet-public/gorcon/pkg/watcher"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// DefaultFilters tailed by the analyzer
+var DefaultFilters = []string{
+	"scripts", "createvehicle", "deletevehicle", "publicvariable", "publicvariableval",
+	"remoteexec", "remotecontrol", "setvariable", "setvariableval", "setpos", "setdamage",
+	"addweaponcargo", "addmagazinecargo", "addbackpackcargo", "attachto", "mpeventhandler",
+	"selectplayer", "teamswitch", "waypointcondition", "waypointstatement",
+}
+
+// Kick caused by a filter restriction
+type Kick struct {
+	Time   time.Time `json:"time"`
+	ID     int       `json:"id"`
+	Player string    `json:"player"`
+	GUID   string    `json:"guid,omitempty"`
+	Reason string    `json:"reason"`
+	Filter string    `json:"filter"`
+	Line   int       `json:"line"`
+	// Entry of the filter log that triggered the kick. Nil if none got logged within the window
+	Entry *Entry `json:"entry,omitempty"`
+	// Exception suggested for allowing the kicked snippet
+	Exception string `json:"exception,omitempty"`
+}
+
+// Suggestion of exceptions for a filter line collected from kicks
+type Suggestion struct {
+	Filter     string   `json:"filter"`
+	Line       int      `json:"line"`
+	Kicks      int      `json:"kicks"`
+	Exceptions []string `json:"exceptions"`
+}
+
+// Analyzer tails the filter logs in Dir and joins their entries with BattlEye kicks received as events
+type Analyzer struct {
+	// Dir containing the BattlEye filter logs
+	Dir     string
+	Filters []string
+	// Window in which a log entry and its kick have to be seen
+	Window time.Duration
+	// Journal receives a json encoded Kick for every filter kick
+	Journal io.Writer
+
+	m           sync.Mutex
+	entries     []*seen
+	pending     []*Kick
+	suggestions map[string]*Suggestion
+	now         func() time.Time
+}
+
+type seen struct {
+	at    time.Time
+	entry *Entry
+}
+
+// New Analyzer tailing the DefaultFilters logs in dir
+func New(dir string) *Analyzer {
+	return &Analyzer{
+		Dir:         dir,
+		Filters:     DefaultFilters,
+		Window:      10 * time.Second,
+		suggestions: make(map[string]*Suggestion),
+		now:         time.Now,
+	}
+}
+
+// Run the analyzer tailing the filter logs and handling all events received on in until ctx is closed or in gets closed
+func (a *Analyzer) Run(ctx context.Context, in <-chan event.Event) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for _, f := range a.Filters {
+		go a.tail(ctx, f)
+	}
+
+	tick := time.NewTicker(a.Window / 2)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.From(ctx).Info("stopping filter log analyzer", zap.Error(ctx.Err()))
+			return ctx.Err()
+		case <-tick.C:
+			a.expire(ctx)
+		case e, ok := <-in:
+			if !ok {
+				log.From(ctx).Info("stopping filter log analyzer")
+				return event.ErrInputClosed
+			}
+			if err := a.Handle(ctx, e); err != nil {
+				log.From(ctx).Error("handling event", zap.String("data", e.Data()), zap.Error(err))
+			}
+		}
+	}
+}
+
+// tail the log of filter. Rotated logs like scripts_<date>.log are followed as well
+func (a *Analyzer) tail(ctx context.Context, filter string) {
+	t := watcher.NewTailer(filepath.Join(a.Dir, filter+"[._]*log"))
+	err := t.Run(ctx, func(file, line string) {
+		e, err := ParseEntry(filter, line)
+		if err != nil {
+			return
+		}
+		a.Add(ctx, e)
+	})
+	if err != nil && err != ctx.Err() {
+		log.From(ctx).Error("tailing filter log", zap.String("filter", filter), zap.Error(err))
+	}
+}
+
+// Handle a single event by joining filter kicks with their log entries
+func (a *Analyzer) Handle(ctx context.Context, e event.Event) error {
+	if e.Kind() != string(rcon.TypePlayer) {
+		return nil
+	}
+	p, err := battleye.ParsePlayerEvent(e.Data())
+	if err != nil {
+		return errors.Wrap(err, "parsing player event")
+	}
+	if p.Kind != battleye.PlayerKicked {
+		return nil
+	}
+	filter, line, err := Restriction(p.Reason)
+	if err != nil {
+		return nil
+	}
+
+	k := &Kick{
+		Time:   a.now(),
+		ID:     p.ID,
+		Player: p.Name,
+		Reason: p.Reason,
+		Filter: filter,
+		Line:   line,
+	}
+	if p.GUID != "-" {
+		k.GUID = strings.ToLower(p.GUID)
+	}
+
+	a.m.Lock()
+	for i := len(a.entries) - 1; i >= 0; i-- {
+		if match(k, a.entries[i].entry) {
+			k.Entry = a.entries[i].entry
+			a.entries = append(a.entries[:i], a.entries[i+1:]...)
+			break
+		}
+	}
+	matched := k.Entry != nil
+	if !matched {
+		a.pending = append(a.pending, k)
+	}
+	a.m.Unlock()
+
+	if matched {
+		a.report(ctx, k)
+	}
+	return nil
+}
+
+// Add entry of a filter log, reporting the kick waiting for it
+func (a *Analyzer) Add(ctx context.Context, e *Entry) {
+	filterEntries.With(e.Filter).Inc()
+	a.m.Lock()
+	var kick *Kick
+	for i, k := range a.pending {
+		if match(k, e) {
+			kick = k
+			kick.Entry = e
+			a.pending = append(a.pending[:i], a.pending[i+1:]...)
+			break
+		}
+	}
+	if kick == nil {
+		a.entries = append(a.entries, &seen{at: a.now(), entry: e})
+	}
+	a.m.Unlock()
+
+	if kick != nil {
+		a.report(ctx, kick)
+	}
+}
+
+// Suggestions of exceptions ordered by the number of kicks caused by their filter line
+func (a *Analyzer) Suggestions() []Suggestion {
+	a.m.Lock()
+	defer a.m.Unlock()
+	var out []Suggestion
+	for _, s := range a.suggestions {
+		c := *s
+		c.Exceptions = append([]string(nil), s.Exceptions...)
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kicks != out[j].Kicks {
+			return out[i].Kicks > out[j].Kicks
+		}
+		if out[i].Filter != out[j].Filter {
+			return out[i].Filter < out[j].Filter
+		}
+		return out[i].Line < out[j].Line
+	})
+	return out
+}
+
+// expire entries and report kicks which did not get joined within the window
+func (a *Analyzer) expire(ctx context.Context) {
+	cutoff := a.now().Add(-a.Window)
+	a.m.Lock()
+	entries := a.entries[:0]
+	for _, s := range a.entries {
+		if s.at.After(cutoff) {
+			entries = append(entries, s)
+		}
+	}
+	a.entries = entries
+
+	var expired []*Kick
+	pending := a.pending[:0]
+	for _, k := range a.pending {
+		if k.Time.After(cutoff) {
+			pending = append(pending, k)
+		} else {
+			expired = append(expired, k)
+		}
+	}
+	a.pending = pending
+	a.m.Unlock()
+
+	for _, k := range expired {
+		a.report(ctx, k)
+	}
+}
+
+func (a *Analyzer) report(ctx context.Context, k *Kick) {
+	result := "unmatched"
+	if k.Entry != nil {
+		result = "matched"
+		k.Exception = k.Entry.Exception()
+		a.suggest(k)
+	}
+	filterKicks.With(k.Filter, result).Inc()
+	log.From(ctx).Info("filter kick",
+		zap.String("player", k.Player),
+		zap.String("guid", k.GUID),
+		zap.String("filter", k.Filter),
+		zap.Int("line", k.Line),
+		zap.String("data", entryData(k.Entry)),
+		zap.String("exception", k.Exception),
+	)
+	if a.Journal == nil {
+		return
+	}
+	a.m.Lock()
+	defer a.m.Unlock()
+	if err := json.NewEncoder(a.Journal).Encode(k); err != nil {
+		log.From(ctx).Error("writing journal", zap.Error(err))
+	}
+}
+
+func (a *Analyzer) suggest(k *Kick) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	key := k.Filter + "#" + strconv.Itoa(k.Line)
+	s, ok := a.suggestions[key]
+	if !ok {
+		s = &Suggestion{Filter: k.Filter, Line: k.Line}
+		a.suggestions[key] = s
+	}
+	s.Kicks++
+	for _, e := range s.Exceptions {
+		if e == k.Exception {
+			return
+		}
+	}
+	s.Exceptions = append(s.Exceptions, k.Exception)
+}
+
+// match reports whether e was logged for kick k
+func match(k *Kick, e *Entry) bool {
+	if k.Filter != e.Filter || k.Line != e.Line {
+		return false
+	}
+	if k.GUID != "" && e.GUID != "" {
+		return k.GUID == e.GUID
+	}
+	return k.Player == e.Name
+}
+
+func entryData(e *Entry) string {
+	if e == nil {
+		return ""
+	}
+	return e.Data
+}
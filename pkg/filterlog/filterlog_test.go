@@ -0,0 +1,153 @@
+package filterlog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+	"github.com/playnet-public/gorcon/pkg/filterlog"
+	"github.com/playnet-public/gorcon/pkg/rcon"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func kicked(reason string) event.Event {
+	return rcon.NewEvent(rcon.TypePlayer, "Player #3 Name ("+guid+") has been kicked by BattlEye: "+reason)
+}
+
+func entry(line int, data string) *filterlog.Entry {
+	return &filterlog.Entry{Filter: "scripts", Line: line, Name: "Name", GUID: guid, Data: data}
+}
+
+// syncBuffer guards the journal as Run writes it concurrently to the test reading it
+type syncBuffer struct {
+	m sync.Mutex
+	b bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return append([]byte(nil), b.b.Bytes()...)
+}
+
+var _ = Describe("Analyzer", func() {
+	var (
+		ctx     context.Context
+		a       *filterlog.Analyzer
+		journal *syncBuffer
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		journal = &syncBuffer{}
+		a = filterlog.New("")
+		a.Journal = journal
+	})
+
+	kicks := func() []filterlog.Kick {
+		var out []filterlog.Kick
+		dec := json.NewDecoder(bytes.NewReader(journal.Bytes()))
+		for dec.More() {
+			var k filterlog.Kick
+			Expect(dec.Decode(&k)).To(BeNil())
+			out = append(out, k)
+		}
+		return out
+	}
+
+	Describe("Handle", func() {
+		It("does join kicks with earlier entries", func() {
+			a.Add(ctx, entry(11, `"other"`))
+			a.Add(ctx, entry(12, `"hint 1;"`))
+			Expect(a.Handle(ctx, kicked("Script Restriction #12"))).To(BeNil())
+
+			Expect(kicks()).To(HaveLen(1))
+			k := kicks()[0]
+			Expect(k.Player).To(Equal("Name"))
+			Expect(k.GUID).To(Equal(guid))
+			Expect(k.Filter).To(Equal("scripts"))
+			Expect(k.Line).To(Equal(12))
+			Expect(k.Entry.Data).To(Equal(`"hint 1;"`))
+			Expect(k.Exception).To(Equal(`!="hint 1;"`))
+		})
+		It("does join kicks with later entries", func() {
+			Expect(a.Handle(ctx, kicked("Script Restriction #12"))).To(BeNil())
+			Expect(kicks()).To(BeEmpty())
+			a.Add(ctx, entry(12, `"hint 1;"`))
+			Expect(kicks()).To(HaveLen(1))
+		})
+		It("does ignore other kicks and events", func() {
+			Expect(a.Handle(ctx, kicked("Client not responding"))).To(BeNil())
+			Expect(a.Handle(ctx, rcon.NewEvent(rcon.TypeChat, "(Global) Name: hi"))).To(BeNil())
+			a.Add(ctx, entry(12, `"hint 1;"`))
+			Expect(kicks()).To(BeEmpty())
+		})
+		It("does collect suggestions", func() {
+			for _, data := range []string{`"a"`, `"b"`, `"a"`} {
+				a.Add(ctx, entry(12, data))
+				a.Handle(ctx, kicked("Script Restriction #12"))
+			}
+			a.Add(ctx, entry(3, `"c"`))
+			a.Handle(ctx, kicked("Script Restriction #3"))
+
+			Expect(a.Suggestions()).To(Equal([]filterlog.Suggestion{
+				{Filter: "scripts", Line: 12, Kicks: 3, Exceptions: []string{`!="a"`, `!="b"`}},
+				{Filter: "scripts", Line: 3, Kicks: 1, Exceptions: []string{`!="c"`}},
+			}))
+		})
+	})
+
+	Describe("Run", func() {
+		var (
+			dir    string
+			cancel context.CancelFunc
+			in     chan event.Event
+		)
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "filterlog")
+			Expect(err).To(BeNil())
+			ctx, cancel = context.WithCancel(ctx)
+			a.Dir = dir
+			a.Filters = []string{"scripts"}
+			in = make(chan event.Event)
+		})
+		AfterEach(func() {
+			cancel()
+			os.RemoveAll(dir)
+		})
+
+		It("does report unmatched kicks after the window", func() {
+			a.Window = 100 * time.Millisecond
+			go a.Run(ctx, in)
+
+			in <- kicked("Script Restriction #1")
+			Eventually(kicks).Should(HaveLen(1))
+			Expect(kicks()[0].Entry).To(BeNil())
+		})
+		It("does join kicks with tailed filter logs", func() {
+			go a.Run(ctx, in)
+
+			in <- kicked("Script Restriction #2")
+			// let the tailer start before creating the log, as it skips lines of existing logs
+			time.Sleep(50 * time.Millisecond)
+			Expect(ioutil.WriteFile(filepath.Join(dir, "scripts.log"), []byte(`13:04:05: Name (1.2.3.4:2304) `+guid+` - #2 "hint 2;"`+"\n"), 0644)).To(BeNil())
+			Eventually(kicks, 3*time.Second).Should(HaveLen(1))
+			Expect(kicks()[0].Entry.Data).To(Equal(`"hint 2;"`))
+		})
+	})
+})
@@ -0,0 +1,10 @@
+package filterlog
+
+import (
+	"github.com/playnet-public/gorcon/pkg/metrics"
+)
+
+var (
+	filterEntries = metrics.NewCounterVec("gorcon_filterlog_entries_total", "Entries read from BattlEye filter logs.", "filter")
+	filterKicks   = metrics.NewCounterVec("gorcon_filterlog_kicks_total", "Filter kicks by whether their log entry got found.", "filter", "result")
+)
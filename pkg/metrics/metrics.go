@@ -0,0 +1,366 @@
+// Package metrics offers counters, gauges and histograms exposed in the prometheus text format
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultBuckets for histograms measuring durations in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry holds metrics and writes them in the prometheus text format
+type Registry struct {
+	m       sync.RWMutex
+	metrics map[string]metric
+}
+
+// Default registry used by all package level constructors
+var Default = NewRegistry()
+
+// NewRegistry without any metrics
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]metric)}
+}
+
+type metric interface {
+	header() (name, help, kind string)
+	write(w io.Writer)
+}
+
+// register m in the registry. Registering two metrics with the same name panics
+func (r *Registry) register(m metric) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	name, _, _ := m.header()
+	if _, ok := r.metrics[name]; ok {
+		panic(errors.Errorf("metric %s registered twice", name))
+	}
+	r.metrics[name] = m
+}
+
+// Write all metrics sorted by name to w
+func (r *Registry) Write(w io.Writer) error {
+	r.m.RLock()
+	names := make([]string, 0, len(r.metrics))
+	for n := range r.metrics {
+		names = append(names, n)
+	}
+	r.m.RUnlock()
+	sort.Strings(names)
+
+	buf := bufio.NewWriter(w)
+	for _, n := range names {
+		r.m.RLock()
+		m := r.metrics[n]
+		r.m.RUnlock()
+		name, help, kind := m.header()
+		fmt.Fprintf(buf, "# HELP %s %s\n", name, escape(help, false))
+		fmt.Fprintf(buf, "# TYPE %s %s\n", name, kind)
+		m.write(buf)
+	}
+	return buf.Flush()
+}
+
+// Handler serving all metrics of the registry
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		r.Write(w)
+	})
+}
+
+// Handler serving all metrics of the Default registry
+func Handler() http.Handler {
+	return Default.Handler()
+}
+
+// vec holds one series per combination of label values
+type vec struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	m      sync.RWMutex
+	series map[string]*series
+}
+
+type series struct {
+	values []string
+
+	m       sync.Mutex
+	value   float64
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+func newVec(name, help, kind string, labels []string) *vec {
+	return &vec{
+		name:   name,
+		help:   help,
+		kind:   kind,
+		labels: labels,
+		series: make(map[string]*series),
+	}
+}
+
+func (v *vec) header() (string, string, string) { return v.name, v.help, v.kind }
+
+func (v *vec) get(values []string, init func(*series)) *series {
+	if len(values) != len(v.labels) {
+		panic(errors.Errorf("metric %s expects %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	v.m.RLock()
+	s, ok := v.series[key]
+	v.m.RUnlock()
+	if ok {
+		return s
+	}
+	v.m.Lock()
+	defer v.m.Unlock()
+	if s, ok = v.series[key]; ok {
+		return s
+	}
+	s = &series{values: append([]string(nil), values...)}
+	if init != nil {
+		init(s)
+	}
+	v.series[key] = s
+	return s
+}
+
+// Delete the series identified by values
+func (v *vec) Delete(values ...string) {
+	v.m.Lock()
+	defer v.m.Unlock()
+	delete(v.series, strings.Join(values, "\xff"))
+}
+
+func (v *vec) sorted() []*series {
+	v.m.RLock()
+	defer v.m.RUnlock()
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	list := make([]*series, len(keys))
+	for i, k := range keys {
+		list[i] = v.series[k]
+	}
+	return list
+}
+
+func (v *vec) write(w io.Writer) {
+	for _, s := range v.sorted() {
+		s.m.Lock()
+		fmt.Fprintf(w, "%s%s %s\n", v.name, labels(v.labels, s.values), format(s.value))
+		s.m.Unlock()
+	}
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct{ *vec }
+
+// Counter only ever increases
+type Counter struct{ s *series }
+
+// NewCounterVec registered in the Default registry
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return Default.NewCounterVec(name, help, labels...)
+}
+
+// NewCounterVec registered in r
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{newVec(name, help, "counter", labels)}
+	r.register(c)
+	return c
+}
+
+// With returns the counter for label values
+func (c *CounterVec) With(values ...string) Counter {
+	return Counter{c.get(values, nil)}
+}
+
+// Inc increments the counter by one
+func (c Counter) Inc() { c.Add(1) }
+
+// Add v to the counter. Negative values are ignored
+func (c Counter) Add(v float64) {
+	if v < 0 {
+		return
+	}
+	c.s.m.Lock()
+	defer c.s.m.Unlock()
+	c.s.value += v
+}
+
+// Value of the counter
+func (c Counter) Value() float64 {
+	c.s.m.Lock()
+	defer c.s.m.Unlock()
+	return c.s.value
+}
+
+// GaugeVec is a gauge partitioned by labels
+type GaugeVec struct{ *vec }
+
+// Gauge can be set to arbitrary values
+type Gauge struct{ s *series }
+
+// NewGaugeVec registered in the Default registry
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return Default.NewGaugeVec(name, help, labels...)
+}
+
+// NewGaugeVec registered in r
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{newVec(name, help, "gauge", labels)}
+	r.register(g)
+	return g
+}
+
+// With returns the gauge for label values
+func (g *GaugeVec) With(values ...string) Gauge {
+	return Gauge{g.get(values, nil)}
+}
+
+// Set the gauge to v
+func (g Gauge) Set(v float64) {
+	g.s.m.Lock()
+	defer g.s.m.Unlock()
+	g.s.value = v
+}
+
+// Add v to the gauge
+func (g Gauge) Add(v float64) {
+	g.s.m.Lock()
+	defer g.s.m.Unlock()
+	g.s.value += v
+}
+
+// Inc increments the gauge by one
+func (g Gauge) Inc() { g.Add(1) }
+
+// Dec decrements the gauge by one
+func (g Gauge) Dec() { g.Add(-1) }
+
+// Value of the gauge
+func (g Gauge) Value() float64 {
+	g.s.m.Lock()
+	defer g.s.m.Unlock()
+	return g.s.value
+}
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct {
+	*vec
+	bounds []float64
+}
+
+// Histogram counts observations in buckets
+type Histogram struct {
+	s      *series
+	bounds []float64
+}
+
+// NewHistogramVec registered in the Default registry using buckets or DefaultBuckets if empty
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return Default.NewHistogramVec(name, help, buckets, labels...)
+}
+
+// NewHistogramVec registered in r using buckets or DefaultBuckets if empty
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) < 1 {
+		buckets = DefaultBuckets
+	}
+	bounds := append([]float64(nil), buckets...)
+	sort.Float64s(bounds)
+	h := &HistogramVec{newVec(name, help, "histogram", labels), bounds}
+	r.register(h)
+	return h
+}
+
+// With returns the histogram for label values
+func (h *HistogramVec) With(values ...string) Histogram {
+	s := h.get(values, func(s *series) { s.buckets = make([]uint64, len(h.bounds)) })
+	return Histogram{s, h.bounds}
+}
+
+// Observe a single value
+func (h Histogram) Observe(v float64) {
+	h.s.m.Lock()
+	defer h.s.m.Unlock()
+	for i, b := range h.bounds {
+		if v <= b {
+			h.s.buckets[i]++
+		}
+	}
+	h.s.sum += v
+	h.s.count++
+}
+
+// Count of all observations
+func (h Histogram) Count() uint64 {
+	h.s.m.Lock()
+	defer h.s.m.Unlock()
+	return h.s.count
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	names := append(append([]string(nil), h.labels...), "le")
+	for _, s := range h.sorted() {
+		s.m.Lock()
+		for i, b := range h.bounds {
+			values := append(append([]string(nil), s.values...), format(b))
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labels(names, values), s.buckets[i])
+		}
+		values := append(append([]string(nil), s.values...), "+Inf")
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labels(names, values), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labels(h.labels, s.values), format(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labels(h.labels, s.values), s.count)
+		s.m.Unlock()
+	}
+}
+
+func labels(names, values []string) string {
+	if len(names) < 1 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, n := range names {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, n, escape(values[i], true))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escape(s string, quotes bool) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	if quotes {
+		s = strings.Replace(s, `"`, `\"`, -1)
+	}
+	return s
+}
+
+func format(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
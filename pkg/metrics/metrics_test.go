@@ -0,0 +1,118 @@
+package metrics_test
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/playnet-public/gorcon/pkg/metrics"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestMetrics(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Metrics Suite")
+}
+
+var _ = Describe("Metrics", func() {
+
+	var r *metrics.Registry
+
+	BeforeEach(func() {
+		r = metrics.NewRegistry()
+	})
+
+	output := func() string {
+		var buf bytes.Buffer
+		Expect(r.Write(&buf)).To(BeNil())
+		return buf.String()
+	}
+
+	Describe("Counter", func() {
+		It("does count per label values", func() {
+			c := r.NewCounterVec("test_total", "Test counter.", "server")
+			c.With("a").Inc()
+			c.With("a").Add(2)
+			c.With("b").Inc()
+			Expect(c.With("a").Value()).To(BeEquivalentTo(3))
+			Expect(output()).To(Equal("# HELP test_total Test counter.\n# TYPE test_total counter\n" +
+				"test_total{server=\"a\"} 3\ntest_total{server=\"b\"} 1\n"))
+		})
+		It("does ignore negative values", func() {
+			c := r.NewCounterVec("test_total", "Test counter.")
+			c.With().Add(-1)
+			Expect(c.With().Value()).To(BeZero())
+		})
+		It("does escape label values", func() {
+			c := r.NewCounterVec("test_total", "Test counter.", "name")
+			c.With("a\"b\\c\n").Inc()
+			Expect(output()).To(ContainSubstring(`test_total{name="a\"b\\c\n"} 1`))
+		})
+		It("does panic on wrong label count", func() {
+			c := r.NewCounterVec("test_total", "Test counter.", "name")
+			Expect(func() { c.With() }).To(Panic())
+		})
+	})
+
+	Describe("Gauge", func() {
+		It("does set and change values", func() {
+			g := r.NewGaugeVec("test", "Test gauge.")
+			g.With().Set(5)
+			g.With().Inc()
+			g.With().Dec()
+			g.With().Add(-2.5)
+			Expect(g.With().Value()).To(BeEquivalentTo(2.5))
+			Expect(output()).To(ContainSubstring("# TYPE test gauge\ntest 2.5\n"))
+		})
+		It("does delete series", func() {
+			g := r.NewGaugeVec("test", "Test gauge.", "name")
+			g.With("a").Set(1)
+			g.Delete("a")
+			Expect(output()).NotTo(ContainSubstring("name="))
+		})
+	})
+
+	Describe("Histogram", func() {
+		It("does count observations in buckets", func() {
+			h := r.NewHistogramVec("test_seconds", "Test histogram.", []float64{1, 0.5}, "server")
+			h.With("a").Observe(0.2)
+			h.With("a").Observe(0.7)
+			h.With("a").Observe(3)
+			Expect(h.With("a").Count()).To(BeEquivalentTo(3))
+			Expect(output()).To(Equal("# HELP test_seconds Test histogram.\n# TYPE test_seconds histogram\n" +
+				"test_seconds_bucket{server=\"a\",le=\"0.5\"} 1\n" +
+				"test_seconds_bucket{server=\"a\",le=\"1\"} 2\n" +
+				"test_seconds_bucket{server=\"a\",le=\"+Inf\"} 3\n" +
+				"test_seconds_sum{server=\"a\"} 3.9\n" +
+				"test_seconds_count{server=\"a\"} 3\n"))
+		})
+		It("does use default buckets", func() {
+			h := r.NewHistogramVec("test_seconds", "Test histogram.", nil)
+			h.With().Observe(1)
+			Expect(output()).To(ContainSubstring(`test_seconds_bucket{le="10"} 1`))
+		})
+	})
+
+	Describe("Registry", func() {
+		It("does sort metrics by name", func() {
+			r.NewCounterVec("b_total", "B.").With().Inc()
+			r.NewCounterVec("a_total", "A.").With().Inc()
+			out := output()
+			Expect(out).To(HavePrefix("# HELP a_total"))
+		})
+		It("does panic on duplicate names", func() {
+			r.NewCounterVec("test_total", "Test counter.")
+			Expect(func() { r.NewGaugeVec("test_total", "Test gauge.") }).To(Panic())
+		})
+		It("does serve metrics", func() {
+			r.NewCounterVec("test_total", "Test counter.").With().Inc()
+			rec := httptest.NewRecorder()
+			r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+			Expect(rec.Code).To(Equal(200))
+			Expect(rec.Header().Get("Content-Type")).To(HavePrefix("text/plain"))
+			Expect(rec.Body.String()).To(ContainSubstring("test_total 1"))
+		})
+	})
+})
@@ -52,6 +52,13 @@ type RconConnection struct {
 		arg1 context.Context
 		arg2 chan<- event.Event
 	}
+	SubscribeFilterStub        func(context.Context, chan<- event.Event, event.Filter)
+	subscribeFilterMutex       sync.RWMutex
+	subscribeFilterArgsForCall []struct {
+		arg1 context.Context
+		arg2 chan<- event.Event
+		arg3 event.Filter
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -229,6 +236,32 @@ func (fake *RconConnection) SubscribeArgsForCall(i int) (context.Context, chan<-
 	return fake.subscribeArgsForCall[i].arg1, fake.subscribeArgsForCall[i].arg2
 }
 
+func (fake *RconConnection) SubscribeFilter(arg1 context.Context, arg2 chan<- event.Event, arg3 event.Filter) {
+	fake.subscribeFilterMutex.Lock()
+	fake.subscribeFilterArgsForCall = append(fake.subscribeFilterArgsForCall, struct {
+		arg1 context.Context
+		arg2 chan<- event.Event
+		arg3 event.Filter
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("SubscribeFilter", []interface{}{arg1, arg2, arg3})
+	fake.subscribeFilterMutex.Unlock()
+	if fake.SubscribeFilterStub != nil {
+		fake.SubscribeFilterStub(arg1, arg2, arg3)
+	}
+}
+
+func (fake *RconConnection) SubscribeFilterCallCount() int {
+	fake.subscribeFilterMutex.RLock()
+	defer fake.subscribeFilterMutex.RUnlock()
+	return len(fake.subscribeFilterArgsForCall)
+}
+
+func (fake *RconConnection) SubscribeFilterArgsForCall(i int) (context.Context, chan<- event.Event, event.Filter) {
+	fake.subscribeFilterMutex.RLock()
+	defer fake.subscribeFilterMutex.RUnlock()
+	return fake.subscribeFilterArgsForCall[i].arg1, fake.subscribeFilterArgsForCall[i].arg2, fake.subscribeFilterArgsForCall[i].arg3
+}
+
 func (fake *RconConnection) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -240,6 +273,8 @@ func (fake *RconConnection) Invocations() map[string][][]interface{} {
 	defer fake.writeMutex.RUnlock()
 	fake.subscribeMutex.RLock()
 	defer fake.subscribeMutex.RUnlock()
+	fake.subscribeFilterMutex.RLock()
+	defer fake.subscribeFilterMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
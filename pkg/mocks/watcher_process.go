@@ -33,6 +33,15 @@ type Process struct {
 	stopReturnsOnCall map[int]struct {
 		result1 error
 	}
+	PIDStub        func() int
+	pIDMutex       sync.RWMutex
+	pIDArgsForCall []struct{}
+	pIDReturns     struct {
+		result1 int
+	}
+	pIDReturnsOnCall map[int]struct {
+		result1 int
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -142,6 +151,46 @@ func (fake *Process) StopReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *Process) PID() int {
+	fake.pIDMutex.Lock()
+	ret, specificReturn := fake.pIDReturnsOnCall[len(fake.pIDArgsForCall)]
+	fake.pIDArgsForCall = append(fake.pIDArgsForCall, struct{}{})
+	fake.recordInvocation("PID", []interface{}{})
+	fake.pIDMutex.Unlock()
+	if fake.PIDStub != nil {
+		return fake.PIDStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.pIDReturns.result1
+}
+
+func (fake *Process) PIDCallCount() int {
+	fake.pIDMutex.RLock()
+	defer fake.pIDMutex.RUnlock()
+	return len(fake.pIDArgsForCall)
+}
+
+func (fake *Process) PIDReturns(result1 int) {
+	fake.PIDStub = nil
+	fake.pIDReturns = struct {
+		result1 int
+	}{result1}
+}
+
+func (fake *Process) PIDReturnsOnCall(i int, result1 int) {
+	fake.PIDStub = nil
+	if fake.pIDReturnsOnCall == nil {
+		fake.pIDReturnsOnCall = make(map[int]struct {
+			result1 int
+		})
+	}
+	fake.pIDReturnsOnCall[i] = struct {
+		result1 int
+	}{result1}
+}
+
 func (fake *Process) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -151,6 +200,8 @@ func (fake *Process) Invocations() map[string][][]interface{} {
 	defer fake.runMutex.RUnlock()
 	fake.stopMutex.RLock()
 	defer fake.stopMutex.RUnlock()
+	fake.pIDMutex.RLock()
+	defer fake.pIDMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -0,0 +1,305 @@
+// Package moderation offers automatic moderation of in-game chat based on rules and escalating actions
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+	"github.com/playnet-public/gorcon/pkg/rcon"
+	"github.com/playnet-public/gorcon/pkg/rcon/battleye"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// Action taken against players violating a rule
+type Action string
+
+const (
+	// Warn the player with a private message
+	Warn Action = "warn"
+	// Kick the player from the server
+	Kick Action = "kick"
+	// Ban the player for the duration of the step
+	Ban Action = "ban"
+)
+
+// Step of the escalation being applied once a player reaches Strikes
+type Step struct {
+	Strikes int
+	Action  Action
+	// Duration of bans rounded up to full minutes, bans last at least a minute
+	Duration time.Duration
+	Message  string
+}
+
+// DefaultEscalation warns on the first strike, kicks on the third and bans for an hour on the fifth
+var DefaultEscalation = []Step{
+	{Strikes: 1, Action: Warn, Message: "Watch your language"},
+	{Strikes: 3, Action: Kick, Message: "Chat rule violation"},
+	{Strikes: 5, Action: Ban, Duration: time.Hour, Message: "Repeated chat rule violations"},
+}
+
+// Record describes an action taken by the engine and is written to the journal for review
+type Record struct {
+	Timestamp time.Time `json:"timestamp"`
+	Rule      string    `json:"rule"`
+	Channel   string    `json:"channel"`
+	Player    string    `json:"player"`
+	GUID      string    `json:"guid,omitempty"`
+	ID        int       `json:"id"`
+	Text      string    `json:"text"`
+	Strikes   int       `json:"strikes"`
+	Action    Action    `json:"action"`
+	Command   string    `json:"command"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Engine consumes chat events, matches them against its rules and issues escalating actions via rcon
+type Engine struct {
+	Rcon       rcon.Writer
+	Rules      []*Rule
+	Escalation []Step
+	// Decay removes one strike from a player for every Decay passed since the last change. Strikes never decay if zero
+	Decay time.Duration
+	// Journal receives a json encoded Record for every action taken
+	Journal io.Writer
+	// Timeout waiting for the player list response when seeding the roster
+	Timeout time.Duration
+
+	roster *battleye.Roster
+
+	m       sync.Mutex
+	strikes map[string]*strikes
+	now     func() time.Time
+}
+
+type strikes struct {
+	count int
+	last  time.Time
+}
+
+// New Engine sending commands to r and applying rules with DefaultEscalation
+func New(r rcon.Writer, rules ...*Rule) *Engine {
+	return &Engine{
+		Rcon:       r,
+		Rules:      rules,
+		Escalation: DefaultEscalation,
+		Decay:      30 * time.Minute,
+		Timeout:    5 * time.Second,
+		roster:     battleye.NewRoster(),
+		strikes:    make(map[string]*strikes),
+		now:        time.Now,
+	}
+}
+
+// Run the engine handling all events received on in until ctx is closed or in gets closed
+// The roster gets seeded first, so players already on the server are moderated as well
+func (e *Engine) Run(ctx context.Context, in <-chan event.Event) error {
+	if err := e.Seed(ctx); err != nil {
+		log.From(ctx).Error("seeding players", zap.Error(err))
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			log.From(ctx).Info("stopping moderation", zap.Error(ctx.Err()))
+			return ctx.Err()
+		case ev, ok := <-in:
+			if !ok {
+				log.From(ctx).Info("stopping moderation")
+				return event.ErrInputClosed
+			}
+			if err := e.Handle(ctx, ev); err != nil {
+				log.From(ctx).Error("handling event", zap.String("data", ev.Data()), zap.Error(err))
+			}
+		}
+	}
+}
+
+// Seed the roster with the players currently on the server
+// Otherwise players are only known once their connect events arrive
+func (e *Engine) Seed(ctx context.Context) error {
+	trm, err := e.Rcon.Write(rcon.WithPriority(ctx, rcon.Moderation), battleye.Players)
+	if err != nil {
+		return errors.Wrap(err, "requesting players")
+	}
+	select {
+	case <-trm.Done():
+	case <-time.After(e.Timeout):
+		return errors.New("timeout waiting for players")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	players, err := battleye.ParsePlayers(trm.Response())
+	if err != nil {
+		return errors.Wrap(err, "parsing players")
+	}
+	e.roster.Add(players...)
+	return nil
+}
+
+// Handle a single event by tracking players and moderating chat messages
+func (e *Engine) Handle(ctx context.Context, ev event.Event) error {
+	switch ev.Kind() {
+	case string(rcon.TypePlayer):
+		p, err := battleye.ParsePlayerEvent(ev.Data())
+		if err != nil {
+			return errors.Wrap(err, "parsing player event")
+		}
+		e.roster.Update(p)
+		return nil
+	case string(rcon.TypeChat):
+		msg, err := battleye.ParseChatMessage(ev.Data())
+		if err != nil {
+			return errors.Wrap(err, "parsing chat message")
+		}
+		return e.moderate(ctx, msg)
+	}
+	return nil
+}
+
+// Strikes returns the current strike count for player identified by guid or name
+func (e *Engine) Strikes(key string) int {
+	e.m.Lock()
+	defer e.m.Unlock()
+	s, ok := e.strikes[strings.ToLower(key)]
+	if !ok {
+		return 0
+	}
+	return e.decay(s)
+}
+
+func (e *Engine) moderate(ctx context.Context, msg *battleye.ChatMessage) error {
+	for _, r := range e.Rules {
+		if !r.Match(msg.Channel, msg.Text) {
+			continue
+		}
+
+		p, ok := e.roster.ByName(msg.Name)
+		if !ok {
+			return errors.Errorf("unknown player %q", msg.Name)
+		}
+		key := p.GUID
+		if key == "" {
+			key = p.Name
+		}
+		count := e.strike(key, r.strikes())
+
+		step, ok := e.step(count)
+		if !ok {
+			return nil
+		}
+
+		rec := Record{
+			Timestamp: e.now(),
+			Rule:      r.Name,
+			Channel:   msg.Channel,
+			Player:    p.Name,
+			GUID:      p.GUID,
+			ID:        p.ID,
+			Text:      msg.Text,
+			Strikes:   count,
+			Action:    step.Action,
+			Command:   command(p.ID, step),
+		}
+		_, err := e.Rcon.Write(rcon.WithPriority(ctx, rcon.Moderation), rec.Command)
+		if err != nil {
+			rec.Error = err.Error()
+		}
+		e.journal(ctx, rec)
+		return errors.Wrap(err, "executing action")
+	}
+	return nil
+}
+
+func (e *Engine) strike(key string, n int) int {
+	e.m.Lock()
+	defer e.m.Unlock()
+	key = strings.ToLower(key)
+	s, ok := e.strikes[key]
+	if !ok {
+		s = &strikes{}
+		e.strikes[key] = s
+	}
+	e.decay(s)
+	s.count += n
+	s.last = e.now()
+	return s.count
+}
+
+// decay removes strikes passed since the last change and returns the remaining count
+// The caller has to hold the lock
+func (e *Engine) decay(s *strikes) int {
+	if e.Decay <= 0 || s.count < 1 {
+		return s.count
+	}
+	passed := int(e.now().Sub(s.last) / e.Decay)
+	if passed < 1 {
+		return s.count
+	}
+	s.count -= passed
+	if s.count < 0 {
+		s.count = 0
+	}
+	s.last = s.last.Add(time.Duration(passed) * e.Decay)
+	return s.count
+}
+
+// step returns the highest escalation step reached by count
+func (e *Engine) step(count int) (Step, bool) {
+	steps := make([]Step, len(e.Escalation))
+	copy(steps, e.Escalation)
+	sort.Slice(steps, func(i, j int) bool { return steps[i].Strikes > steps[j].Strikes })
+	for _, s := range steps {
+		if count >= s.Strikes {
+			return s, true
+		}
+	}
+	return Step{}, false
+}
+
+func command(id int, s Step) string {
+	switch s.Action {
+	case Kick:
+		return battleye.Kick(id, s.Message)
+	case Ban:
+		return battleye.Ban(id, minutes(s.Duration), s.Message)
+	}
+	return battleye.Say(id, s.Message)
+}
+
+// minutes of d rounded up, but at least one as bans of zero minutes are permanent
+func minutes(d time.Duration) int {
+	m := int((d + time.Minute - 1) / time.Minute)
+	if m < 1 {
+		return 1
+	}
+	return m
+}
+
+func (e *Engine) journal(ctx context.Context, rec Record) {
+	log.From(ctx).Info("moderating player",
+		zap.String("rule", rec.Rule),
+		zap.String("player", rec.Player),
+		zap.String("guid", rec.GUID),
+		zap.Int("strikes", rec.Strikes),
+		zap.String("action", string(rec.Action)),
+		zap.String("command", rec.Command),
+		zap.String("error", rec.Error),
+	)
+	if e.Journal == nil {
+		return
+	}
+	e.m.Lock()
+	defer e.m.Unlock()
+	if err := json.NewEncoder(e.Journal).Encode(rec); err != nil {
+		log.From(ctx).Error("writing journal", zap.Error(err))
+	}
+}
@@ -0,0 +1,52 @@
+package moderation
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Engine", func() {
+	var (
+		e   *Engine
+		now time.Time
+	)
+
+	BeforeEach(func() {
+		e = New(nil)
+		e.Decay = time.Minute
+		now = time.Now()
+		e.now = func() time.Time { return now }
+	})
+
+	Describe("strike", func() {
+		It("does add strikes", func() {
+			e.strike("test", 1)
+			Expect(e.strike("test", 2)).To(BeEquivalentTo(3))
+		})
+		It("does decay one strike per interval", func() {
+			e.strike("test", 3)
+			now = now.Add(2*time.Minute + time.Second)
+			Expect(e.Strikes("test")).To(BeEquivalentTo(1))
+		})
+		It("does not decay below zero", func() {
+			e.strike("test", 1)
+			now = now.Add(time.Hour)
+			Expect(e.Strikes("test")).To(BeEquivalentTo(0))
+		})
+		It("does not decay if disabled", func() {
+			e.Decay = 0
+			e.strike("test", 1)
+			now = now.Add(time.Hour)
+			Expect(e.Strikes("test")).To(BeEquivalentTo(1))
+		})
+		It("does keep partial intervals", func() {
+			e.strike("test", 2)
+			now = now.Add(90 * time.Second)
+			Expect(e.Strikes("test")).To(BeEquivalentTo(1))
+			now = now.Add(30 * time.Second)
+			Expect(e.Strikes("test")).To(BeEquivalentTo(0))
+		})
+	})
+})
@@ -0,0 +1,181 @@
+package moderation_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+	"github.com/playnet-public/gorcon/pkg/mocks"
+	"github.com/playnet-public/gorcon/pkg/moderation"
+	"github.com/playnet-public/gorcon/pkg/rcon"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestModeration(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Moderation Suite")
+}
+
+const guid = "0123456789abcdef0123456789abcdef"
+
+var _ = Describe("Engine", func() {
+	var (
+		ctx     context.Context
+		r       *mocks.RconWriter
+		e       *moderation.Engine
+		journal *bytes.Buffer
+	)
+
+	chat := func(text string) event.Event {
+		return rcon.NewEvent(rcon.TypeChat, "(Global) Test: "+text)
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		r = &mocks.RconWriter{}
+		rule, _ := moderation.NewRule("language", "", "badword")
+		e = moderation.New(r, rule)
+		journal = &bytes.Buffer{}
+		e.Journal = journal
+		e.Escalation = []moderation.Step{
+			{Strikes: 1, Action: moderation.Warn, Message: "warning"},
+			{Strikes: 2, Action: moderation.Kick, Message: "kicked"},
+			{Strikes: 3, Action: moderation.Ban, Duration: 2 * time.Hour, Message: "banned"},
+		}
+		e.Handle(ctx, rcon.NewEvent(rcon.TypePlayer, "Player #4 Test (127.0.0.1:2304) connected"))
+		e.Handle(ctx, rcon.NewEvent(rcon.TypePlayer, "Verified GUID ("+guid+") of player #4 Test"))
+	})
+
+	Describe("Run", func() {
+		BeforeEach(func() {
+			done := make(chan bool, 1)
+			done <- true
+			trm := &mocks.RconTransmission{}
+			trm.DoneReturns(done)
+			trm.ResponseReturns(`Players on server:
+[#] [IP Address]:[Port] [Ping] [GUID] [Name]
+--------------------------------------------------
+7   127.0.0.1:2304        31   fedcba9876543210fedcba9876543210(OK) Present
+(1 players in total)`)
+			r.WriteReturns(trm, nil)
+		})
+
+		It("does exit on closed context", func() {
+			ctx, close := context.WithCancel(ctx)
+			close()
+			Expect(e.Run(ctx, make(chan event.Event))).To(BeEquivalentTo(context.Canceled))
+		})
+		It("does return error on closed input", func() {
+			in := make(chan event.Event)
+			close(in)
+			Expect(e.Run(ctx, in)).To(BeEquivalentTo(event.ErrInputClosed))
+		})
+		It("does moderate incoming chat", func() {
+			in := make(chan event.Event)
+			go e.Run(ctx, in)
+			in <- chat("badword")
+			Eventually(r.WriteCallCount).Should(BeEquivalentTo(2))
+		})
+		It("does moderate players already on the server", func() {
+			in := make(chan event.Event)
+			go e.Run(ctx, in)
+			in <- rcon.NewEvent(rcon.TypeChat, "(Global) Present: badword")
+			Eventually(r.WriteCallCount).Should(BeEquivalentTo(2))
+			_, cmd := r.WriteArgsForCall(0)
+			Expect(cmd).To(BeEquivalentTo("players"))
+			_, cmd = r.WriteArgsForCall(1)
+			Expect(cmd).To(BeEquivalentTo("say 7 warning"))
+		})
+		It("does run without players if seeding fails", func() {
+			r.WriteReturnsOnCall(0, nil, errors.New("test"))
+			in := make(chan event.Event)
+			go e.Run(ctx, in)
+			in <- chat("badword")
+			Eventually(r.WriteCallCount).Should(BeEquivalentTo(2))
+		})
+	})
+
+	Describe("Handle", func() {
+		It("does ignore clean messages", func() {
+			Expect(e.Handle(ctx, chat("hello"))).To(BeNil())
+			Expect(r.WriteCallCount()).To(BeEquivalentTo(0))
+		})
+		It("does warn the player on first strike", func() {
+			Expect(e.Handle(ctx, chat("badword"))).To(BeNil())
+			_, cmd := r.WriteArgsForCall(0)
+			Expect(cmd).To(BeEquivalentTo("say 4 warning"))
+		})
+		It("does escalate to kick", func() {
+			e.Handle(ctx, chat("badword"))
+			e.Handle(ctx, chat("badword"))
+			_, cmd := r.WriteArgsForCall(1)
+			Expect(cmd).To(BeEquivalentTo("kick 4 kicked"))
+		})
+		It("does escalate to temporary ban", func() {
+			e.Handle(ctx, chat("badword"))
+			e.Handle(ctx, chat("badword"))
+			e.Handle(ctx, chat("badword"))
+			_, cmd := r.WriteArgsForCall(2)
+			Expect(cmd).To(BeEquivalentTo("ban 4 120 banned"))
+		})
+		It("does not ban permanently for durations below a minute", func() {
+			e.Escalation = []moderation.Step{{Strikes: 1, Action: moderation.Ban, Duration: 30 * time.Second, Message: "banned"}}
+			e.Handle(ctx, chat("badword"))
+			_, cmd := r.WriteArgsForCall(0)
+			Expect(cmd).To(BeEquivalentTo("ban 4 1 banned"))
+		})
+		It("does round ban durations up to full minutes", func() {
+			e.Escalation = []moderation.Step{{Strikes: 1, Action: moderation.Ban, Duration: 90 * time.Second, Message: "banned"}}
+			e.Handle(ctx, chat("badword"))
+			_, cmd := r.WriteArgsForCall(0)
+			Expect(cmd).To(BeEquivalentTo("ban 4 2 banned"))
+		})
+		It("does count strikes by guid", func() {
+			e.Handle(ctx, chat("badword"))
+			Expect(e.Strikes(guid)).To(BeEquivalentTo(1))
+		})
+		It("does add rule strikes", func() {
+			e.Rules[0].Strikes = 2
+			e.Handle(ctx, chat("badword"))
+			_, cmd := r.WriteArgsForCall(0)
+			Expect(cmd).To(BeEquivalentTo("kick 4 kicked"))
+		})
+		It("does not act below the first step", func() {
+			e.Escalation = []moderation.Step{{Strikes: 2, Action: moderation.Kick}}
+			Expect(e.Handle(ctx, chat("badword"))).To(BeNil())
+			Expect(r.WriteCallCount()).To(BeEquivalentTo(0))
+		})
+		It("does return error for unknown players", func() {
+			err := e.Handle(ctx, rcon.NewEvent(rcon.TypeChat, "(Global) Unknown: badword"))
+			Expect(err).NotTo(BeNil())
+			Expect(r.WriteCallCount()).To(BeEquivalentTo(0))
+		})
+		It("does forget disconnected players", func() {
+			e.Handle(ctx, rcon.NewEvent(rcon.TypePlayer, "Player #4 Test disconnected"))
+			Expect(e.Handle(ctx, chat("badword"))).NotTo(BeNil())
+		})
+		It("does write actions to journal", func() {
+			e.Handle(ctx, chat("badword"))
+			var rec moderation.Record
+			Expect(json.Unmarshal(journal.Bytes(), &rec)).To(BeNil())
+			Expect(rec.Rule).To(BeEquivalentTo("language"))
+			Expect(rec.GUID).To(BeEquivalentTo(guid))
+			Expect(rec.Action).To(BeEquivalentTo(moderation.Warn))
+			Expect(rec.Command).To(BeEquivalentTo("say 4 warning"))
+			Expect(rec.Error).To(BeEmpty())
+		})
+		It("does journal and return failed actions", func() {
+			r.WriteReturns(nil, errors.New("test"))
+			Expect(e.Handle(ctx, chat("badword"))).NotTo(BeNil())
+			var rec moderation.Record
+			Expect(json.Unmarshal(journal.Bytes(), &rec)).To(BeNil())
+			Expect(rec.Error).To(BeEquivalentTo("test"))
+		})
+	})
+})
@@ -0,0 +1,83 @@
+package moderation
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Rule describes chat messages which should be moderated
+type Rule struct {
+	// Name identifying the rule in logs and warnings
+	Name string
+	// Pattern matching offending messages
+	Pattern *regexp.Regexp
+	// Words which are not allowed in messages. Matching is case insensitive and respects word boundaries
+	Words []string
+	// Channels the rule applies to (e.g. Global, Side). The rule applies to all channels if empty
+	Channels []string
+	// Strikes added to a player for every match. Defaults to one
+	Strikes int
+
+	once  sync.Once
+	words *regexp.Regexp
+}
+
+// NewRule with name matching messages by pattern and words. An empty pattern only matches by words
+func NewRule(name, pattern string, words ...string) (*Rule, error) {
+	r := &Rule{
+		Name:    name,
+		Words:   words,
+		Strikes: 1,
+	}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.Wrap(err, "compiling pattern")
+		}
+		r.Pattern = re
+	}
+	return r, nil
+}
+
+// Match returns true if text sent in channel violates the rule
+func (r *Rule) Match(channel, text string) bool {
+	if !r.applies(channel) {
+		return false
+	}
+	if r.Pattern != nil && r.Pattern.MatchString(text) {
+		return true
+	}
+	if len(r.Words) < 1 {
+		return false
+	}
+	r.once.Do(func() {
+		quoted := make([]string, len(r.Words))
+		for i, w := range r.Words {
+			quoted[i] = regexp.QuoteMeta(w)
+		}
+		r.words = regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`)
+	})
+	return r.words.MatchString(text)
+}
+
+func (r *Rule) applies(channel string) bool {
+	if len(r.Channels) < 1 {
+		return true
+	}
+	for _, c := range r.Channels {
+		if strings.EqualFold(c, channel) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) strikes() int {
+	if r.Strikes < 1 {
+		return 1
+	}
+	return r.Strikes
+}
@@ -0,0 +1,48 @@
+package moderation_test
+
+import (
+	"github.com/playnet-public/gorcon/pkg/moderation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Rule", func() {
+	Describe("NewRule", func() {
+		It("does return error on invalid pattern", func() {
+			_, err := moderation.NewRule("test", "(")
+			Expect(err).NotTo(BeNil())
+		})
+		It("does default to one strike", func() {
+			r, err := moderation.NewRule("test", "")
+			Expect(err).To(BeNil())
+			Expect(r.Strikes).To(BeEquivalentTo(1))
+		})
+	})
+
+	Describe("Match", func() {
+		It("does match by pattern", func() {
+			r, _ := moderation.NewRule("test", `discord\.gg/\w+`)
+			Expect(r.Match("Global", "join discord.gg/abc")).To(BeTrue())
+			Expect(r.Match("Global", "join us")).To(BeFalse())
+		})
+		It("does match words case insensitive", func() {
+			r, _ := moderation.NewRule("test", "", "badword")
+			Expect(r.Match("Global", "this is a BadWord!")).To(BeTrue())
+		})
+		It("does respect word boundaries", func() {
+			r, _ := moderation.NewRule("test", "", "ass")
+			Expect(r.Match("Global", "passing by")).To(BeFalse())
+		})
+		It("does not match without pattern and words", func() {
+			r, _ := moderation.NewRule("test", "")
+			Expect(r.Match("Global", "anything")).To(BeFalse())
+		})
+		It("does only match configured channels", func() {
+			r, _ := moderation.NewRule("test", "", "badword")
+			r.Channels = []string{"side"}
+			Expect(r.Match("Side", "badword")).To(BeTrue())
+			Expect(r.Match("Global", "badword")).To(BeFalse())
+		})
+	})
+})
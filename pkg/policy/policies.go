@@ -0,0 +1,152 @@
+package policy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/rcon/battleye"
+)
+
+// key identifies a player across polls. Ids get reused once players leave so the name is part of it
+func key(p battleye.Player) string {
+	return fmt.Sprintf("%d/%s", p.ID, p.Name)
+}
+
+// HighPing kicks players whose ping stays above Max for Polls consecutive polls
+type HighPing struct {
+	Max     int
+	Polls   int
+	Message string
+	Exempt  Exemptions
+
+	m      sync.Mutex
+	counts map[string]int
+}
+
+// NewHighPing policy kicking players above max ping for polls consecutive polls
+func NewHighPing(max, polls int) *HighPing {
+	return &HighPing{
+		Max:     max,
+		Polls:   polls,
+		Message: fmt.Sprintf("Ping too high (max %d)", max),
+		counts:  make(map[string]int),
+	}
+}
+
+// Name of the policy
+func (h *HighPing) Name() string { return "high-ping" }
+
+// Evaluate players and return the ones exceeding the ping limit for too long
+func (h *HighPing) Evaluate(now time.Time, players []battleye.Player) []Violation {
+	h.m.Lock()
+	defer h.m.Unlock()
+	counts := make(map[string]int)
+	var violations []Violation
+	for _, p := range players {
+		if p.Lobby || p.Ping <= h.Max || h.Exempt.Exempt(p) {
+			continue
+		}
+		k := key(p)
+		counts[k] = h.counts[k] + 1
+		if counts[k] >= h.Polls {
+			violations = append(violations, Violation{Player: p, Reason: h.Message})
+			delete(counts, k)
+		}
+	}
+	h.counts = counts
+	return violations
+}
+
+// Unverified kicks players whose guid is still not verified After being seen first
+type Unverified struct {
+	After   time.Duration
+	Message string
+	Exempt  Exemptions
+
+	m    sync.Mutex
+	seen map[string]time.Time
+}
+
+// NewUnverified policy kicking players still unverified after
+func NewUnverified(after time.Duration) *Unverified {
+	return &Unverified{
+		After:   after,
+		Message: "GUID not verified",
+		seen:    make(map[string]time.Time),
+	}
+}
+
+// Name of the policy
+func (u *Unverified) Name() string { return "unverified-guid" }
+
+// Evaluate players and return the ones being unverified for too long
+func (u *Unverified) Evaluate(now time.Time, players []battleye.Player) []Violation {
+	u.m.Lock()
+	defer u.m.Unlock()
+	var violations []Violation
+	u.seen = since(u.seen, now, players, func(p battleye.Player) bool {
+		return !p.Verified && !u.Exempt.Exempt(p)
+	}, func(p battleye.Player, first time.Time) {
+		if now.Sub(first) >= u.After {
+			violations = append(violations, Violation{Player: p, Reason: u.Message})
+		}
+	})
+	return violations
+}
+
+// LobbyIdle kicks players staying in the lobby for longer than After
+type LobbyIdle struct {
+	After   time.Duration
+	Message string
+	Exempt  Exemptions
+
+	m    sync.Mutex
+	seen map[string]time.Time
+}
+
+// NewLobbyIdle policy kicking players idling in the lobby for longer than after
+func NewLobbyIdle(after time.Duration) *LobbyIdle {
+	return &LobbyIdle{
+		After:   after,
+		Message: "Idling in lobby",
+		seen:    make(map[string]time.Time),
+	}
+}
+
+// Name of the policy
+func (l *LobbyIdle) Name() string { return "lobby-idle" }
+
+// Evaluate players and return the ones idling in the lobby for too long
+func (l *LobbyIdle) Evaluate(now time.Time, players []battleye.Player) []Violation {
+	l.m.Lock()
+	defer l.m.Unlock()
+	var violations []Violation
+	l.seen = since(l.seen, now, players, func(p battleye.Player) bool {
+		return p.Lobby && !l.Exempt.Exempt(p)
+	}, func(p battleye.Player, first time.Time) {
+		if now.Sub(first) >= l.After {
+			violations = append(violations, Violation{Player: p, Reason: l.Message})
+		}
+	})
+	return violations
+}
+
+// since tracks when players matching cond were first seen in a row and calls check for each of them
+// Players not matching cond anymore or having left are forgotten. The updated tracking map is returned
+func since(seen map[string]time.Time, now time.Time, players []battleye.Player, cond func(battleye.Player) bool, check func(battleye.Player, time.Time)) map[string]time.Time {
+	next := make(map[string]time.Time)
+	for _, p := range players {
+		if !cond(p) {
+			continue
+		}
+		k := key(p)
+		first, ok := seen[k]
+		if !ok {
+			first = now
+		}
+		next[k] = first
+		check(p, first)
+	}
+	return next
+}
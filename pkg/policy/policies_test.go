@@ -0,0 +1,126 @@
+package policy_test
+
+import (
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/policy"
+	"github.com/playnet-public/gorcon/pkg/rcon/battleye"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Policies", func() {
+	var now time.Time
+
+	BeforeEach(func() {
+		now = time.Now()
+	})
+
+	Describe("HighPing", func() {
+		var (
+			h    *policy.HighPing
+			high battleye.Player
+		)
+
+		BeforeEach(func() {
+			h = policy.NewHighPing(200, 3)
+			high = battleye.Player{ID: 1, Name: "High", Ping: 300, GUID: guid}
+		})
+
+		It("does not kick before enough polls", func() {
+			Expect(h.Evaluate(now, []battleye.Player{high})).To(BeEmpty())
+			Expect(h.Evaluate(now, []battleye.Player{high})).To(BeEmpty())
+		})
+		It("does kick after consecutive polls", func() {
+			h.Evaluate(now, []battleye.Player{high})
+			h.Evaluate(now, []battleye.Player{high})
+			v := h.Evaluate(now, []battleye.Player{high})
+			Expect(v).To(HaveLen(1))
+			Expect(v[0].Player.ID).To(BeEquivalentTo(1))
+		})
+		It("does reset on a poll below threshold", func() {
+			low := high
+			low.Ping = 50
+			h.Evaluate(now, []battleye.Player{high})
+			h.Evaluate(now, []battleye.Player{high})
+			h.Evaluate(now, []battleye.Player{low})
+			Expect(h.Evaluate(now, []battleye.Player{high})).To(BeEmpty())
+		})
+		It("does not kick exempted players", func() {
+			h.Polls = 1
+			h.Exempt = policy.Exemptions{guid}
+			Expect(h.Evaluate(now, []battleye.Player{high})).To(BeEmpty())
+		})
+		It("does ignore players in lobby", func() {
+			h.Polls = 1
+			high.Lobby = true
+			Expect(h.Evaluate(now, []battleye.Player{high})).To(BeEmpty())
+		})
+	})
+
+	Describe("Unverified", func() {
+		var (
+			u *policy.Unverified
+			p battleye.Player
+		)
+
+		BeforeEach(func() {
+			u = policy.NewUnverified(time.Minute)
+			p = battleye.Player{ID: 1, Name: "Test"}
+		})
+
+		It("does not kick before timeout", func() {
+			Expect(u.Evaluate(now, []battleye.Player{p})).To(BeEmpty())
+			Expect(u.Evaluate(now.Add(30*time.Second), []battleye.Player{p})).To(BeEmpty())
+		})
+		It("does kick players unverified after timeout", func() {
+			u.Evaluate(now, []battleye.Player{p})
+			Expect(u.Evaluate(now.Add(time.Minute), []battleye.Player{p})).To(HaveLen(1))
+		})
+		It("does not kick verified players", func() {
+			u.Evaluate(now, []battleye.Player{p})
+			p.Verified = true
+			Expect(u.Evaluate(now.Add(time.Minute), []battleye.Player{p})).To(BeEmpty())
+		})
+		It("does forget players who left", func() {
+			u.Evaluate(now, []battleye.Player{p})
+			u.Evaluate(now.Add(30*time.Second), nil)
+			Expect(u.Evaluate(now.Add(time.Minute), []battleye.Player{p})).To(BeEmpty())
+		})
+		It("does not kick exempted players", func() {
+			u.Exempt = policy.Exemptions{"Test"}
+			u.Evaluate(now, []battleye.Player{p})
+			Expect(u.Evaluate(now.Add(time.Minute), []battleye.Player{p})).To(BeEmpty())
+		})
+	})
+
+	Describe("LobbyIdle", func() {
+		var (
+			l *policy.LobbyIdle
+			p battleye.Player
+		)
+
+		BeforeEach(func() {
+			l = policy.NewLobbyIdle(5 * time.Minute)
+			p = battleye.Player{ID: 1, Name: "Test", Lobby: true, Verified: true}
+		})
+
+		It("does not kick before timeout", func() {
+			Expect(l.Evaluate(now, []battleye.Player{p})).To(BeEmpty())
+		})
+		It("does kick players idling in lobby", func() {
+			l.Evaluate(now, []battleye.Player{p})
+			v := l.Evaluate(now.Add(5*time.Minute), []battleye.Player{p})
+			Expect(v).To(HaveLen(1))
+			Expect(v[0].Reason).To(BeEquivalentTo(l.Message))
+		})
+		It("does reset once players leave the lobby", func() {
+			l.Evaluate(now, []battleye.Player{p})
+			p.Lobby = false
+			l.Evaluate(now.Add(time.Minute), []battleye.Player{p})
+			p.Lobby = true
+			Expect(l.Evaluate(now.Add(5*time.Minute), []battleye.Player{p})).To(BeEmpty())
+		})
+	})
+})
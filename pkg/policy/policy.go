@@ -0,0 +1,139 @@
+// Package policy kicks players violating server policies like high ping, unverified guids or idling in the lobby
+// Policies are evaluated against the player list polled periodically from the server
+package policy
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/rcon"
+	"github.com/playnet-public/gorcon/pkg/rcon/battleye"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// Policy decides which players violate it based on the current player list
+//go:generate counterfeiter -o ../mocks/policy.go --fake-name Policy . Policy
+type Policy interface {
+	Name() string
+	Evaluate(time.Time, []battleye.Player) []Violation
+}
+
+// Violation of a policy by a player which leads to a kick
+type Violation struct {
+	Player battleye.Player
+	Reason string
+}
+
+// Exemptions contains guids and names of players a policy never applies to
+type Exemptions []string
+
+// Exempt returns true if p is listed by guid or name
+func (e Exemptions) Exempt(p battleye.Player) bool {
+	for _, x := range e {
+		if (p.GUID != "" && strings.EqualFold(x, p.GUID)) || x == p.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// Enforcer polls the player list and kicks players violating any of its policies
+type Enforcer struct {
+	Rcon     rcon.Writer
+	Policies []Policy
+
+	// Interval between two polls of the player list
+	Interval time.Duration
+	// Timeout waiting for the player list response
+	Timeout time.Duration
+	// DryRun only logs kicks without sending them to the server
+	DryRun bool
+
+	now func() time.Time
+}
+
+// NewEnforcer polling r every 30 seconds and enforcing policies
+func NewEnforcer(r rcon.Writer, policies ...Policy) *Enforcer {
+	return &Enforcer{
+		Rcon:     r,
+		Policies: policies,
+		Interval: 30 * time.Second,
+		Timeout:  5 * time.Second,
+		now:      time.Now,
+	}
+}
+
+// Run polls the player list every Interval until ctx is closed
+func (e *Enforcer) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			log.From(ctx).Info("stopping policy enforcer", zap.Error(ctx.Err()))
+			return ctx.Err()
+		case <-time.After(e.Interval):
+			if err := e.Poll(ctx); err != nil {
+				log.From(ctx).Error("polling players", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Poll the player list once and enforce all policies on it
+func (e *Enforcer) Poll(ctx context.Context) error {
+	trm, err := e.Rcon.Write(rcon.WithPriority(ctx, rcon.Moderation), battleye.Players)
+	if err != nil {
+		return errors.Wrap(err, "requesting players")
+	}
+	select {
+	case <-trm.Done():
+	case <-time.After(e.Timeout):
+		return errors.New("timeout waiting for players")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	players, err := battleye.ParsePlayers(trm.Response())
+	if err != nil {
+		return errors.Wrap(err, "parsing players")
+	}
+	return e.Enforce(ctx, players)
+}
+
+// Enforce all policies on players and kick the ones violating them
+// Every player gets kicked at most once even if violating multiple policies
+func (e *Enforcer) Enforce(ctx context.Context, players []battleye.Player) error {
+	now := e.now()
+	kicked := make(map[int]struct{})
+	var errs []string
+	for _, p := range e.Policies {
+		for _, v := range p.Evaluate(now, players) {
+			if _, ok := kicked[v.Player.ID]; ok {
+				continue
+			}
+			kicked[v.Player.ID] = struct{}{}
+
+			fields := []zap.Field{
+				zap.String("policy", p.Name()),
+				zap.Int("id", v.Player.ID),
+				zap.String("player", v.Player.Name),
+				zap.String("guid", v.Player.GUID),
+				zap.String("reason", v.Reason),
+			}
+			if e.DryRun {
+				log.From(ctx).Info("would kick player", fields...)
+				continue
+			}
+			log.From(ctx).Info("kicking player", fields...)
+			if _, err := e.Rcon.Write(rcon.WithPriority(ctx, rcon.Moderation), battleye.Kick(v.Player.ID, v.Reason)); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Errorf("kicking players: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
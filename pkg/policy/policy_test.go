@@ -0,0 +1,137 @@
+package policy_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/mocks"
+	"github.com/playnet-public/gorcon/pkg/policy"
+	"github.com/playnet-public/gorcon/pkg/rcon/battleye"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestPolicy(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Policy Suite")
+}
+
+const guid = "0123456789abcdef0123456789abcdef"
+
+var _ = Describe("Exemptions", func() {
+	It("does exempt players by guid", func() {
+		e := policy.Exemptions{"0123456789ABCDEF0123456789ABCDEF"}
+		Expect(e.Exempt(battleye.Player{GUID: guid})).To(BeTrue())
+	})
+	It("does exempt players by name", func() {
+		e := policy.Exemptions{"Admin"}
+		Expect(e.Exempt(battleye.Player{Name: "Admin"})).To(BeTrue())
+	})
+	It("does not exempt other players", func() {
+		e := policy.Exemptions{"Admin"}
+		Expect(e.Exempt(battleye.Player{Name: "Test", GUID: guid})).To(BeFalse())
+	})
+})
+
+var _ = Describe("Enforcer", func() {
+	const resp = `Players on server:
+[#] [IP Address]:[Port] [Ping] [GUID] [Name]
+--------------------------------------------------
+0   127.0.0.1:2304        31   0123456789abcdef0123456789abcdef(OK) First
+(1 players in total)`
+
+	var (
+		ctx  context.Context
+		r    *mocks.RconWriter
+		trm  *mocks.RconTransmission
+		done chan bool
+		pol  *mocks.Policy
+		e    *policy.Enforcer
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		done = make(chan bool, 1)
+		done <- true
+		trm = &mocks.RconTransmission{}
+		trm.DoneReturns(done)
+		trm.ResponseReturns(resp)
+		r = &mocks.RconWriter{}
+		r.WriteReturns(trm, nil)
+		pol = &mocks.Policy{}
+		pol.NameReturns("test")
+		e = policy.NewEnforcer(r, pol)
+	})
+
+	Describe("Poll", func() {
+		It("does request the player list", func() {
+			Expect(e.Poll(ctx)).To(BeNil())
+			_, cmd := r.WriteArgsForCall(0)
+			Expect(cmd).To(BeEquivalentTo("players"))
+		})
+		It("does evaluate policies with parsed players", func() {
+			e.Poll(ctx)
+			Expect(pol.EvaluateCallCount()).To(BeEquivalentTo(1))
+			_, players := pol.EvaluateArgsForCall(0)
+			Expect(players).To(HaveLen(1))
+			Expect(players[0].Name).To(BeEquivalentTo("First"))
+		})
+		It("does return error if request fails", func() {
+			r.WriteReturns(nil, errors.New("test"))
+			Expect(e.Poll(ctx)).NotTo(BeNil())
+		})
+		It("does return error on response timeout", func() {
+			trm.DoneReturns(make(chan bool))
+			e.Timeout = time.Millisecond
+			Expect(e.Poll(ctx)).NotTo(BeNil())
+			Expect(pol.EvaluateCallCount()).To(BeEquivalentTo(0))
+		})
+	})
+
+	Describe("Enforce", func() {
+		var p battleye.Player
+
+		BeforeEach(func() {
+			p = battleye.Player{ID: 3, Name: "Test"}
+			pol.EvaluateReturns([]policy.Violation{{Player: p, Reason: "test reason"}})
+		})
+
+		It("does kick violating players", func() {
+			Expect(e.Enforce(ctx, []battleye.Player{p})).To(BeNil())
+			_, cmd := r.WriteArgsForCall(0)
+			Expect(cmd).To(BeEquivalentTo("kick 3 test reason"))
+		})
+		It("does kick players only once", func() {
+			e.Policies = append(e.Policies, pol)
+			e.Enforce(ctx, []battleye.Player{p})
+			Expect(r.WriteCallCount()).To(BeEquivalentTo(1))
+		})
+		It("does not kick in dry run", func() {
+			e.DryRun = true
+			Expect(e.Enforce(ctx, []battleye.Player{p})).To(BeNil())
+			Expect(r.WriteCallCount()).To(BeEquivalentTo(0))
+		})
+		It("does return error if kick fails", func() {
+			r.WriteReturns(nil, errors.New("test"))
+			Expect(e.Enforce(ctx, []battleye.Player{p})).NotTo(BeNil())
+		})
+	})
+
+	Describe("Run", func() {
+		It("does exit on closed context", func() {
+			ctx, close := context.WithCancel(ctx)
+			close()
+			Expect(e.Run(ctx)).To(BeEquivalentTo(context.Canceled))
+		})
+		It("does poll every interval", func() {
+			ctx, close := context.WithCancel(ctx)
+			defer close()
+			e.Interval = time.Millisecond
+			go e.Run(ctx)
+			Eventually(r.WriteCallCount).Should(BeNumerically(">", 1))
+		})
+	})
+})
@@ -9,6 +9,7 @@ import (
 
 	"github.com/playnet-public/gorcon/pkg/event"
 	"github.com/playnet-public/gorcon/pkg/rcon"
+	"github.com/playnet-public/gorcon/pkg/trace"
 
 	"github.com/pkg/errors"
 	be_proto "github.com/playnet-public/battleye/battleye"
@@ -19,6 +20,11 @@ import (
 
 // Client is a BattlEye specific implementation of rcon.Client to create new BattlEye rcon connections
 type Client struct {
+	// Addr, Password and Dialer are passed on to all connections created by the client
+	Addr     *net.UDPAddr
+	Password string
+	Dialer   udpDialer
+
 	*event.Broker
 	events chan event.Event
 }
@@ -26,15 +32,21 @@ type Client struct {
 // New battleye client
 func New(ctx context.Context) *Client {
 	e := make(chan event.Event)
+	b := event.NewBroker(ctx, e)
+	b.Name = "battleye"
 	return &Client{
-		Broker: event.NewBroker(ctx, e),
+		Broker: b,
 		events: e,
 	}
 }
 
 // NewConnection from the current client's configuration
 func (c *Client) NewConnection(ctx context.Context) rcon.Connection {
-	return NewConnection(ctx, c.Broker, c.events)
+	con := NewConnection(ctx, c.Broker, c.events)
+	con.Addr = c.Addr
+	con.Password = c.Password
+	con.Dialer = c.Dialer
+	return con
 }
 
 // Connection is a BattlEye specific implementation of rcon.Connection offering all required rcon generics
@@ -55,6 +67,8 @@ type Connection struct {
 
 	*event.Broker
 	events chan event.Event
+	roster *Roster
+	muted  uint32
 
 	Tomb *tomb.Tomb
 }
@@ -65,6 +79,7 @@ func NewConnection(ctx context.Context, broker *event.Broker, events chan event.
 		Protocol: be_proto.New(),
 		Broker:   broker,
 		events:   events,
+		roster:   NewRoster(),
 	}
 	atomic.StoreUint32(&c.seq, 0)
 	atomic.StoreInt64(&c.keepAliveCount, 0)
@@ -100,11 +115,24 @@ func (c *Connection) Open(ctx context.Context) error {
 		return errors.Wrap(err, "dialing udp failed")
 	}
 	c.UDP = udp
+	if err := c.login(); err != nil {
+		// reset the connection, so opening it can be retried while the server is still starting
+		c.UDP.Close()
+		c.UDP = nil
+		return err
+	}
+	connectionUp.With(c.Server()).Set(1)
+	c.Hold(ctx)
+	return nil
+}
+
+// login to the server using the current udp connection
+func (c *Connection) login() error {
 	c.UDP.SetReadDeadline(time.Now().Add(time.Second * 2)) // TODO: Evaluate if this is required
 	c.UDP.SetWriteDeadline(time.Now().Add(time.Millisecond * 100))
 
 	buf := make([]byte, 9)
-	_, err = c.UDP.Write(c.Protocol.BuildLoginPacket(c.Password))
+	_, err := c.UDP.Write(c.Protocol.BuildLoginPacket(c.Password))
 	if err != nil {
 		return errors.Wrap(err, "sending login packet failed")
 	}
@@ -118,10 +146,32 @@ func (c *Connection) Open(ctx context.Context) error {
 	if err != nil {
 		return errors.Wrap(err, "login failed")
 	}
-	c.Hold(ctx)
 	return nil
 }
 
+// Server returns the address of the server the connection is targeting
+func (c *Connection) Server() string {
+	if c.Addr == nil {
+		return ""
+	}
+	return c.Addr.String()
+}
+
+// Mute drops server messages after acknowledging them instead of emitting events
+// Pools use this to receive events on a single connection only
+func (c *Connection) Mute(mute bool) {
+	var v uint32
+	if mute {
+		v = 1
+	}
+	atomic.StoreUint32(&c.muted, v)
+}
+
+// Roster of the players on the server as seen by the connection's server messages
+func (c *Connection) Roster() *Roster {
+	return c.roster
+}
+
 // Hold the connection by sending keepalive packets as required by the battleye protocol
 func (c *Connection) Hold(ctx context.Context) {
 	c.Tomb.Go(c.WriterLoop(ctx))
@@ -139,6 +189,7 @@ func (c *Connection) WriterLoop(ctx context.Context) func() error {
 				if c.UDP != nil {
 					c.UDP.Write(c.Protocol.BuildKeepAlivePacket(c.Sequence()))
 					c.AddKeepAlive()
+					keepAlivesSent.With(c.Server()).Inc()
 					continue
 				}
 				return errors.New("udp connection must not be nil")
@@ -155,19 +206,19 @@ func (c *Connection) ReaderLoop(ctx context.Context) func() error {
 			case <-c.Tomb.Dying():
 				return tomb.ErrDying
 			default:
-				if c.UDP != nil {
-					buf := make([]byte, 4096)
-					_, err := c.UDP.Read(buf)
-					if err, ok := err.(net.Error); ok && err.Timeout() {
-						log.From(ctx).Debug("timeout", zap.Error(err))
-						continue
-					}
-					if err != nil {
-						return errors.Wrap(err, "reading udp failed")
-					}
-					go c.HandlePacket(ctx, buf)
+				if c.UDP == nil {
+					return errors.New("udp connection must not be nil")
 				}
-				return errors.New("udp connection must not be nil")
+				buf := make([]byte, 4096)
+				n, err := c.UDP.Read(buf)
+				if err, ok := err.(net.Error); ok && err.Timeout() {
+					log.From(ctx).Debug("timeout", zap.Error(err))
+					continue
+				}
+				if err != nil {
+					return errors.Wrap(err, "reading udp failed")
+				}
+				go c.HandlePacket(ctx, buf[:n])
 			}
 		}
 	}
@@ -177,6 +228,7 @@ func (c *Connection) ReaderLoop(ctx context.Context) func() error {
 func (c *Connection) Close(ctx context.Context) error {
 	c.Tomb.Kill(errors.New("SIGCLOSE"))
 	c.Tomb.Wait()
+	connectionUp.With(c.Server()).Set(0)
 	if c.UDP == nil {
 		return errors.New("connection must not be nil")
 	}
@@ -194,12 +246,20 @@ func (c *Connection) Write(ctx context.Context, cmd string) (rcon.Transmission,
 		return nil, errors.New("udp connection must not be nil")
 	}
 	seq := c.AddSequence()
+	_, span := trace.Start(ctx, "battleye.Write")
+	defer span.End()
+	span.SetAttribute("rcon.server", c.Server())
+	span.SetAttribute("rcon.sequence", int(seq))
 	trm := NewTransmission(cmd)
+	trm.seq = uint32(seq)
+	trm.span = span.Context()
 	c.AddTransmission(seq, trm)
 	_, err := c.UDP.Write(c.Protocol.BuildCmdPacket([]byte(trm.Request()), seq))
 	if err != nil {
 		c.DeleteTransmission(seq)
+		span.RecordError(err)
 		return nil, errors.Wrap(err, "writing udp failed")
 	}
+	pendingTransmissions.With(c.Server()).Inc()
 	return trm, nil
 }
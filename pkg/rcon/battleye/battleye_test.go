@@ -38,6 +38,15 @@ var _ = Describe("Client", func() {
 		It("does not return nil", func() {
 			Expect(c.NewConnection(ctx)).NotTo(BeNil())
 		})
+		It("does pass on the client configuration", func() {
+			c.Addr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 2302}
+			c.Password = "secret"
+			c.Dialer = &mocks.UDPDialer{}
+			con := c.NewConnection(ctx).(*be.Connection)
+			Expect(con.Addr).To(Equal(c.Addr))
+			Expect(con.Password).To(Equal("secret"))
+			Expect(con.Dialer).To(Equal(c.Dialer))
+		})
 	})
 })
 
@@ -54,6 +63,14 @@ var _ = Describe("Connection", func() {
 		con.Protocol = proto
 
 		udp = &mocks.UDPConnection{}
+		udp.ReadStub = func([]byte) (int, error) {
+			// the login reads the first packet, the reader loop waits for further packets
+			if udp.ReadCallCount() > 1 {
+				time.Sleep(10 * time.Millisecond)
+				return 0, &timeoutError{}
+			}
+			return 0, nil
+		}
 		dial.DialUDPReturns(udp, nil)
 		con.UDP = udp
 
@@ -156,6 +173,15 @@ var _ = Describe("Connection", func() {
 			proto.VerifyLoginReturns(errors.New("test"))
 			Expect(con.Open(ctx)).NotTo(BeNil())
 		})
+		It("does close the udp connection if login fails", func() {
+			ctx, _, con, dial, proto, udp := setup()
+			con.UDP = nil
+			con.Dialer = dial
+			proto.VerifyLoginReturns(errors.New("test"))
+			Expect(con.Open(ctx)).NotTo(BeNil())
+			Expect(udp.CloseCallCount()).To(Equal(1))
+			Expect(con.UDP).To(BeNil())
+		})
 	})
 
 	Describe("WriterLoop", func() {
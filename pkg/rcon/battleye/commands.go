@@ -0,0 +1,41 @@
+package battleye
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Everyone is the player id addressing all players on the server (e.g. for global messages)
+const Everyone = -1
+
+// Commands without arguments
+const (
+	// Players is the command listing all players currently on the server
+	Players = "players"
+	// Lock is the command preventing players from joining the server
+	Lock = "#lock"
+	// Unlock is the command allowing players to join the server again
+	Unlock = "#unlock"
+	// Shutdown is the command making the server exit cleanly
+	Shutdown = "#shutdown"
+)
+
+// Kick builds the command for kicking player id with reason
+func Kick(id int, reason string) string {
+	return strings.TrimSpace(fmt.Sprintf("kick %d %s", id, reason))
+}
+
+// Say builds the command for sending msg to player id or Everyone
+func Say(id int, msg string) string {
+	return fmt.Sprintf("say %d %s", id, msg)
+}
+
+// Ban builds the command for banning player id for minutes with reason. Zero minutes ban permanently
+func Ban(id, minutes int, reason string) string {
+	return strings.TrimSpace(fmt.Sprintf("ban %d %d %s", id, minutes, reason))
+}
+
+// Monitor builds the command making the server report its load every interval seconds. Zero disables it
+func Monitor(interval int) string {
+	return fmt.Sprintf("#monitor %d", interval)
+}
@@ -0,0 +1,40 @@
+package battleye_test
+
+import (
+	be "github.com/playnet-public/gorcon/pkg/rcon/battleye"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Commands", func() {
+	Describe("Kick", func() {
+		It("does build kick command", func() {
+			Expect(be.Kick(1, "test reason")).To(BeEquivalentTo("kick 1 test reason"))
+		})
+		It("does omit empty reason", func() {
+			Expect(be.Kick(1, "")).To(BeEquivalentTo("kick 1"))
+		})
+	})
+
+	Describe("Say", func() {
+		It("does build say command", func() {
+			Expect(be.Say(2, "hello")).To(BeEquivalentTo("say 2 hello"))
+		})
+		It("does address everyone", func() {
+			Expect(be.Say(be.Everyone, "hello")).To(BeEquivalentTo("say -1 hello"))
+		})
+	})
+
+	Describe("Ban", func() {
+		It("does build ban command", func() {
+			Expect(be.Ban(3, 60, "test")).To(BeEquivalentTo("ban 3 60 test"))
+		})
+	})
+
+	Describe("Monitor", func() {
+		It("does build monitor command", func() {
+			Expect(be.Monitor(5)).To(BeEquivalentTo("#monitor 5"))
+		})
+	})
+})
@@ -0,0 +1,172 @@
+package battleye
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PlayerEventKind identifies the different player related server messages sent by BattlEye
+type PlayerEventKind int
+
+const (
+	// PlayerConnected is sent once a player connects to the server
+	PlayerConnected PlayerEventKind = iota + 1
+	// PlayerGUID is sent once the unverified guid of a player is known
+	PlayerGUID
+	// PlayerGUIDVerified is sent once the guid of a player got verified by the master server
+	PlayerGUIDVerified
+	// PlayerDisconnected is sent once a player leaves the server
+	PlayerDisconnected
+	// PlayerKicked is sent when a player gets kicked by BattlEye or an admin
+	PlayerKicked
+)
+
+// String returns the name of the kind
+func (k PlayerEventKind) String() string {
+	switch k {
+	case PlayerConnected:
+		return "connected"
+	case PlayerGUID:
+		return "guid"
+	case PlayerGUIDVerified:
+		return "guid_verified"
+	case PlayerDisconnected:
+		return "disconnected"
+	case PlayerKicked:
+		return "kicked"
+	}
+	return "unknown"
+}
+
+// ErrNoPlayerEvent is returned when parsing a server message not describing a player event
+var ErrNoPlayerEvent = errors.New("no player event")
+
+// ErrNoChatMessage is returned when parsing a server message not being a chat message
+var ErrNoChatMessage = errors.New("no chat message")
+
+// Channels contains all chat channels known to BattlEye
+var Channels = []string{
+	"Global",
+	"Side",
+	"Command",
+	"Group",
+	"Vehicle",
+	"Direct",
+	"Unknown",
+}
+
+// ChatMessage contains the information parsed from chat server messages
+type ChatMessage struct {
+	Channel string
+	Name    string
+	Text    string
+}
+
+var chatMessagePattern = regexp.MustCompile(`\((` + strings.Join(Channels, "|") + `)\) (.+?): (.*)$`)
+
+// PlayerEvent contains the information parsed from player related server messages
+type PlayerEvent struct {
+	Kind   PlayerEventKind
+	ID     int
+	Name   string
+	Addr   string
+	GUID   string
+	Reason string
+}
+
+var playerEventPatterns = []struct {
+	kind PlayerEventKind
+	re   *regexp.Regexp
+}{
+	{PlayerKicked, regexp.MustCompile(`Player #(?P<id>\d+) (?P<name>.+?) \((?P<guid>[0-9a-fA-F]{32}|-)\) has been kicked by BattlEye: (?P<reason>.*)$`)},
+	{PlayerGUIDVerified, regexp.MustCompile(`Verified GUID \((?P<guid>[0-9a-fA-F]{32})\) of player #(?P<id>\d+) (?P<name>.+)$`)},
+	{PlayerGUID, regexp.MustCompile(`Player #(?P<id>\d+) (?P<name>.+) - (?:BE )?GUID: (?P<guid>[0-9a-fA-F]{32})`)},
+	{PlayerConnected, regexp.MustCompile(`Player #(?P<id>\d+) (?P<name>.+) \((?P<addr>[0-9a-fA-F.:\[\]]+:\d+)\) connected$`)},
+	{PlayerDisconnected, regexp.MustCompile(`Player #(?P<id>\d+) (?P<name>.+) disconnected$`)},
+}
+
+// ParsePlayerEvent from a BattlEye server message. ErrNoPlayerEvent is returned if msg is not player related
+func ParsePlayerEvent(msg string) (*PlayerEvent, error) {
+	for _, p := range playerEventPatterns {
+		match := p.re.FindStringSubmatch(msg)
+		if match == nil {
+			continue
+		}
+		e := &PlayerEvent{Kind: p.kind}
+		for i, name := range p.re.SubexpNames() {
+			switch name {
+			case "id":
+				id, err := strconv.Atoi(match[i])
+				if err != nil {
+					return nil, errors.Wrap(err, "parsing player id")
+				}
+				e.ID = id
+			case "name":
+				e.Name = match[i]
+			case "addr":
+				e.Addr = match[i]
+			case "guid":
+				e.GUID = match[i]
+			case "reason":
+				e.Reason = match[i]
+			}
+		}
+		return e, nil
+	}
+	return nil, ErrNoPlayerEvent
+}
+
+// ParseChatMessage from a BattlEye server message. ErrNoChatMessage is returned if msg is no chat message
+func ParseChatMessage(msg string) (*ChatMessage, error) {
+	match := chatMessagePattern.FindStringSubmatch(msg)
+	if match == nil {
+		return nil, ErrNoChatMessage
+	}
+	return &ChatMessage{
+		Channel: match[1],
+		Name:    match[2],
+		Text:    match[3],
+	}, nil
+}
+
+// ErrNoServerLoad is returned when parsing a server message not being #monitor output
+var ErrNoServerLoad = errors.New("no server load")
+
+// ServerLoad reported by the server every interval once enabled using Monitor
+type ServerLoad struct {
+	FPS float64
+	// Memory used in MB
+	Memory int
+	// Out and In are the network traffic in Kbps
+	Out, In int
+	// Players is -1 if not reported
+	Players int
+}
+
+var (
+	serverLoadPattern = regexp.MustCompile(`Server load: FPS (\d+(?:\.\d+)?), memory used: (\d+) MB, out: (\d+) Kbps, in: (\d+) Kbps`)
+	serverLoadPlayers = regexp.MustCompile(`Players: (\d+)`)
+)
+
+// ParseServerLoad from #monitor output. ErrNoServerLoad is returned for other messages
+func ParseServerLoad(msg string) (*ServerLoad, error) {
+	match := serverLoadPattern.FindStringSubmatch(msg)
+	if match == nil {
+		return nil, ErrNoServerLoad
+	}
+	fps, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing fps")
+	}
+	l := &ServerLoad{FPS: fps, Players: -1}
+	l.Memory, _ = strconv.Atoi(match[2])
+	l.Out, _ = strconv.Atoi(match[3])
+	l.In, _ = strconv.Atoi(match[4])
+	if p := serverLoadPlayers.FindStringSubmatch(msg); p != nil {
+		l.Players, _ = strconv.Atoi(p[1])
+	}
+	return l, nil
+}
@@ -0,0 +1,94 @@
+package battleye_test
+
+import (
+	be "github.com/playnet-public/gorcon/pkg/rcon/battleye"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Messages", func() {
+	const guid = "0123456789abcdef0123456789abcdef"
+
+	Describe("ParsePlayerEvent", func() {
+		It("does return error on non player messages", func() {
+			_, err := be.ParsePlayerEvent("(Global) Test: hello")
+			Expect(err).To(BeEquivalentTo(be.ErrNoPlayerEvent))
+		})
+		It("does parse connects", func() {
+			e, err := be.ParsePlayerEvent("Player #3 Some Name (127.0.0.1:2304) connected")
+			Expect(err).To(BeNil())
+			Expect(e.Kind).To(BeEquivalentTo(be.PlayerConnected))
+			Expect(e.ID).To(BeEquivalentTo(3))
+			Expect(e.Name).To(BeEquivalentTo("Some Name"))
+			Expect(e.Addr).To(BeEquivalentTo("127.0.0.1:2304"))
+		})
+		It("does parse unverified guids", func() {
+			e, err := be.ParsePlayerEvent("Player #3 Test - BE GUID: " + guid)
+			Expect(err).To(BeNil())
+			Expect(e.Kind).To(BeEquivalentTo(be.PlayerGUID))
+			Expect(e.GUID).To(BeEquivalentTo(guid))
+		})
+		It("does parse verified guids", func() {
+			e, err := be.ParsePlayerEvent("Verified GUID (" + guid + ") of player #3 Test")
+			Expect(err).To(BeNil())
+			Expect(e.Kind).To(BeEquivalentTo(be.PlayerGUIDVerified))
+			Expect(e.ID).To(BeEquivalentTo(3))
+			Expect(e.Name).To(BeEquivalentTo("Test"))
+			Expect(e.GUID).To(BeEquivalentTo(guid))
+		})
+		It("does parse disconnects", func() {
+			e, err := be.ParsePlayerEvent("Player #3 Test disconnected")
+			Expect(err).To(BeNil())
+			Expect(e.Kind).To(BeEquivalentTo(be.PlayerDisconnected))
+			Expect(e.ID).To(BeEquivalentTo(3))
+		})
+		It("does parse kicks", func() {
+			e, err := be.ParsePlayerEvent("Player #3 Test (" + guid + ") has been kicked by BattlEye: Script Restriction #12")
+			Expect(err).To(BeNil())
+			Expect(e.Kind).To(BeEquivalentTo(be.PlayerKicked))
+			Expect(e.GUID).To(BeEquivalentTo(guid))
+			Expect(e.Reason).To(BeEquivalentTo("Script Restriction #12"))
+		})
+	})
+})
+
+var _ = Describe("Chat", func() {
+	Describe("ParseChatMessage", func() {
+		It("does return error on non chat messages", func() {
+			_, err := be.ParseChatMessage("Player #1 Test disconnected")
+			Expect(err).To(BeEquivalentTo(be.ErrNoChatMessage))
+		})
+		It("does parse channel, name and text", func() {
+			m, err := be.ParseChatMessage("(Side) Some Name: hello: world")
+			Expect(err).To(BeNil())
+			Expect(m.Channel).To(BeEquivalentTo("Side"))
+			Expect(m.Name).To(BeEquivalentTo("Some Name"))
+			Expect(m.Text).To(BeEquivalentTo("hello: world"))
+		})
+		It("does not parse unknown channels", func() {
+			_, err := be.ParseChatMessage("(Foo) Test: hello")
+			Expect(err).NotTo(BeNil())
+		})
+	})
+})
+
+var _ = Describe("ServerLoad", func() {
+	Describe("ParseServerLoad", func() {
+		It("does return error on other messages", func() {
+			_, err := be.ParseServerLoad("Player #1 Test disconnected")
+			Expect(err).To(BeEquivalentTo(be.ErrNoServerLoad))
+		})
+		It("does parse fps, memory, traffic and players", func() {
+			l, err := be.ParseServerLoad("Server load: FPS 47, memory used: 1021 MB, out: 1055 Kbps, in: 283 Kbps, NG:0, G:1047, BE-NG:0, BE-G:0, Players: 30 (L:0, R:0, B:0, G:30, D:0)")
+			Expect(err).To(BeNil())
+			Expect(*l).To(Equal(be.ServerLoad{FPS: 47, Memory: 1021, Out: 1055, In: 283, Players: 30}))
+		})
+		It("does mark missing player counts", func() {
+			l, err := be.ParseServerLoad("Server load: FPS 12.5, memory used: 800 MB, out: 0 Kbps, in: 0 Kbps, NG:0, G:0, BE-NG:0, BE-G:0")
+			Expect(err).To(BeNil())
+			Expect(l.FPS).To(Equal(12.5))
+			Expect(l.Players).To(Equal(-1))
+		})
+	})
+})
@@ -0,0 +1,15 @@
+package battleye
+
+import (
+	"github.com/playnet-public/gorcon/pkg/metrics"
+)
+
+var (
+	connectionUp         = metrics.NewGaugeVec("gorcon_rcon_connection_up", "Whether the rcon connection to the server is open.", "server")
+	keepAlivesSent       = metrics.NewCounterVec("gorcon_rcon_keepalives_total", "Keepalive packets sent to the server.", "server")
+	pingbacksReceived    = metrics.NewCounterVec("gorcon_rcon_pingbacks_total", "Keepalive pingbacks received from the server.", "server")
+	commandDuration      = metrics.NewHistogramVec("gorcon_rcon_command_duration_seconds", "Time between sending a command and receiving its complete response.", nil, "server")
+	pendingTransmissions = metrics.NewGaugeVec("gorcon_rcon_pending_transmissions", "Commands sent to the server still waiting for their response.", "server")
+	serverMessages       = metrics.NewCounterVec("gorcon_rcon_events_total", "Server messages received by kind.", "server", "kind")
+	playersOnline        = metrics.NewGaugeVec("gorcon_rcon_players", "Players on the server as seen by the connection.", "server")
+)
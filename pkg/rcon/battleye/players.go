@@ -0,0 +1,46 @@
+package battleye
+
+import (
+	"bufio"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var playerLinePattern = regexp.MustCompile(`^(\d+)\s+(\S+:\d+)\s+(-?\d+)\s+(?:([0-9a-fA-F]{32})\((OK|\?)\)|-)\s+(.*?)( \(Lobby\))?$`)
+
+// ParsePlayers from the response of the players command
+// Lines not describing a player (like headers or the total count) are skipped
+func ParsePlayers(resp string) ([]Player, error) {
+	var players []Player
+	scn := bufio.NewScanner(strings.NewReader(resp))
+	for scn.Scan() {
+		match := playerLinePattern.FindStringSubmatch(strings.TrimSpace(scn.Text()))
+		if match == nil {
+			continue
+		}
+		id, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing player id")
+		}
+		ping, err := strconv.Atoi(match[3])
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing player ping")
+		}
+		players = append(players, Player{
+			ID:       id,
+			Addr:     match[2],
+			Ping:     ping,
+			GUID:     match[4],
+			Verified: match[5] == "OK",
+			Name:     match[6],
+			Lobby:    match[7] != "",
+		})
+	}
+	if err := scn.Err(); err != nil {
+		return nil, errors.Wrap(err, "reading players")
+	}
+	return players, nil
+}
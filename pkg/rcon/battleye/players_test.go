@@ -0,0 +1,55 @@
+package battleye_test
+
+import (
+	be "github.com/playnet-public/gorcon/pkg/rcon/battleye"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Players", func() {
+	const resp = `Players on server:
+[#] [IP Address]:[Port] [Ping] [GUID] [Name]
+--------------------------------------------------
+0   127.0.0.1:2304        31   0123456789abcdef0123456789abcdef(OK) First Player
+1   127.0.0.2:2304        120  fedcba9876543210fedcba9876543210(?) Second
+2   127.0.0.3:2304        -1   -  Third (Lobby)
+(3 players in total)`
+
+	Describe("ParsePlayers", func() {
+		It("does skip non player lines", func() {
+			p, err := be.ParsePlayers(resp)
+			Expect(err).To(BeNil())
+			Expect(p).To(HaveLen(3))
+		})
+		It("does parse verified players", func() {
+			p, _ := be.ParsePlayers(resp)
+			Expect(p[0]).To(BeEquivalentTo(be.Player{
+				ID:       0,
+				Addr:     "127.0.0.1:2304",
+				Ping:     31,
+				GUID:     "0123456789abcdef0123456789abcdef",
+				Verified: true,
+				Name:     "First Player",
+			}))
+		})
+		It("does parse unverified players", func() {
+			p, _ := be.ParsePlayers(resp)
+			Expect(p[1].Verified).To(BeFalse())
+			Expect(p[1].GUID).To(BeEquivalentTo("fedcba9876543210fedcba9876543210"))
+			Expect(p[1].Ping).To(BeEquivalentTo(120))
+		})
+		It("does parse players in lobby", func() {
+			p, _ := be.ParsePlayers(resp)
+			Expect(p[2].Lobby).To(BeTrue())
+			Expect(p[2].Name).To(BeEquivalentTo("Third"))
+			Expect(p[2].GUID).To(BeEmpty())
+			Expect(p[2].Ping).To(BeEquivalentTo(-1))
+		})
+		It("does return empty list for empty server", func() {
+			p, err := be.ParsePlayers("Players on server:\n(0 players in total)")
+			Expect(err).To(BeNil())
+			Expect(p).To(BeEmpty())
+		})
+	})
+})
@@ -2,13 +2,16 @@ package battleye
 
 import (
 	"context"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
 
 	be_proto "github.com/playnet-public/battleye/battleye"
 	"github.com/playnet-public/gorcon/pkg/rcon"
+	"github.com/playnet-public/gorcon/pkg/trace"
 
 	"github.com/pkg/errors"
 	"github.com/seibert-media/golibs/log"
@@ -33,6 +36,7 @@ func (c *Connection) HandlePacket(ctx context.Context, p be_proto.Packet) (err e
 	// Handle KeepAlive Pingback
 	if len(data) < 1 {
 		c.AddPingback()
+		pingbacksReceived.With(c.Server()).Inc()
 		log.From(ctx).Debug("pingback", zap.Int64("count", c.Pingback()))
 		return nil
 	}
@@ -66,6 +70,11 @@ func (c *Connection) HandleResponse(ctx context.Context, p be_proto.Packet) erro
 		return errors.New("no transmission for response")
 	}
 
+	// continue the trace of the command this response belongs to
+	ctx, span := trace.Start(trace.WithSpanContext(ctx, trm.span), "battleye.HandleResponse")
+	defer span.End()
+	span.SetAttribute("rcon.sequence", int(s))
+
 	t, err := c.Protocol.Type(p)
 	if err != nil {
 		return errors.Wrap(err, "handling response")
@@ -88,6 +97,10 @@ func (c *Connection) HandleResponse(ctx context.Context, p be_proto.Packet) erro
 	}
 
 	if last {
+		if trm.completed() {
+			commandDuration.With(c.Server()).Observe(time.Since(trm.created).Seconds())
+			pendingTransmissions.With(c.Server()).Dec()
+		}
 		select {
 		case trm.done <- true:
 			return nil
@@ -106,27 +119,71 @@ func (c *Connection) HandleServerMessage(ctx context.Context, p be_proto.Packet)
 	if err != nil {
 		return errors.Wrap(err, "handling server message")
 	}
+	_, span := trace.Start(ctx, "battleye.HandleServerMessage")
+	defer span.End()
+	span.SetAttribute("rcon.sequence", int(s))
 
-	var channels = []string{
-		"(Group)",
-		"(Vehicle)",
-		"(Unknown)",
+	data, err := c.Protocol.Data(p)
+	if err != nil {
+		return errors.Wrap(err, "handling server message")
 	}
+	// the data starts with the 0xFF marker, the packet type and the sequence in front of the message
+	if len(data) < 3 {
+		return errors.New("handling server message: missing message")
+	}
+	msg := string(data[3:])
+
 	var t = rcon.TypeEvent
-	for _, c := range channels {
-		if strings.HasPrefix(string(p), c) {
+	pe, err := ParsePlayerEvent(msg)
+	if err == nil {
+		t = rcon.TypePlayer
+		if c.roster != nil {
+			c.roster.Update(pe)
+			playersOnline.With(c.Server()).Set(float64(c.roster.Len()))
+		}
+	}
+	for _, c := range Channels {
+		if strings.HasPrefix(msg, "("+c+")") {
 			t = rcon.TypeChat
 		}
 	}
 
-	event := rcon.NewEvent(t, string(p))
+	event := rcon.NewServerEvent(c.Server(), t, msg)
+	switch t {
+	case rcon.TypePlayer:
+		setPlayerAttributes(event, pe)
+	case rcon.TypeChat:
+		if m, err := ParseChatMessage(msg); err == nil {
+			event.Set("chat.channel", m.Channel).Set("chat.name", m.Name).Set("chat.text", m.Text)
+		}
+	}
+	span.SetAttribute("rcon.kind", rcon.KindName(event.Kind()))
 
 	_, err = c.UDP.Write(c.Protocol.BuildMsgAckPacket(s))
 	if err != nil {
+		span.RecordError(err)
 		return errors.Wrap(err, "handling server message")
 	}
 
+	if atomic.LoadUint32(&c.muted) == 1 {
+		return nil
+	}
+	serverMessages.With(c.Server(), rcon.KindName(event.Kind())).Inc()
 	go func(e *rcon.Event) { c.events <- e }(event)
 
 	return nil
 }
+
+// setPlayerAttributes on e from the non empty fields of pe
+func setPlayerAttributes(e *rcon.Event, pe *PlayerEvent) {
+	e.Set("player.event", pe.Kind.String()).Set("player.id", strconv.Itoa(pe.ID)).Set("player.name", pe.Name)
+	if pe.Addr != "" {
+		e.Set("player.addr", pe.Addr)
+	}
+	if pe.GUID != "" {
+		e.Set("player.guid", pe.GUID)
+	}
+	if pe.Reason != "" {
+		e.Set("player.reason", pe.Reason)
+	}
+}
@@ -2,6 +2,9 @@ package battleye_test
 
 import (
 	"context"
+	"net"
+	"sync"
+	"time"
 
 	be_proto "github.com/playnet-public/battleye/battleye"
 	be_mocks "github.com/playnet-public/battleye/mocks"
@@ -9,12 +12,25 @@ import (
 	"github.com/playnet-public/gorcon/pkg/mocks"
 	"github.com/playnet-public/gorcon/pkg/rcon"
 	be "github.com/playnet-public/gorcon/pkg/rcon/battleye"
+	"github.com/playnet-public/gorcon/pkg/trace"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/pkg/errors"
 )
 
+type spanRecorder struct {
+	m     sync.Mutex
+	spans []trace.SpanData
+}
+
+func (r *spanRecorder) Export(ctx context.Context, spans []trace.SpanData) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
 var _ = Describe("Reader", func() {
 	var (
 		ctx context.Context
@@ -178,6 +194,21 @@ var _ = Describe("Reader", func() {
 			}()
 			Expect(<-trm.Done()).To(BeTrue())
 		})
+		It("does continue the trace of the command", func() {
+			rec := &spanRecorder{}
+			trace.Default.SetExporter(rec)
+			defer trace.Default.SetExporter(nil)
+			trm, err := con.Write(ctx, "test")
+			Expect(err).To(BeNil())
+			go func() { <-trm.Done() }()
+			pr.SequenceReturns(be_proto.Sequence(trm.Key()), nil)
+			Expect(con.HandleResponse(ctx, nil)).To(BeNil())
+			Expect(rec.spans).To(HaveLen(2))
+			Expect(rec.spans[0].Name).To(Equal("battleye.Write"))
+			Expect(rec.spans[1].Name).To(Equal("battleye.HandleResponse"))
+			Expect(rec.spans[1].TraceID).To(Equal(rec.spans[0].TraceID))
+			Expect(rec.spans[1].Parent).To(Equal(rec.spans[0].SpanID))
+		})
 		It("does timeout on blocking done channel", func() {
 			trm := be.NewTransmission("test")
 			con.AddTransmission(0, trm)
@@ -186,17 +217,21 @@ var _ = Describe("Reader", func() {
 	})
 
 	Describe("HandleServerMessage", func() {
+		message := func(msg string) be_proto.Packet {
+			return be_proto.New().BuildPacket(append([]byte{0}, msg...), be_proto.ServerMessage)
+		}
 		BeforeEach(func() {
 			pr.SequenceReturns(0, nil)
+			pr.DataStub = be_proto.New().Data
 			udp.WriteReturns(0, nil)
 		})
 		It("does return nil", func() {
-			Expect(con.HandleServerMessage(ctx, be_proto.Packet("test"))).To(BeNil())
+			Expect(con.HandleServerMessage(ctx, message("test"))).To(BeNil())
 		})
 		It("does call Sequence with packet", func() {
-			con.HandleServerMessage(ctx, []byte("test"))
+			con.HandleServerMessage(ctx, message("test"))
 			Expect(pr.SequenceCallCount()).To(BeEquivalentTo(1))
-			Expect(pr.SequenceArgsForCall(0)).To(BeEquivalentTo(be_proto.Packet("test")))
+			Expect(pr.SequenceArgsForCall(0)).To(BeEquivalentTo(message("test")))
 		})
 		It("does return error if Sequence returns error", func() {
 			pr.SequenceReturns(0, errors.New("test"))
@@ -206,21 +241,101 @@ var _ = Describe("Reader", func() {
 			c := make(chan event.Event)
 			go con.Broker.Run(ctx)
 			con.Subscribe(ctx, c)
-			con.HandleServerMessage(ctx, []byte("test"))
+			con.HandleServerMessage(ctx, message("test"))
 			event := <-c
 			Expect(event.Data()).NotTo(BeEquivalentTo(""))
 		})
+		It("does set the server of events", func() {
+			c := make(chan event.Event)
+			con.Addr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 2302}
+			go con.Broker.Run(ctx)
+			con.Subscribe(ctx, c)
+			con.HandleServerMessage(ctx, message("test"))
+			e := <-c
+			Expect(e.(event.Sourced).Server()).To(Equal("127.0.0.1:2302"))
+		})
 		It("does set correct type when handling chat event", func() {
 			c := make(chan event.Event)
 			go con.Broker.Run(ctx)
 			con.Subscribe(ctx, c)
-			con.HandleServerMessage(ctx, []byte("(Group) Test"))
+			con.HandleServerMessage(ctx, message("(Group) Test"))
+			event := <-c
+			Expect(event.Kind()).To(BeEquivalentTo(string(rcon.TypeChat)))
+		})
+		It("does set chat type for all channels", func() {
+			c := make(chan event.Event)
+			go con.Broker.Run(ctx)
+			con.Subscribe(ctx, c)
+			con.HandleServerMessage(ctx, message("(Global) Test: hello"))
 			event := <-c
 			Expect(event.Kind()).To(BeEquivalentTo(string(rcon.TypeChat)))
 		})
+		It("does set correct type when handling player event", func() {
+			c := make(chan event.Event)
+			go con.Broker.Run(ctx)
+			con.Subscribe(ctx, c)
+			con.HandleServerMessage(ctx, message("Player #1 Test disconnected"))
+			event := <-c
+			Expect(event.Kind()).To(BeEquivalentTo(string(rcon.TypePlayer)))
+		})
+		It("does not send events when muted", func() {
+			c := make(chan event.Event)
+			go con.Broker.Run(ctx)
+			con.Subscribe(ctx, c)
+			con.Mute(true)
+			Expect(con.HandleServerMessage(ctx, message("test"))).To(BeNil())
+			Expect(udp.WriteCallCount()).To(Equal(1))
+			Consistently(c, 50*time.Millisecond).ShouldNot(Receive())
+		})
+		It("does set player attributes", func() {
+			c := make(chan event.Event)
+			go con.Broker.Run(ctx)
+			con.Subscribe(ctx, c)
+			con.HandleServerMessage(ctx, message("Player #1 Test (127.0.0.1:2304) connected"))
+			r := (<-c).(*event.Record)
+			Expect(r.Attributes).To(Equal(map[string]string{
+				"player.event": "connected",
+				"player.id":    "1",
+				"player.name":  "Test",
+				"player.addr":  "127.0.0.1:2304",
+			}))
+		})
+		It("does set chat attributes", func() {
+			c := make(chan event.Event)
+			go con.Broker.Run(ctx)
+			con.Subscribe(ctx, c)
+			con.HandleServerMessage(ctx, message("(Global) Test: hello"))
+			r := (<-c).(*event.Record)
+			Expect(r.Source.Component).To(Equal(event.ComponentRcon))
+			Expect(r.Attribute("chat.channel")).To(Equal("Global"))
+			Expect(r.Attribute("chat.name")).To(Equal("Test"))
+			Expect(r.Attribute("chat.text")).To(Equal("hello"))
+		})
+		It("does track players in roster", func() {
+			con.HandleServerMessage(ctx, message("Player #1 Test (127.0.0.1:2304) connected"))
+			Expect(con.Roster().Len()).To(BeEquivalentTo(1))
+			con.HandleServerMessage(ctx, message("Player #1 Test disconnected"))
+			Expect(con.Roster().Len()).To(BeEquivalentTo(0))
+		})
+		It("does return error on packets without message", func() {
+			Expect(con.HandleServerMessage(ctx, be_proto.New().BuildPacket(nil, be_proto.ServerMessage))).NotTo(BeNil())
+		})
+		It("does parse packets read from the connection", func() {
+			con.Protocol = be_proto.New()
+			packet := message("Player #1 Test (127.0.0.1:2304) connected")
+			udp.ReadStub = func(b []byte) (int, error) {
+				if udp.ReadCallCount() > 1 {
+					return 0, errors.New("closed")
+				}
+				return copy(b, packet), nil
+			}
+			Expect(con.ReaderLoop(ctx)()).NotTo(BeNil())
+			Eventually(func() int { return con.Roster().Len() }).Should(Equal(1))
+			Expect(udp.WriteArgsForCall(0)).To(BeEquivalentTo(be_proto.New().BuildMsgAckPacket(0)))
+		})
 		It("does return error if UDP.Write fails", func() {
 			udp.WriteReturns(0, errors.New("test"))
-			Expect(con.HandleServerMessage(ctx, []byte("test"))).NotTo(BeNil())
+			Expect(con.HandleServerMessage(ctx, message("test"))).NotTo(BeNil())
 		})
 	})
 })
@@ -0,0 +1,119 @@
+package battleye
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Player describes a player currently on the server
+type Player struct {
+	ID       int
+	Name     string
+	Addr     string
+	GUID     string
+	Verified bool
+	Joined   time.Time
+
+	// Ping and Lobby are only known when parsing the players command
+	Ping  int
+	Lobby bool
+}
+
+// Roster keeps track of the players on a server by handling player events
+type Roster struct {
+	m       sync.RWMutex
+	players map[int]*Player
+}
+
+// NewRoster without any players
+func NewRoster() *Roster {
+	return &Roster{
+		players: make(map[int]*Player),
+	}
+}
+
+// Update the roster from a player event
+func (r *Roster) Update(e *PlayerEvent) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if e.Kind == PlayerDisconnected || e.Kind == PlayerKicked {
+		delete(r.players, e.ID)
+		return
+	}
+
+	p, ok := r.players[e.ID]
+	if !ok {
+		p = &Player{ID: e.ID, Joined: time.Now()}
+		r.players[e.ID] = p
+	}
+	if e.Name != "" {
+		p.Name = e.Name
+	}
+	if e.Addr != "" {
+		p.Addr = e.Addr
+	}
+	if e.GUID != "" {
+		p.GUID = e.GUID
+	}
+	if e.Kind == PlayerGUIDVerified {
+		p.Verified = true
+	}
+}
+
+// Add players not known yet, e.g. parsed from the players command when starting to follow a server
+func (r *Roster) Add(players ...Player) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	for _, p := range players {
+		if _, ok := r.players[p.ID]; ok {
+			continue
+		}
+		p := p
+		if p.Joined.IsZero() {
+			p.Joined = time.Now()
+		}
+		r.players[p.ID] = &p
+	}
+}
+
+// ByID returns the player with id
+func (r *Roster) ByID(id int) (Player, bool) {
+	r.m.RLock()
+	defer r.m.RUnlock()
+	p, ok := r.players[id]
+	if !ok {
+		return Player{}, false
+	}
+	return *p, true
+}
+
+// ByName returns the player with name. If multiple players share the name the one with the lowest id is returned
+func (r *Roster) ByName(name string) (Player, bool) {
+	for _, p := range r.List() {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Player{}, false
+}
+
+// List all players sorted by id
+func (r *Roster) List() []Player {
+	r.m.RLock()
+	defer r.m.RUnlock()
+	players := make([]Player, 0, len(r.players))
+	for _, p := range r.players {
+		players = append(players, *p)
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].ID < players[j].ID })
+	return players
+}
+
+// Len returns the number of players on the roster
+func (r *Roster) Len() int {
+	r.m.RLock()
+	defer r.m.RUnlock()
+	return len(r.players)
+}
@@ -0,0 +1,84 @@
+package battleye_test
+
+import (
+	be "github.com/playnet-public/gorcon/pkg/rcon/battleye"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Roster", func() {
+	const guid = "0123456789abcdef0123456789abcdef"
+
+	var r *be.Roster
+
+	BeforeEach(func() {
+		r = be.NewRoster()
+		r.Update(&be.PlayerEvent{Kind: be.PlayerConnected, ID: 2, Name: "Second", Addr: "127.0.0.1:2304"})
+		r.Update(&be.PlayerEvent{Kind: be.PlayerConnected, ID: 1, Name: "First", Addr: "127.0.0.1:2304"})
+	})
+
+	Describe("Update", func() {
+		It("does add connected players", func() {
+			Expect(r.Len()).To(BeEquivalentTo(2))
+		})
+		It("does remove disconnected players", func() {
+			r.Update(&be.PlayerEvent{Kind: be.PlayerDisconnected, ID: 1, Name: "First"})
+			Expect(r.Len()).To(BeEquivalentTo(1))
+		})
+		It("does remove kicked players", func() {
+			r.Update(&be.PlayerEvent{Kind: be.PlayerKicked, ID: 1, Name: "First"})
+			_, ok := r.ByID(1)
+			Expect(ok).To(BeFalse())
+		})
+		It("does keep the address when setting the guid", func() {
+			r.Update(&be.PlayerEvent{Kind: be.PlayerGUID, ID: 1, Name: "First", GUID: guid})
+			p, _ := r.ByID(1)
+			Expect(p.GUID).To(BeEquivalentTo(guid))
+			Expect(p.Addr).To(BeEquivalentTo("127.0.0.1:2304"))
+			Expect(p.Verified).To(BeFalse())
+		})
+		It("does mark verified guids", func() {
+			r.Update(&be.PlayerEvent{Kind: be.PlayerGUIDVerified, ID: 1, Name: "First", GUID: guid})
+			p, _ := r.ByID(1)
+			Expect(p.Verified).To(BeTrue())
+		})
+	})
+
+	Describe("Add", func() {
+		It("does add unknown players", func() {
+			r.Add(be.Player{ID: 3, Name: "Third", GUID: guid})
+			p, ok := r.ByName("Third")
+			Expect(ok).To(BeTrue())
+			Expect(p.GUID).To(BeEquivalentTo(guid))
+			Expect(p.Joined).NotTo(BeZero())
+		})
+		It("does keep known players", func() {
+			r.Add(be.Player{ID: 1, Name: "Renamed"})
+			p, _ := r.ByID(1)
+			Expect(p.Name).To(BeEquivalentTo("First"))
+			Expect(p.Addr).To(BeEquivalentTo("127.0.0.1:2304"))
+		})
+	})
+
+	Describe("ByName", func() {
+		It("does find players by name", func() {
+			p, ok := r.ByName("Second")
+			Expect(ok).To(BeTrue())
+			Expect(p.ID).To(BeEquivalentTo(2))
+		})
+		It("does not find unknown players", func() {
+			_, ok := r.ByName("Third")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("List", func() {
+		It("does return players sorted by id", func() {
+			l := r.List()
+			Expect(l).To(HaveLen(2))
+			Expect(l[0].Name).To(BeEquivalentTo("First"))
+			Expect(l[1].Name).To(BeEquivalentTo("Second"))
+		})
+	})
+})
@@ -2,6 +2,10 @@ package battleye
 
 import (
 	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/trace"
 )
 
 // Transmission is the BattlEye implementation of rcon.Transmission
@@ -10,6 +14,10 @@ type Transmission struct {
 	request  []byte
 	done     chan bool
 	response []byte
+	created  time.Time
+	complete uint32
+	// span of the command used as parent when handling its response
+	span trace.SpanContext
 
 	// As we might receive multiple packets responding to a single transmission
 	// we have to collect them by their respective id and return them after a final sort
@@ -22,6 +30,7 @@ func NewTransmission(request string) *Transmission {
 	return &Transmission{
 		request:     []byte(request),
 		done:        make(chan bool),
+		created:     time.Now(),
 		multiBuffer: make(map[int][]byte),
 	}
 }
@@ -41,6 +50,11 @@ func (t *Transmission) Done() <-chan bool {
 	return t.done
 }
 
+// completed marks the transmission as completed returning false if it already was
+func (t *Transmission) completed() bool {
+	return atomic.CompareAndSwapUint32(&t.complete, 0, 1)
+}
+
 // Response returns the final response
 // Checking if the transmission is done before retrieving is suggested
 // Otherwise this might render the transaction useless caused by the way multiResponsePackets get handled
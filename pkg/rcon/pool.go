@@ -0,0 +1,390 @@
+package rcon
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// ErrPoolExhausted is returned when a command could not be sent within the pool's QueueTimeout
+var ErrPoolExhausted = errors.New("too many commands in flight")
+
+// ErrPoolDown is returned when all connections of a pool are being reconnected
+var ErrPoolDown = errors.New("no pooled connection available")
+
+// MaxPoolSize caps the connections a pool opens to a single server
+// BattlEye limits the rcon connections accepted per ip so this is kept conservative
+var MaxPoolSize = 4
+
+// Muter is implemented by connections able to drop their events
+// Pools mute all but one connection to avoid emitting every server message multiple times
+type Muter interface {
+	Mute(bool)
+}
+
+// Pool is a Client maintaining multiple authenticated connections to the server of the underlying Client
+// Commands are routed to the connection with the fewest commands in flight. The total of commands in flight
+// is capped by MaxInFlight, excess commands wait for up to QueueTimeout
+// Connections failing writes or health checks are replaced by new ones while the others keep serving commands
+type Pool struct {
+	Client Client
+	// Size of the pool, capped by MaxPoolSize
+	Size int
+	// MaxInFlight commands across all connections. Unlimited if zero
+	MaxInFlight int
+	// QueueTimeout is the maximum time a command waits for a free slot
+	QueueTimeout time.Duration
+	// ResponseTimeout frees the slot of a command that did not get a response in time
+	ResponseTimeout time.Duration
+	// HealthInterval in which every opened connection has to answer HealthCommand within ResponseTimeout
+	// Health checks are disabled if zero
+	HealthInterval time.Duration
+	// HealthCommand sent by health checks. BattlEye answers empty commands like keep alive packets
+	HealthCommand string
+	// ReconnectInterval between attempts of replacing a failed connection
+	ReconnectInterval time.Duration
+}
+
+// NewPool of size connections created by c
+func NewPool(c Client, size int) *Pool {
+	return &Pool{
+		Client:            c,
+		Size:              size,
+		MaxInFlight:       size * 4,
+		QueueTimeout:      5 * time.Second,
+		ResponseTimeout:   10 * time.Second,
+		HealthInterval:    30 * time.Second,
+		ReconnectInterval: 5 * time.Second,
+	}
+}
+
+// NewConnection returns a single Connection backed by the pool's connections
+func (p *Pool) NewConnection(ctx context.Context) Connection {
+	size := p.Size
+	if size > MaxPoolSize {
+		size = MaxPoolSize
+	}
+	if size < 1 {
+		size = 1
+	}
+	c := &pooled{pool: p}
+	if p.MaxInFlight > 0 {
+		c.slots = make(chan struct{}, p.MaxInFlight)
+	}
+	for i := 0; i < size; i++ {
+		con := p.newConnection(ctx, i)
+		if con == nil {
+			return nil
+		}
+		c.members = append(c.members, &member{index: i, con: con})
+	}
+	return c
+}
+
+// newConnection of the member index, muting all but the first one
+func (p *Pool) newConnection(ctx context.Context, index int) Connection {
+	con := p.Client.NewConnection(ctx)
+	if m, ok := con.(Muter); ok && con != nil {
+		m.Mute(index > 0)
+	}
+	return con
+}
+
+type member struct {
+	index    int
+	inFlight int64
+	// down is set while the connection gets replaced
+	down int32
+
+	m   sync.Mutex
+	con Connection
+}
+
+func (m *member) connection() Connection {
+	m.m.Lock()
+	defer m.m.Unlock()
+	return m.con
+}
+
+func (m *member) replace(con Connection) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.con = con
+}
+
+// subscription to events of the unmuted connection, renewed when it gets replaced
+type subscription struct {
+	ctx      context.Context
+	out      chan<- event.Event
+	filtered bool
+	filter   event.Filter
+}
+
+// pooled routes commands across its members
+type pooled struct {
+	pool    *Pool
+	members []*member
+	slots   chan struct{}
+
+	m    sync.Mutex
+	next int
+	subs []subscription
+	// ctx of the opened pool used for health checks and reconnects
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Open all connections. Already opened connections are closed again if one fails
+func (c *pooled) Open(ctx context.Context) error {
+	for i, m := range c.members {
+		if err := m.connection().Open(ctx); err != nil {
+			for _, o := range c.members[:i] {
+				o.connection().Close(ctx)
+			}
+			return errors.Wrapf(err, "opening pooled connection %d", i)
+		}
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.ctx, c.cancel = context.WithCancel(ctx)
+	if c.pool.HealthInterval > 0 {
+		c.wg.Add(1)
+		go c.health(c.ctx)
+	}
+	return nil
+}
+
+// Close all connections returning the first error. Health checks and reconnects are stopped
+func (c *pooled) Close(ctx context.Context) error {
+	c.m.Lock()
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.ctx, c.cancel = nil, nil
+	c.m.Unlock()
+	c.wg.Wait()
+	var first error
+	for i, m := range c.members {
+		if err := m.connection().Close(ctx); err != nil && first == nil {
+			first = errors.Wrapf(err, "closing pooled connection %d", i)
+		}
+	}
+	return first
+}
+
+// Subscribe to events of the unmuted connection
+func (c *pooled) Subscribe(ctx context.Context, out chan<- event.Event) {
+	c.m.Lock()
+	c.subs = append(c.subs, subscription{ctx: ctx, out: out})
+	c.m.Unlock()
+	c.members[0].connection().Subscribe(ctx, out)
+}
+
+// SubscribeFilter to events of the unmuted connection matching f
+func (c *pooled) SubscribeFilter(ctx context.Context, out chan<- event.Event, f event.Filter) {
+	c.m.Lock()
+	c.subs = append(c.subs, subscription{ctx: ctx, out: out, filtered: true, filter: f})
+	c.m.Unlock()
+	c.members[0].connection().SubscribeFilter(ctx, out, f)
+}
+
+// Write cmd to the least busy connection once a slot is free
+func (c *pooled) Write(ctx context.Context, cmd string) (Transmission, error) {
+	if err := c.acquire(ctx); err != nil {
+		return nil, err
+	}
+	m := c.pick()
+	if m == nil {
+		c.release(nil)
+		return nil, ErrPoolDown
+	}
+	trm, err := m.connection().Write(ctx, cmd)
+	if err != nil {
+		c.release(m)
+		c.fail(m, err)
+		return nil, err
+	}
+
+	t := &pooledTransmission{Transmission: trm, done: make(chan bool, 1)}
+	go func() {
+		select {
+		case ok := <-trm.Done():
+			c.release(m)
+			t.done <- ok
+		case <-time.After(c.pool.ResponseTimeout):
+			c.release(m)
+			t.done <- false
+		}
+	}()
+	return t, nil
+}
+
+func (c *pooled) acquire(ctx context.Context) error {
+	if c.slots == nil {
+		return nil
+	}
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(c.pool.QueueTimeout):
+		return ErrPoolExhausted
+	}
+}
+
+// release the slot of a command sent through m, which is nil if no member got picked
+func (c *pooled) release(m *member) {
+	if m != nil {
+		atomic.AddInt64(&m.inFlight, -1)
+	}
+	if c.slots != nil {
+		<-c.slots
+	}
+}
+
+// pick the member with the fewest commands in flight, rotating between equally busy ones, and count the new command
+// Members being reconnected are skipped. Returns nil if all of them are
+func (c *pooled) pick() *member {
+	c.m.Lock()
+	defer c.m.Unlock()
+	n := len(c.members)
+	var best *member
+	for i := 0; i < n; i++ {
+		m := c.members[(c.next+i)%n]
+		if atomic.LoadInt32(&m.down) == 1 {
+			continue
+		}
+		if best == nil || atomic.LoadInt64(&m.inFlight) < atomic.LoadInt64(&best.inFlight) {
+			best = m
+		}
+	}
+	c.next++
+	if best != nil {
+		atomic.AddInt64(&best.inFlight, 1)
+	}
+	return best
+}
+
+// health checks all members every HealthInterval until ctx is closed
+func (c *pooled) health(ctx context.Context) {
+	defer c.wg.Done()
+	tick := time.NewTicker(c.pool.HealthInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+		for _, m := range c.members {
+			if atomic.LoadInt32(&m.down) == 1 {
+				continue
+			}
+			if err := c.check(ctx, m); err != nil && ctx.Err() == nil {
+				c.fail(m, err)
+			}
+		}
+	}
+}
+
+// check whether m answers HealthCommand within ResponseTimeout
+func (c *pooled) check(ctx context.Context, m *member) error {
+	trm, err := m.connection().Write(ctx, c.pool.HealthCommand)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-trm.Done():
+		return nil
+	case <-time.After(c.pool.ResponseTimeout):
+		return errors.New("health check timed out")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// fail marks m as down because of err and replaces its connection in the background
+// Nothing is replaced while the pool is not opened
+func (c *pooled) fail(m *member, err error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.ctx == nil || !atomic.CompareAndSwapInt32(&m.down, 0, 1) {
+		return
+	}
+	log.From(c.ctx).Warn("replacing pooled connection", zap.Int("index", m.index), zap.Error(err))
+	c.wg.Add(1)
+	go c.reconnect(c.ctx, m)
+}
+
+// reconnect m by replacing its connection with a new one every ReconnectInterval until one opens or ctx is closed
+func (c *pooled) reconnect(ctx context.Context, m *member) {
+	defer c.wg.Done()
+	if err := m.connection().Close(ctx); err != nil {
+		log.From(ctx).Debug("closing failed pooled connection", zap.Int("index", m.index), zap.Error(err))
+	}
+	for {
+		con := c.pool.newConnection(ctx, m.index)
+		if con != nil {
+			err := con.Open(ctx)
+			if err == nil {
+				m.replace(con)
+				c.resubscribe(m)
+				atomic.StoreInt32(&m.down, 0)
+				log.From(ctx).Info("replaced pooled connection", zap.Int("index", m.index))
+				return
+			}
+			log.From(ctx).Debug("opening pooled connection", zap.Int("index", m.index), zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.pool.ReconnectInterval):
+		}
+	}
+}
+
+// resubscribe the subscriptions to the new connection of m if it is the unmuted one
+func (c *pooled) resubscribe(m *member) {
+	if m.index > 0 {
+		return
+	}
+	c.m.Lock()
+	subs := append([]subscription(nil), c.subs...)
+	c.m.Unlock()
+	con := m.connection()
+	for _, s := range subs {
+		if s.ctx.Err() != nil {
+			continue
+		}
+		if s.filtered {
+			con.SubscribeFilter(s.ctx, s.out, s.filter)
+		} else {
+			con.Subscribe(s.ctx, s.out)
+		}
+	}
+}
+
+// pooledTransmission forwards the done signal of the wrapped transmission after freeing its slot
+// Transmissions without response within ResponseTimeout signal false
+type pooledTransmission struct {
+	Transmission
+	done chan bool
+}
+
+func (t *pooledTransmission) Done() <-chan bool {
+	return t.done
+}
@@ -0,0 +1,280 @@
+package rcon_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+	"github.com/playnet-public/gorcon/pkg/mocks"
+	"github.com/playnet-public/gorcon/pkg/rcon"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type mutedConnection struct {
+	*mocks.RconConnection
+	muted bool
+}
+
+func (m *mutedConnection) Mute(mute bool) { m.muted = mute }
+
+var _ = Describe("Pool", func() {
+	var (
+		ctx    context.Context
+		client *mocks.RconClient
+		cons   []*mutedConnection
+		dones  []chan bool
+		m      sync.Mutex
+		p      *rcon.Pool
+	)
+
+	// connection i created by the client or nil, for connections created in the background
+	connection := func(i int) *mutedConnection {
+		m.Lock()
+		defer m.Unlock()
+		if i >= len(cons) {
+			return nil
+		}
+		return cons[i]
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		m.Lock()
+		cons = nil
+		dones = nil
+		m.Unlock()
+		client = &mocks.RconClient{}
+		client.NewConnectionStub = func(context.Context) rcon.Connection {
+			con := &mutedConnection{RconConnection: &mocks.RconConnection{}}
+			con.WriteStub = func(_ context.Context, cmd string) (rcon.Transmission, error) {
+				done := make(chan bool, 1)
+				if cmd == "" {
+					// health checks are answered right away
+					done <- true
+				}
+				m.Lock()
+				dones = append(dones, done)
+				m.Unlock()
+				trm := &mocks.RconTransmission{}
+				trm.DoneReturns(done)
+				return trm, nil
+			}
+			m.Lock()
+			cons = append(cons, con)
+			m.Unlock()
+			return con
+		}
+		p = rcon.NewPool(client, 3)
+	})
+
+	Describe("NewConnection", func() {
+		It("does create size connections", func() {
+			Expect(p.NewConnection(ctx)).NotTo(BeNil())
+			Expect(client.NewConnectionCallCount()).To(Equal(3))
+		})
+		It("does cap the size", func() {
+			p.Size = rcon.MaxPoolSize + 5
+			p.NewConnection(ctx)
+			Expect(cons).To(HaveLen(rcon.MaxPoolSize))
+		})
+		It("does mute all but the first connection", func() {
+			p.NewConnection(ctx)
+			Expect(cons[0].muted).To(BeFalse())
+			Expect(cons[1].muted).To(BeTrue())
+			Expect(cons[2].muted).To(BeTrue())
+		})
+		It("does return nil if the client does", func() {
+			client.NewConnectionStub = nil
+			client.NewConnectionReturns(nil)
+			Expect(p.NewConnection(ctx)).To(BeNil())
+		})
+	})
+
+	Describe("Open", func() {
+		It("does open all connections", func() {
+			Expect(p.NewConnection(ctx).Open(ctx)).To(BeNil())
+			for _, c := range cons {
+				Expect(c.OpenCallCount()).To(Equal(1))
+			}
+		})
+		It("does close opened connections on failure", func() {
+			con := p.NewConnection(ctx)
+			cons[1].OpenReturns(errors.New("test"))
+			Expect(con.Open(ctx)).NotTo(BeNil())
+			Expect(cons[0].CloseCallCount()).To(Equal(1))
+			Expect(cons[2].OpenCallCount()).To(BeZero())
+		})
+	})
+
+	Describe("Close", func() {
+		It("does close all connections and return the first error", func() {
+			con := p.NewConnection(ctx)
+			cons[1].CloseReturns(errors.New("test"))
+			Expect(con.Close(ctx)).NotTo(BeNil())
+			for _, c := range cons {
+				Expect(c.CloseCallCount()).To(Equal(1))
+			}
+		})
+	})
+
+	Describe("Subscribe", func() {
+		It("does subscribe to the first connection", func() {
+			p.NewConnection(ctx).Subscribe(ctx, nil)
+			Expect(cons[0].SubscribeCallCount()).To(Equal(1))
+			Expect(cons[1].SubscribeCallCount()).To(BeZero())
+		})
+		It("does subscribe with filter to the first connection", func() {
+			p.NewConnection(ctx).SubscribeFilter(ctx, nil, event.Filter{Kinds: []event.Kind{"chat"}})
+			Expect(cons[0].SubscribeFilterCallCount()).To(Equal(1))
+			_, _, f := cons[0].SubscribeFilterArgsForCall(0)
+			Expect(f.Kinds).To(Equal([]event.Kind{"chat"}))
+			Expect(cons[1].SubscribeFilterCallCount()).To(BeZero())
+		})
+	})
+
+	Describe("Write", func() {
+		It("does spread commands across idle connections", func() {
+			con := p.NewConnection(ctx)
+			for i := 0; i < 3; i++ {
+				_, err := con.Write(ctx, "players")
+				Expect(err).To(BeNil())
+			}
+			for _, c := range cons {
+				Expect(c.WriteCallCount()).To(Equal(1))
+			}
+		})
+		It("does prefer connections with fewer commands in flight", func() {
+			con := p.NewConnection(ctx)
+			for i := 0; i < 3; i++ {
+				con.Write(ctx, "players")
+			}
+			trm := dones[1]
+			trm <- true
+			Eventually(func() int {
+				t, _ := con.Write(ctx, "players")
+				Expect(t).NotTo(BeNil())
+				return cons[1].WriteCallCount()
+			}).Should(Equal(2))
+		})
+		It("does forward done signals", func() {
+			con := p.NewConnection(ctx)
+			t, _ := con.Write(ctx, "players")
+			dones[0] <- true
+			Expect(<-t.Done()).To(BeTrue())
+		})
+		It("does queue commands exceeding the in flight limit", func() {
+			p.MaxInFlight = 1
+			con := p.NewConnection(ctx)
+			con.Write(ctx, "players")
+			written := make(chan error)
+			go func() {
+				_, err := con.Write(ctx, "players")
+				written <- err
+			}()
+			Consistently(written, 50*time.Millisecond).ShouldNot(Receive())
+			dones[0] <- true
+			Eventually(written).Should(Receive(BeNil()))
+		})
+		It("does give up after the queue timeout", func() {
+			p.MaxInFlight = 1
+			p.QueueTimeout = 10 * time.Millisecond
+			con := p.NewConnection(ctx)
+			con.Write(ctx, "players")
+			_, err := con.Write(ctx, "players")
+			Expect(err).To(Equal(rcon.ErrPoolExhausted))
+		})
+		It("does free slots of unanswered commands", func() {
+			p.MaxInFlight = 1
+			p.ResponseTimeout = 10 * time.Millisecond
+			con := p.NewConnection(ctx)
+			con.Write(ctx, "players")
+			_, err := con.Write(ctx, "players")
+			Expect(err).To(BeNil())
+		})
+		It("does signal unanswered commands as failed", func() {
+			p.ResponseTimeout = 10 * time.Millisecond
+			con := p.NewConnection(ctx)
+			t, _ := con.Write(ctx, "players")
+			Eventually(t.Done()).Should(Receive(BeFalse()))
+		})
+		It("does free slots of failed writes", func() {
+			p.MaxInFlight = 1
+			p.QueueTimeout = 10 * time.Millisecond
+			con := p.NewConnection(ctx)
+			cons[0].WriteStub = nil
+			cons[0].WriteReturns(nil, errors.New("test"))
+			_, err := con.Write(ctx, "players")
+			Expect(err).NotTo(BeNil())
+			_, err = con.Write(ctx, "players")
+			Expect(err).To(BeNil())
+		})
+	})
+
+	Describe("Health", func() {
+		var con rcon.Connection
+
+		BeforeEach(func() {
+			p.HealthInterval = 10 * time.Millisecond
+			p.ResponseTimeout = 20 * time.Millisecond
+			p.ReconnectInterval = 10 * time.Millisecond
+			con = p.NewConnection(ctx)
+		})
+
+		AfterEach(func() {
+			con.Close(ctx)
+		})
+
+		It("does replace connections failing health checks", func() {
+			cons[1].WriteStub = nil
+			cons[1].WriteReturns(nil, errors.New("test"))
+			Expect(con.Open(ctx)).To(BeNil())
+			Eventually(func() *mutedConnection { return connection(3) }).ShouldNot(BeNil())
+			Expect(cons[1].CloseCallCount()).To(Equal(1))
+			Eventually(connection(3).OpenCallCount).Should(Equal(1))
+			Expect(connection(3).muted).To(BeTrue())
+		})
+		It("does replace connections not answering health checks", func() {
+			cons[2].WriteStub = func(context.Context, string) (rcon.Transmission, error) {
+				trm := &mocks.RconTransmission{}
+				trm.DoneReturns(make(chan bool))
+				return trm, nil
+			}
+			Expect(con.Open(ctx)).To(BeNil())
+			Eventually(func() *mutedConnection { return connection(3) }).ShouldNot(BeNil())
+			Expect(cons[2].CloseCallCount()).To(Equal(1))
+		})
+		It("does skip connections being replaced", func() {
+			p.ReconnectInterval = time.Hour
+			client.NewConnectionStub = nil
+			Expect(con.Open(ctx)).To(BeNil())
+			failing := &mocks.RconConnection{}
+			failing.OpenReturns(errors.New("test"))
+			client.NewConnectionReturns(failing)
+			cons[0].WriteStub = nil
+			cons[0].WriteReturns(nil, errors.New("test"))
+			_, err := con.Write(ctx, "players")
+			Expect(err).NotTo(BeNil())
+			for i := 0; i < 4; i++ {
+				_, err := con.Write(ctx, "players")
+				Expect(err).To(BeNil())
+			}
+			Expect(cons[0].WriteCallCount()).To(Equal(1))
+			Eventually(failing.OpenCallCount).Should(Equal(1))
+		})
+		It("does renew subscriptions when replacing the unmuted connection", func() {
+			p.HealthInterval = 0
+			Expect(con.Open(ctx)).To(BeNil())
+			con.Subscribe(ctx, nil)
+			cons[0].WriteStub = nil
+			cons[0].WriteReturns(nil, errors.New("test"))
+			con.Write(ctx, "players")
+			Eventually(func() *mutedConnection { return connection(3) }).ShouldNot(BeNil())
+			Eventually(connection(3).SubscribeCallCount).Should(Equal(1))
+			Expect(connection(3).muted).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,285 @@
+package rcon
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/metrics"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// Priority of a command in the Queue. Lower values are sent first
+type Priority int
+
+const (
+	// Interactive commands issued by admins
+	Interactive Priority = iota
+	// Moderation commands issued by automated enforcement like kicks and warnings
+	Moderation
+	// Scheduled commands like announcements
+	Scheduled
+)
+
+// String returns the name of the priority
+func (p Priority) String() string {
+	switch p {
+	case Interactive:
+		return "interactive"
+	case Moderation:
+		return "moderation"
+	case Scheduled:
+		return "scheduled"
+	}
+	return "unknown"
+}
+
+var priorities = []Priority{Interactive, Moderation, Scheduled}
+
+type priorityKey struct{}
+
+// WithPriority returns a context queueing all commands written with it at p
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFrom returns the priority set on ctx defaulting to Interactive
+func PriorityFrom(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok && p >= Interactive && p <= Scheduled {
+		return p
+	}
+	return Interactive
+}
+
+// ErrQueueFull is returned when a command exceeds the queue's MaxDepth
+var ErrQueueFull = errors.New("command queue full")
+
+var (
+	queueDepth = metrics.NewGaugeVec("gorcon_rcon_queue_depth", "Commands waiting in the outbound queue.", "server", "priority")
+	queueWait  = metrics.NewHistogramVec("gorcon_rcon_queue_wait_seconds", "Time commands spent in the outbound queue.", nil, "server", "priority")
+)
+
+// Queue is a Writer sending commands to the underlying Writer in priority order while enforcing a rate limit
+// Commands of the same priority are sent in the order they were written
+type Queue struct {
+	Writer Writer
+	// Server labels the queue's metrics
+	Server string
+	// Rate of commands per second. Unlimited if zero
+	Rate float64
+	// Burst of commands sent without delay after being idle
+	Burst int
+	// MaxDepth of commands waiting per priority. Unlimited if zero
+	MaxDepth int
+
+	m       sync.Mutex
+	queued  map[Priority][]*request
+	notify  chan struct{}
+	tokens  float64
+	updated time.Time
+}
+
+type request struct {
+	ctx      context.Context
+	cmd      string
+	priority Priority
+	queued   time.Time
+	result   chan result
+}
+
+type result struct {
+	trm Transmission
+	err error
+}
+
+// NewQueue sending rate commands per second with bursts of up to burst commands to w
+func NewQueue(w Writer, rate float64, burst int) *Queue {
+	return &Queue{
+		Writer:  w,
+		Rate:    rate,
+		Burst:   burst,
+		queued:  make(map[Priority][]*request),
+		notify:  make(chan struct{}, 1),
+		tokens:  float64(burst),
+		updated: time.Now(),
+	}
+}
+
+// Write queues cmd at the priority found in ctx and waits until it got sent
+// Canceling ctx removes the command from the queue if it was not sent yet
+func (q *Queue) Write(ctx context.Context, cmd string) (Transmission, error) {
+	r := &request{
+		ctx:      ctx,
+		cmd:      cmd,
+		priority: PriorityFrom(ctx),
+		queued:   time.Now(),
+		result:   make(chan result, 1),
+	}
+
+	q.m.Lock()
+	if q.MaxDepth > 0 && len(q.queued[r.priority]) >= q.MaxDepth {
+		q.m.Unlock()
+		return nil, ErrQueueFull
+	}
+	q.queued[r.priority] = append(q.queued[r.priority], r)
+	q.depth(r.priority)
+	q.m.Unlock()
+	q.signal()
+
+	select {
+	case res := <-r.result:
+		return res.trm, res.err
+	case <-ctx.Done():
+		if q.remove(r) {
+			return nil, ctx.Err()
+		}
+		// the command is already being sent
+		res := <-r.result
+		return res.trm, res.err
+	}
+}
+
+// Len returns the number of commands waiting at p
+func (q *Queue) Len(p Priority) int {
+	q.m.Lock()
+	defer q.m.Unlock()
+	return len(q.queued[p])
+}
+
+// Run the queue sending commands until ctx is closed. Commands still queued fail with the context's error
+func (q *Queue) Run(ctx context.Context) error {
+	defer q.drain(ctx.Err)
+	for {
+		select {
+		case <-ctx.Done():
+			log.From(ctx).Info("stopping command queue", zap.Error(ctx.Err()))
+			return ctx.Err()
+		case <-q.notify:
+		}
+
+		for ctx.Err() == nil && q.pending() {
+			if err := q.take(ctx); err != nil {
+				return err
+			}
+			r := q.pop()
+			if r == nil {
+				// everything got canceled while waiting, return the token
+				q.give()
+				break
+			}
+			queueWait.With(q.Server, r.priority.String()).Observe(time.Since(r.queued).Seconds())
+			trm, err := q.Writer.Write(r.ctx, r.cmd)
+			r.result <- result{trm, err}
+		}
+	}
+}
+
+func (q *Queue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) pending() bool {
+	q.m.Lock()
+	defer q.m.Unlock()
+	for _, p := range priorities {
+		if len(q.queued[p]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// pop the oldest command of the highest priority
+func (q *Queue) pop() *request {
+	q.m.Lock()
+	defer q.m.Unlock()
+	for _, p := range priorities {
+		if len(q.queued[p]) > 0 {
+			r := q.queued[p][0]
+			q.queued[p] = q.queued[p][1:]
+			q.depth(p)
+			return r
+		}
+	}
+	return nil
+}
+
+// remove r from the queue returning false if it was not queued anymore
+func (q *Queue) remove(r *request) bool {
+	q.m.Lock()
+	defer q.m.Unlock()
+	list := q.queued[r.priority]
+	for i, o := range list {
+		if o == r {
+			q.queued[r.priority] = append(list[:i:i], list[i+1:]...)
+			q.depth(r.priority)
+			return true
+		}
+	}
+	return false
+}
+
+func (q *Queue) drain(err func() error) {
+	q.m.Lock()
+	defer q.m.Unlock()
+	for _, p := range priorities {
+		for _, r := range q.queued[p] {
+			r.result <- result{nil, err()}
+		}
+		q.queued[p] = nil
+		q.depth(p)
+	}
+}
+
+// depth updates the metric of p. The caller must hold the lock
+func (q *Queue) depth(p Priority) {
+	queueDepth.With(q.Server, p.String()).Set(float64(len(q.queued[p])))
+}
+
+// take a token from the bucket waiting for it to refill if necessary
+func (q *Queue) take(ctx context.Context) error {
+	if q.Rate <= 0 {
+		return nil
+	}
+	for {
+		q.m.Lock()
+		now := time.Now()
+		burst := float64(q.Burst)
+		if burst < 1 {
+			burst = 1
+		}
+		q.tokens += now.Sub(q.updated).Seconds() * q.Rate
+		if q.tokens > burst {
+			q.tokens = burst
+		}
+		q.updated = now
+		if q.tokens >= 1 {
+			q.tokens--
+			q.m.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - q.tokens) / q.Rate * float64(time.Second))
+		q.m.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (q *Queue) give() {
+	if q.Rate <= 0 {
+		return
+	}
+	q.m.Lock()
+	defer q.m.Unlock()
+	q.tokens++
+}
@@ -0,0 +1,143 @@
+package rcon_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/mocks"
+	"github.com/playnet-public/gorcon/pkg/rcon"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Queue", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		w      *mocks.RconWriter
+		q      *rcon.Queue
+
+		m    sync.Mutex
+		sent []string
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		sent = nil
+		w = &mocks.RconWriter{}
+		w.WriteStub = func(ctx context.Context, cmd string) (rcon.Transmission, error) {
+			m.Lock()
+			defer m.Unlock()
+			sent = append(sent, cmd)
+			return &mocks.RconTransmission{}, nil
+		}
+		q = rcon.NewQueue(w, 0, 0)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	commands := func() []string {
+		m.Lock()
+		defer m.Unlock()
+		return append([]string(nil), sent...)
+	}
+
+	write := func(ctx context.Context, cmd string) chan error {
+		errs := make(chan error, 1)
+		go func() {
+			_, err := q.Write(ctx, cmd)
+			errs <- err
+		}()
+		return errs
+	}
+
+	Describe("PriorityFrom", func() {
+		It("does default to interactive", func() {
+			Expect(rcon.PriorityFrom(ctx)).To(Equal(rcon.Interactive))
+		})
+		It("does return the priority of ctx", func() {
+			Expect(rcon.PriorityFrom(rcon.WithPriority(ctx, rcon.Scheduled))).To(Equal(rcon.Scheduled))
+		})
+	})
+
+	It("does send commands once running", func() {
+		errs := write(ctx, "players")
+		Eventually(func() int { return q.Len(rcon.Interactive) }).Should(Equal(1))
+		go q.Run(ctx)
+		Eventually(errs).Should(Receive(BeNil()))
+		Expect(commands()).To(Equal([]string{"players"}))
+	})
+	It("does send higher priorities first and keep order within priorities", func() {
+		var errs []chan error
+		for _, c := range []struct {
+			p   rcon.Priority
+			cmd string
+		}{
+			{rcon.Scheduled, "say -1 news"},
+			{rcon.Moderation, "kick 1"},
+			{rcon.Interactive, "players"},
+			{rcon.Moderation, "kick 2"},
+		} {
+			errs = append(errs, write(rcon.WithPriority(ctx, c.p), c.cmd))
+			p := c.p
+			n := q.Len(p) + 1
+			Eventually(func() int { return q.Len(p) }).Should(Equal(n))
+		}
+		go q.Run(ctx)
+		for _, e := range errs {
+			Eventually(e).Should(Receive(BeNil()))
+		}
+		Expect(commands()).To(Equal([]string{"players", "kick 1", "kick 2", "say -1 news"}))
+	})
+	It("does remove canceled commands", func() {
+		cctx, ccancel := context.WithCancel(ctx)
+		errs := write(cctx, "players")
+		Eventually(func() int { return q.Len(rcon.Interactive) }).Should(Equal(1))
+		ccancel()
+		Eventually(errs).Should(Receive(Equal(context.Canceled)))
+		Expect(q.Len(rcon.Interactive)).To(BeZero())
+		go q.Run(ctx)
+		Consistently(commands, 50*time.Millisecond).Should(BeEmpty())
+	})
+	It("does reject commands exceeding the depth", func() {
+		q.MaxDepth = 1
+		write(ctx, "players")
+		Eventually(func() int { return q.Len(rcon.Interactive) }).Should(Equal(1))
+		_, err := q.Write(ctx, "players")
+		Expect(err).To(Equal(rcon.ErrQueueFull))
+		write(rcon.WithPriority(ctx, rcon.Scheduled), "say -1 hi")
+		Eventually(func() int { return q.Len(rcon.Scheduled) }).Should(Equal(1))
+	})
+	It("does fail queued commands when stopping", func() {
+		errs := write(ctx, "players")
+		Eventually(func() int { return q.Len(rcon.Interactive) }).Should(Equal(1))
+		rctx, rcancel := context.WithCancel(context.Background())
+		rcancel()
+		Expect(q.Run(rctx)).To(Equal(context.Canceled))
+		Eventually(errs).Should(Receive(Equal(context.Canceled)))
+	})
+	It("does limit the rate", func() {
+		q = rcon.NewQueue(w, 20, 1)
+		go q.Run(ctx)
+		start := time.Now()
+		for i := 0; i < 4; i++ {
+			_, err := q.Write(ctx, "players")
+			Expect(err).To(BeNil())
+		}
+		Expect(time.Since(start)).To(BeNumerically(">=", 140*time.Millisecond))
+	})
+	It("does allow bursts", func() {
+		q = rcon.NewQueue(w, 1, 5)
+		go q.Run(ctx)
+		start := time.Now()
+		for i := 0; i < 5; i++ {
+			_, err := q.Write(ctx, "players")
+			Expect(err).To(BeNil())
+		}
+		Expect(time.Since(start)).To(BeNumerically("<", 500*time.Millisecond))
+	})
+})
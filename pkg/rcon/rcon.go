@@ -3,10 +3,11 @@ package rcon
 
 import (
 	"context"
+	"strings"
 	"sync"
-	"time"
 
 	"github.com/playnet-public/gorcon/pkg/event"
+	"github.com/playnet-public/gorcon/pkg/trace"
 
 	"github.com/pkg/errors"
 )
@@ -30,6 +31,14 @@ type Connection interface {
 	Write(context.Context, string) (Transmission, error)
 	// Listen for events on the connection.
 	Subscribe(context.Context, chan<- event.Event)
+	// SubscribeFilter listens for events on the connection matching the filter
+	SubscribeFilter(context.Context, chan<- event.Event, event.Filter)
+}
+
+// Writer is the interface for sending commands to rcon. It is implemented by Rcon as well as Connection
+//go:generate counterfeiter -o ../mocks/rcon_writer.go --fake-name RconWriter . Writer
+type Writer interface {
+	Write(context.Context, string) (Transmission, error)
 }
 
 // Client is the interface for specific rcon implementations which provides connections or acts as connection pool
@@ -48,61 +57,88 @@ type Transmission interface {
 	Response() string
 }
 
-// Event describes a log event emitted by the process
-// TODO(kwiesmueller): rework this to a new Event interface used by all broker dependents
-type Event struct {
-	timestamp time.Time
-	kind      byte
-	payload   string
-}
+// Event emitted by rcon connections
+//
+// Deprecated: use event.Record
+type Event = event.Record
 
 // NewEvent of kind with data
-func NewEvent(kind byte, data string) *Event {
-	return &Event{
-		timestamp: time.Now().UTC(),
-		kind:      kind,
-		payload:   data,
-	}
+func NewEvent(kind event.Kind, data string) *Event {
+	return NewServerEvent("", kind, data)
 }
 
-// Timestamp when the event occurred
-func (e Event) Timestamp() time.Time {
-	return e.timestamp
+// NewServerEvent of kind with data originating from server
+func NewServerEvent(server string, kind event.Kind, data string) *Event {
+	return event.New(event.Source{Server: server, Component: event.ComponentRcon}, kind, data)
 }
 
-// Kind of the event
-func (e Event) Kind() string {
-	return string(e.kind)
-}
-
-// Data of the real event
-func (e Event) Data() string { return e.payload }
-
-// TypeEvent identifies default rcon events
-var TypeEvent byte = 0x00
+// Kinds of events sent via rcon
+const (
+	// TypeEvent identifies default rcon events
+	TypeEvent = event.KindEvent
+	// TypeChat identifies chat events sent via rcon
+	TypeChat = event.KindChat
+	// TypePlayer identifies player events like connects, guid verifications and kicks sent via rcon
+	TypePlayer = event.KindPlayer
+)
 
-// TypeChat identifies chat events sent via rcon
-var TypeChat byte = 0x01
+// KindName returns a readable name for the kind of rcon events
+// Kinds emitted before events became structured are resolved to their current name, others are returned unchanged
+func KindName(kind string) string {
+	switch kind {
+	case "\x00":
+		return string(TypeEvent)
+	case "\x01":
+		return string(TypeChat)
+	case "\x02":
+		return string(TypePlayer)
+	}
+	return kind
+}
 
 // Connect to rcon server
-func (r *Rcon) Connect(ctx context.Context) error {
+func (r *Rcon) Connect(ctx context.Context) (err error) {
+	ctx, span := trace.Start(ctx, "rcon.Connect")
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
 	if r.Client == nil {
 		return errors.New("client must not be nil")
 	}
+	r.m.Lock()
+	defer r.m.Unlock()
 	if r.Con != nil {
 		return errors.New("connection already present")
 	}
-	r.Con = r.Client.NewConnection(ctx)
-	if r.Con == nil {
+	con := r.Client.NewConnection(ctx)
+	if con == nil {
 		return errors.New("client returned nil connection")
 	}
+	// the failed connection is dropped, so connecting can be retried
+	if err := con.Open(ctx); err != nil {
+		return err
+	}
+	r.Con = con
+	return nil
+}
+
+// Connected returns whether a connection is present
+func (r *Rcon) Connected() bool {
 	r.m.Lock()
 	defer r.m.Unlock()
-	return r.Con.Open(ctx)
+	return r.Con != nil
 }
 
 // Write to rcon server
-func (r *Rcon) Write(ctx context.Context, cmd string) (Transmission, error) {
+func (r *Rcon) Write(ctx context.Context, cmd string) (trm Transmission, err error) {
+	ctx, span := trace.Start(ctx, "rcon.Write")
+	// arguments are not recorded, as they may contain passwords, e.g. of #login
+	span.SetAttribute("rcon.command", CommandName(cmd))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
 	r.m.Lock()
 	defer r.m.Unlock()
 	if r.Con == nil {
@@ -113,32 +149,45 @@ func (r *Rcon) Write(ctx context.Context, cmd string) (Transmission, error) {
 
 // Reconnect to rcon server. This tries to gracefully close the current connection and then replace it with a new one
 // A failing close will not stop the reconnection process for now
-func (r *Rcon) Reconnect(ctx context.Context) error {
+func (r *Rcon) Reconnect(ctx context.Context) (err error) {
+	ctx, span := trace.Start(ctx, "rcon.Reconnect")
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
 	if r.Client == nil {
 		return errors.New("client must not be nil")
 	}
-	r.Con.Close(ctx)
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.Con != nil {
+		r.Con.Close(ctx)
+	}
 	r.Con = r.Client.NewConnection(ctx)
 	if r.Con == nil {
 		return errors.New("client returned nil connection")
 	}
-	r.m.Lock()
-	defer r.m.Unlock()
 	return r.Con.Open(ctx)
 }
 
 // Disconnect from rcon. This tries to gracefully close the current connection and resets the local Connection internally
-// A failing close will result in an error
+// The connection is reset even if closing it fails, which results in an error
 func (r *Rcon) Disconnect(ctx context.Context) error {
+	r.m.Lock()
+	defer r.m.Unlock()
 	if r.Con == nil {
 		return errors.New("connection already nil")
 	}
 	err := r.Con.Close(ctx)
-	if err != nil {
-		return errors.Wrap(err, "failed to close current connection")
-	}
-	r.m.Lock()
-	defer r.m.Unlock()
 	r.Con = nil
-	return nil
+	return errors.Wrap(err, "failed to close current connection")
+}
+
+// CommandName returns the first word of cmd naming the command
+func CommandName(cmd string) string {
+	fields := strings.Fields(cmd)
+	if len(fields) < 1 {
+		return ""
+	}
+	return fields[0]
 }
@@ -1,12 +1,14 @@
 package rcon_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"testing"
 
 	"github.com/playnet-public/gorcon/pkg/mocks"
 	"github.com/playnet-public/gorcon/pkg/rcon"
+	"github.com/playnet-public/gorcon/pkg/trace"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -67,6 +69,9 @@ var _ = Describe("Rcon", func() {
 		It("returns error if opening the connection fails", func() {
 			mockConnection.OpenReturns(errors.New("test"))
 			Expect(r.Connect(ctx)).NotTo(BeNil())
+			Expect(r.Con).To(BeNil())
+			Expect(r.Connect(ctx)).NotTo(BeNil())
+			Expect(mockConnection.OpenCallCount()).To(Equal(2))
 		})
 	})
 
@@ -83,6 +88,14 @@ var _ = Describe("Rcon", func() {
 			r.Write(ctx, "")
 			Expect(mockConnection.WriteCallCount()).To(BeEquivalentTo(1))
 		})
+		It("does not trace the arguments of commands", func() {
+			var out bytes.Buffer
+			trace.Default.SetExporter(trace.NewStdout(&out))
+			defer trace.Default.SetExporter(nil)
+			r.Write(ctx, "#login secret")
+			Expect(out.String()).To(ContainSubstring(`"rcon.command":"#login"`))
+			Expect(out.String()).NotTo(ContainSubstring("secret"))
+		})
 	})
 
 	Describe("Reconnect", func() {
@@ -114,6 +127,11 @@ var _ = Describe("Rcon", func() {
 			mockClient.NewConnectionReturns(nil)
 			Expect(r.Reconnect(ctx)).NotTo(BeNil())
 		})
+		It("does connect without current connection", func() {
+			r.Con = nil
+			Expect(r.Reconnect(ctx)).To(BeNil())
+			Expect(r.Connected()).To(BeTrue())
+		})
 	})
 
 	Describe("Disconnect", func() {
@@ -139,5 +157,30 @@ var _ = Describe("Rcon", func() {
 			r.Disconnect(ctx)
 			Expect(r.Con).To(BeNil())
 		})
+		It("sets the connection to nil if Con.Close fails", func() {
+			mockConnection.CloseReturns(errors.New("test"))
+			r.Disconnect(ctx)
+			Expect(r.Connected()).To(BeFalse())
+		})
+	})
+
+	Describe("Connected", func() {
+		It("does report present connections", func() {
+			Expect(r.Connected()).To(BeFalse())
+			r.Connect(ctx)
+			Expect(r.Connected()).To(BeTrue())
+		})
+	})
+})
+
+var _ = Describe("KindName", func() {
+	It("does name rcon kinds", func() {
+		Expect(rcon.KindName(string(rcon.TypeChat))).To(BeEquivalentTo("chat"))
+		Expect(rcon.KindName(string(rcon.TypePlayer))).To(BeEquivalentTo("player"))
+		Expect(rcon.KindName(string(rcon.TypeEvent))).To(BeEquivalentTo("event"))
+		Expect(rcon.KindName("\x01")).To(BeEquivalentTo("chat"))
+	})
+	It("does return unknown kinds unchanged", func() {
+		Expect(rcon.KindName("StdOut")).To(BeEquivalentTo("StdOut"))
 	})
 })
@@ -0,0 +1,293 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Stdout exporter writing one json object per span to Writer
+type Stdout struct {
+	m      sync.Mutex
+	Writer io.Writer
+}
+
+// NewStdout exporter writing to w
+func NewStdout(w io.Writer) *Stdout {
+	return &Stdout{Writer: w}
+}
+
+type stdoutSpan struct {
+	TraceID    string                 `json:"traceId"`
+	SpanID     string                 `json:"spanId"`
+	ParentID   string                 `json:"parentSpanId,omitempty"`
+	Name       string                 `json:"name"`
+	Start      time.Time              `json:"start"`
+	End        time.Time              `json:"end"`
+	Duration   string                 `json:"duration"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// Export spans to the writer
+func (s *Stdout) Export(ctx context.Context, spans []SpanData) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	enc := json.NewEncoder(s.Writer)
+	for _, d := range spans {
+		out := stdoutSpan{
+			TraceID:  d.TraceID.String(),
+			SpanID:   d.SpanID.String(),
+			Name:     d.Name,
+			Start:    d.Start,
+			End:      d.End,
+			Duration: d.End.Sub(d.Start).String(),
+			Error:    d.Err,
+		}
+		if d.Parent.IsValid() {
+			out.ParentID = d.Parent.String()
+		}
+		if len(d.Attributes) > 0 {
+			out.Attributes = make(map[string]interface{}, len(d.Attributes))
+			for _, a := range d.Attributes {
+				out.Attributes[a.Key] = a.Value
+			}
+		}
+		if err := enc.Encode(out); err != nil {
+			return errors.Wrap(err, "writing span")
+		}
+	}
+	return nil
+}
+
+// OTLP exporter posting spans to a collector using the OTLP/HTTP json encoding
+// Exported spans are queued and sent by Run in batches once BatchSize is reached or every Interval,
+// so ending spans never waits for the collector. Spans exceeding QueueSize are dropped
+type OTLP struct {
+	// Endpoint of the collector including the path, e.g. http://localhost:4318/v1/traces
+	Endpoint string
+	// Service name reported as resource attribute
+	Service   string
+	Client    *http.Client
+	BatchSize int
+	Interval  time.Duration
+	// QueueSize bounds the spans waiting to be sent. It has to be set before the first export
+	QueueSize int
+
+	once  sync.Once
+	queue chan SpanData
+	m     sync.Mutex
+	batch []SpanData
+}
+
+// NewOTLP exporter posting to endpoint
+func NewOTLP(endpoint, service string) *OTLP {
+	return &OTLP{
+		Endpoint:  endpoint,
+		Service:   service,
+		Client:    &http.Client{Timeout: 10 * time.Second},
+		BatchSize: 512,
+		Interval:  5 * time.Second,
+		QueueSize: 4096,
+	}
+}
+
+func (o *OTLP) init() chan SpanData {
+	o.once.Do(func() {
+		o.queue = make(chan SpanData, o.QueueSize)
+	})
+	return o.queue
+}
+
+// Export queues spans for Run without blocking. Returns an error if spans got dropped because the queue is full
+func (o *OTLP) Export(ctx context.Context, spans []SpanData) error {
+	queue := o.init()
+	dropped := 0
+	for _, s := range spans {
+		select {
+		case queue <- s:
+		default:
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		return errors.Errorf("dropped %d spans", dropped)
+	}
+	return nil
+}
+
+// Run sends the queued spans every Interval or once BatchSize is reached until ctx is closed
+// Remaining spans are flushed before returning
+func (o *OTLP) Run(ctx context.Context) error {
+	queue := o.init()
+	ticker := time.NewTicker(o.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			o.Flush(context.Background())
+			return ctx.Err()
+		case s := <-queue:
+			o.m.Lock()
+			o.batch = append(o.batch, s)
+			full := len(o.batch) >= o.BatchSize
+			o.m.Unlock()
+			if full {
+				o.Flush(ctx)
+			}
+		case <-ticker.C:
+			o.Flush(ctx)
+		}
+	}
+}
+
+// Flush sends all queued spans to the collector in batches of BatchSize
+func (o *OTLP) Flush(ctx context.Context) error {
+	queue := o.init()
+	o.m.Lock()
+	batch := o.batch
+	o.batch = nil
+	for more := true; more; {
+		select {
+		case s := <-queue:
+			batch = append(batch, s)
+		default:
+			more = false
+		}
+	}
+	o.m.Unlock()
+
+	for len(batch) > 0 {
+		n := len(batch)
+		if o.BatchSize > 0 && n > o.BatchSize {
+			n = o.BatchSize
+		}
+		if err := o.post(ctx, batch[:n]); err != nil {
+			return err
+		}
+		batch = batch[n:]
+	}
+	return nil
+}
+
+// post a batch of spans to the collector
+func (o *OTLP) post(ctx context.Context, batch []SpanData) error {
+	body, err := json.Marshal(o.request(batch))
+	if err != nil {
+		return errors.Wrap(err, "encoding spans")
+	}
+	req, err := http.NewRequest(http.MethodPost, o.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "building request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := o.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "posting spans")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type otlpValue map[string]interface{}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID      string          `json:"traceId"`
+	SpanID       string          `json:"spanId"`
+	ParentSpanID string          `json:"parentSpanId,omitempty"`
+	Name         string          `json:"name"`
+	Kind         int             `json:"kind"`
+	Start        string          `json:"startTimeUnixNano"`
+	End          string          `json:"endTimeUnixNano"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+	Status       otlpStatus      `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func (o *OTLP) request(batch []SpanData) otlpRequest {
+	spans := make([]otlpSpan, len(batch))
+	for i, d := range batch {
+		s := otlpSpan{
+			TraceID: d.TraceID.String(),
+			SpanID:  d.SpanID.String(),
+			Name:    d.Name,
+			Kind:    1,
+			Start:   strconv.FormatInt(d.Start.UnixNano(), 10),
+			End:     strconv.FormatInt(d.End.UnixNano(), 10),
+		}
+		if d.Parent.IsValid() {
+			s.ParentSpanID = d.Parent.String()
+		}
+		for _, a := range d.Attributes {
+			s.Attributes = append(s.Attributes, otlpAttribute{a.Key, value(a.Value)})
+		}
+		if d.Err != "" {
+			s.Status = otlpStatus{Code: 2, Message: d.Err}
+		}
+		spans[i] = s
+	}
+
+	var rs otlpResourceSpans
+	rs.Resource.Attributes = []otlpAttribute{{"service.name", value(o.Service)}}
+	scope := otlpScopeSpans{Spans: spans}
+	scope.Scope.Name = "github.com/playnet-public/gorcon"
+	rs.ScopeSpans = []otlpScopeSpans{scope}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{rs}}
+}
+
+// value converts v to an OTLP AnyValue
+func value(v interface{}) otlpValue {
+	switch v := v.(type) {
+	case string:
+		return otlpValue{"stringValue": v}
+	case bool:
+		return otlpValue{"boolValue": v}
+	case int:
+		return otlpValue{"intValue": strconv.FormatInt(int64(v), 10)}
+	case int64:
+		return otlpValue{"intValue": strconv.FormatInt(v, 10)}
+	case uint32:
+		return otlpValue{"intValue": strconv.FormatUint(uint64(v), 10)}
+	case float64:
+		return otlpValue{"doubleValue": v}
+	}
+	b, _ := json.Marshal(v)
+	return otlpValue{"stringValue": string(b)}
+}
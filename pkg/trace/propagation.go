@@ -0,0 +1,86 @@
+package trace
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Header carrying the span context between services as specified by W3C Trace Context
+const Header = "traceparent"
+
+// Format sc as traceparent header value
+func Format(sc SpanContext) string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// Parse a traceparent header value
+func Parse(v string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, false
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, sc.IsValid()
+}
+
+// Inject the span context of ctx into h
+func Inject(ctx context.Context, h http.Header) {
+	if sc := SpanContextFrom(ctx); sc.IsValid() {
+		h.Set(Header, Format(sc))
+	}
+}
+
+// Extract the span context from h into ctx. ctx is returned unchanged if h carries none
+func Extract(ctx context.Context, h http.Header) context.Context {
+	if sc, ok := Parse(h.Get(Header)); ok {
+		return WithSpanContext(ctx, sc)
+	}
+	return ctx
+}
+
+// Middleware wraps next in a span named name continuing traces of incoming requests
+func Middleware(name string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := Start(Extract(r.Context(), r.Header), name)
+		defer span.End()
+		span.SetAttribute("http.method", r.Method)
+		span.SetAttribute("http.target", r.URL.Path)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+		span.SetAttribute("http.status_code", rec.status)
+		if rec.status >= http.StatusInternalServerError {
+			span.RecordError(fmt.Errorf("status %d", rec.status))
+		}
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,201 @@
+// Package trace records spans around command execution and event handling and exports them to stdout or an OTLP collector
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// TraceID identifies a whole trace across services
+type TraceID [16]byte
+
+// String returns the hex representation of the id
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+// IsValid reports whether the id is not all zeros
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+// SpanID identifies a single span inside a trace
+type SpanID [8]byte
+
+// String returns the hex representation of the id
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// IsValid reports whether the id is not all zeros
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+// SpanContext is the part of a span being propagated to children and remote services
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid reports whether the span context carries both ids
+func (s SpanContext) IsValid() bool { return s.TraceID.IsValid() && s.SpanID.IsValid() }
+
+// Attribute is a key value pair attached to a span
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// SpanData is the finished span handed to exporters
+type SpanData struct {
+	SpanContext
+	Parent     SpanID
+	Name       string
+	Start      time.Time
+	End        time.Time
+	Attributes []Attribute
+	Err        string
+}
+
+// Span records a single operation. A nil span is valid and records nothing
+type Span struct {
+	tracer *Tracer
+
+	m     sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// Context of the span for propagation
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.SpanContext
+}
+
+// SetAttribute on the span. Values should be strings, bools or numbers
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.data.Attributes = append(s.data.Attributes, Attribute{key, value})
+}
+
+// RecordError marks the span as failed with err. Nil errors are ignored
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.data.Err = err.Error()
+}
+
+// End the span and hand it to the exporter. Calling End more than once has no effect
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.m.Lock()
+	if s.ended {
+		s.m.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.m.Unlock()
+	if data.Sampled {
+		s.tracer.export(data)
+	}
+}
+
+// Exporter receives finished spans
+type Exporter interface {
+	Export(context.Context, []SpanData) error
+}
+
+// Tracer creates spans and passes them to its exporter once they end
+type Tracer struct {
+	m        sync.RWMutex
+	exporter Exporter
+}
+
+// Default tracer used by the package level functions. It does not export anything until an exporter is set
+var Default = &Tracer{}
+
+// SetExporter replaces the exporter of the tracer. Passing nil disables exporting
+func (t *Tracer) SetExporter(e Exporter) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	t.exporter = e
+}
+
+func (t *Tracer) export(data SpanData) {
+	t.m.RLock()
+	e := t.exporter
+	t.m.RUnlock()
+	if e != nil {
+		e.Export(context.Background(), []SpanData{data})
+	}
+}
+
+// Start a new span named name as child of the span or remote span context found in ctx
+// The returned context carries the new span
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	parent := SpanContextFrom(ctx)
+	s := &Span{tracer: t}
+	s.data.Name = name
+	s.data.Start = time.Now()
+	s.data.SpanID = newSpanID()
+	if parent.IsValid() {
+		s.data.TraceID = parent.TraceID
+		s.data.Parent = parent.SpanID
+		s.data.Sampled = parent.Sampled
+	} else {
+		s.data.TraceID = newTraceID()
+		s.data.Sampled = true
+	}
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// Start a new span using the Default tracer
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return Default.Start(ctx, name)
+}
+
+// spanKey holds either the current *Span or a remote SpanContext
+type spanKey struct{}
+
+// FromContext returns the span carried by ctx or nil
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// WithSpanContext returns a context carrying sc as parent for new spans
+// It takes precedence over any span already carried by ctx, e.g. to continue the trace of a request in a background loop
+func WithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanKey{}, sc)
+}
+
+// SpanContextFrom returns the context of the current span or remote span context carried by ctx
+func SpanContextFrom(ctx context.Context) SpanContext {
+	switch v := ctx.Value(spanKey{}).(type) {
+	case *Span:
+		return v.Context()
+	case SpanContext:
+		return v
+	}
+	return SpanContext{}
+}
+
+func newTraceID() (id TraceID) {
+	rand.Read(id[:])
+	return id
+}
+
+func newSpanID() (id SpanID) {
+	rand.Read(id[:])
+	return id
+}
@@ -0,0 +1,309 @@
+package trace_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/playnet-public/gorcon/pkg/trace"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestTrace(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Trace Suite")
+}
+
+type recorder struct {
+	m     sync.Mutex
+	spans []trace.SpanData
+}
+
+func (r *recorder) Export(ctx context.Context, spans []trace.SpanData) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+var _ = Describe("Trace", func() {
+	var (
+		ctx context.Context
+		t   *trace.Tracer
+		rec *recorder
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		rec = &recorder{}
+		t = &trace.Tracer{}
+		t.SetExporter(rec)
+	})
+
+	Describe("Start", func() {
+		It("does start new traces", func() {
+			_, s := t.Start(ctx, "root")
+			Expect(s.Context().IsValid()).To(BeTrue())
+			Expect(s.Context().Sampled).To(BeTrue())
+		})
+		It("does create children of spans in ctx", func() {
+			ctx, parent := t.Start(ctx, "parent")
+			_, child := t.Start(ctx, "child")
+			child.End()
+			parent.End()
+			Expect(rec.spans).To(HaveLen(2))
+			Expect(rec.spans[0].Name).To(Equal("child"))
+			Expect(rec.spans[0].TraceID).To(Equal(parent.Context().TraceID))
+			Expect(rec.spans[0].Parent).To(Equal(parent.Context().SpanID))
+			Expect(rec.spans[1].Parent.IsValid()).To(BeFalse())
+		})
+		It("does prefer span contexts over spans in ctx", func() {
+			ctx, _ := t.Start(ctx, "current")
+			remote := trace.SpanContext{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{2}, Sampled: true}
+			_, s := t.Start(trace.WithSpanContext(ctx, remote), "child")
+			s.End()
+			Expect(rec.spans[0].TraceID).To(Equal(remote.TraceID))
+			Expect(rec.spans[0].Parent).To(Equal(remote.SpanID))
+		})
+		It("does not export unsampled spans", func() {
+			remote := trace.SpanContext{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{2}}
+			_, s := t.Start(trace.WithSpanContext(ctx, remote), "child")
+			s.End()
+			Expect(rec.spans).To(BeEmpty())
+		})
+	})
+
+	Describe("Span", func() {
+		It("does record attributes and errors", func() {
+			_, s := t.Start(ctx, "test")
+			s.SetAttribute("key", "value")
+			s.RecordError(errors.New("failed"))
+			s.End()
+			Expect(rec.spans[0].Attributes).To(ConsistOf(trace.Attribute{Key: "key", Value: "value"}))
+			Expect(rec.spans[0].Err).To(Equal("failed"))
+			Expect(rec.spans[0].End).NotTo(BeTemporally("<", rec.spans[0].Start))
+		})
+		It("does export only once", func() {
+			_, s := t.Start(ctx, "test")
+			s.End()
+			s.End()
+			Expect(rec.spans).To(HaveLen(1))
+		})
+		It("does allow nil spans", func() {
+			var s *trace.Span
+			s.SetAttribute("key", "value")
+			s.RecordError(errors.New("failed"))
+			s.End()
+			Expect(s.Context().IsValid()).To(BeFalse())
+		})
+	})
+
+	Describe("Propagation", func() {
+		It("does round trip span contexts", func() {
+			ctx, s := t.Start(ctx, "test")
+			h := http.Header{}
+			trace.Inject(ctx, h)
+			Expect(h.Get(trace.Header)).To(Equal(trace.Format(s.Context())))
+			Expect(trace.SpanContextFrom(trace.Extract(context.Background(), h))).To(Equal(s.Context()))
+		})
+		It("does parse traceparent headers", func() {
+			sc, ok := trace.Parse("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+			Expect(ok).To(BeTrue())
+			Expect(sc.TraceID.String()).To(Equal("4bf92f3577b34da6a3ce929d0e0e4736"))
+			Expect(sc.SpanID.String()).To(Equal("00f067aa0ba902b7"))
+			Expect(sc.Sampled).To(BeTrue())
+		})
+		It("does reject invalid headers", func() {
+			for _, v := range []string{
+				"",
+				"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+				"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+				"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01",
+				"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			} {
+				_, ok := trace.Parse(v)
+				Expect(ok).To(BeFalse(), v)
+			}
+		})
+		It("does leave ctx without header unchanged", func() {
+			Expect(trace.Extract(ctx, http.Header{})).To(Equal(ctx))
+		})
+	})
+
+	Describe("Middleware", func() {
+		It("does continue incoming traces", func() {
+			trace.Default.SetExporter(rec)
+			defer trace.Default.SetExporter(nil)
+			var inner trace.SpanContext
+			h := trace.Middleware("api", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				inner = trace.SpanContextFrom(r.Context())
+				w.WriteHeader(http.StatusTeapot)
+			}))
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set(trace.Header, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			Expect(rec.spans).To(HaveLen(1))
+			Expect(rec.spans[0].Name).To(Equal("api"))
+			Expect(rec.spans[0].Parent.String()).To(Equal("00f067aa0ba902b7"))
+			Expect(inner.SpanID).To(Equal(rec.spans[0].SpanID))
+			Expect(rec.spans[0].Attributes).To(ContainElement(trace.Attribute{Key: "http.status_code", Value: http.StatusTeapot}))
+		})
+	})
+
+	Describe("Stdout", func() {
+		It("does write one json object per span", func() {
+			var buf bytes.Buffer
+			t.SetExporter(trace.NewStdout(&buf))
+			ctx, parent := t.Start(ctx, "parent")
+			_, child := t.Start(ctx, "child")
+			child.SetAttribute("key", "value")
+			child.End()
+			parent.End()
+			dec := json.NewDecoder(&buf)
+			var out map[string]interface{}
+			Expect(dec.Decode(&out)).To(BeNil())
+			Expect(out["name"]).To(Equal("child"))
+			Expect(out["parentSpanId"]).To(Equal(parent.Context().SpanID.String()))
+			Expect(out["attributes"]).To(HaveKeyWithValue("key", "value"))
+			Expect(dec.Decode(&out)).To(BeNil())
+			Expect(out["name"]).To(Equal("parent"))
+		})
+	})
+
+	Describe("OTLP", func() {
+		var (
+			srv    *httptest.Server
+			bodies chan []byte
+			status int
+		)
+
+		BeforeEach(func() {
+			bodies = make(chan []byte, 10)
+			status = http.StatusOK
+			srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := ioutil.ReadAll(r.Body)
+				bodies <- b
+				w.WriteHeader(status)
+			}))
+		})
+
+		AfterEach(func() {
+			srv.Close()
+		})
+
+		It("does batch spans until flushed", func() {
+			exp := trace.NewOTLP(srv.URL, "test")
+			t.SetExporter(exp)
+			_, s := t.Start(ctx, "test")
+			s.SetAttribute("count", 3)
+			s.RecordError(errors.New("failed"))
+			s.End()
+			Expect(bodies).To(BeEmpty())
+			Expect(exp.Flush(ctx)).To(BeNil())
+
+			var req struct {
+				ResourceSpans []struct {
+					Resource struct {
+						Attributes []struct {
+							Key   string
+							Value map[string]interface{}
+						}
+					}
+					ScopeSpans []struct {
+						Spans []struct {
+							TraceID    string
+							SpanID     string
+							Name       string
+							Attributes []struct {
+								Key   string
+								Value map[string]interface{}
+							}
+							Status struct {
+								Code    int
+								Message string
+							}
+						}
+					}
+				}
+			}
+			Expect(json.Unmarshal(<-bodies, &req)).To(BeNil())
+			Expect(req.ResourceSpans[0].Resource.Attributes[0].Value["stringValue"]).To(Equal("test"))
+			span := req.ResourceSpans[0].ScopeSpans[0].Spans[0]
+			Expect(span.Name).To(Equal("test"))
+			Expect(span.TraceID).To(Equal(s.Context().TraceID.String()))
+			Expect(span.Attributes[0].Value["intValue"]).To(Equal("3"))
+			Expect(span.Status.Code).To(Equal(2))
+			Expect(span.Status.Message).To(Equal("failed"))
+		})
+		It("does send full batches", func() {
+			exp := trace.NewOTLP(srv.URL, "test")
+			exp.BatchSize = 2
+			runCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			go exp.Run(runCtx)
+			t.SetExporter(exp)
+			for i := 0; i < 2; i++ {
+				_, s := t.Start(ctx, "test")
+				s.End()
+			}
+			Eventually(bodies).Should(HaveLen(1))
+		})
+		It("does not block ending spans while the collector is slow", func() {
+			block := make(chan struct{})
+			slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-block
+			}))
+			defer slow.Close()
+			defer close(block)
+			exp := trace.NewOTLP(slow.URL, "test")
+			exp.BatchSize = 1
+			runCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			go exp.Run(runCtx)
+			t.SetExporter(exp)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 0; i < 3; i++ {
+					_, s := t.Start(ctx, "test")
+					s.End()
+				}
+			}()
+			Eventually(done).Should(BeClosed())
+		})
+		It("does drop spans exceeding the queue", func() {
+			exp := trace.NewOTLP(srv.URL, "test")
+			exp.QueueSize = 1
+			Expect(exp.Export(ctx, []trace.SpanData{{Name: "first"}, {Name: "second"}})).NotTo(BeNil())
+			Expect(exp.Flush(ctx)).To(BeNil())
+			Expect(string(<-bodies)).To(ContainSubstring("first"))
+			Expect(bodies).To(BeEmpty())
+		})
+		It("does send batches of BatchSize when flushing", func() {
+			exp := trace.NewOTLP(srv.URL, "test")
+			exp.BatchSize = 2
+			exp.Export(ctx, []trace.SpanData{{Name: "test"}, {Name: "test"}, {Name: "test"}})
+			Expect(exp.Flush(ctx)).To(BeNil())
+			Expect(bodies).To(HaveLen(2))
+		})
+		It("does not post empty batches", func() {
+			exp := trace.NewOTLP(srv.URL, "test")
+			Expect(exp.Flush(ctx)).To(BeNil())
+			Expect(bodies).To(BeEmpty())
+		})
+		It("does return error on failed posts", func() {
+			status = http.StatusInternalServerError
+			exp := trace.NewOTLP(srv.URL, "test")
+			exp.Export(ctx, []trace.SpanData{{Name: "test"}})
+			Expect(exp.Flush(ctx)).NotTo(BeNil())
+		})
+	})
+})
@@ -0,0 +1,293 @@
+package watcher
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// Crash described in the crash.json of a bundle
+type Crash struct {
+	Name     string    `json:"name"`
+	Time     time.Time `json:"time"`
+	Error    string    `json:"error"`
+	ExitCode string    `json:"exit_code,omitempty"`
+	Signal   string    `json:"signal,omitempty"`
+}
+
+// CrashCollector bundles the evidence of processes exiting with failure into tar.gz archives
+// Bundles contain the crash, the last lines of output, the current logs and the dumps written since the previous crash
+type CrashCollector struct {
+	Watcher *Watcher
+	// Dir receiving the bundles
+	Dir string
+	// Lines of stdout and stderr kept for bundles
+	Lines int
+	// Logs are glob patterns of which the newest match is added, e.g. the RPT and BattlEye logs
+	Logs []string
+	// Dumps are glob patterns of which all matches written since the previous crash are added, e.g. *.mdmp and *.bidmp
+	Dumps []string
+	// Keep is the number of bundles retained
+	Keep int
+
+	m      sync.Mutex
+	output []string
+	next   int
+	since  time.Time
+}
+
+// NewCrashCollector writing bundles of w to dir
+// The logs of w are included with the dumps written next to them
+func NewCrashCollector(w *Watcher, dir string) *CrashCollector {
+	c := &CrashCollector{
+		Watcher: w,
+		Dir:     dir,
+		Lines:   200,
+		Keep:    10,
+		since:   time.Now(),
+	}
+	dirs := make(map[string]bool)
+	for _, l := range w.Logs {
+		c.Logs = append(c.Logs, l.Pattern)
+		d := filepath.Dir(l.Pattern)
+		if !dirs[d] {
+			dirs[d] = true
+			c.Dumps = append(c.Dumps, filepath.Join(d, "*.mdmp"), filepath.Join(d, "*.bidmp"))
+		}
+	}
+	return c
+}
+
+// Run the collector keeping the output and bundling crashes received on in until ctx is closed or in gets closed
+// in should receive the events of the watcher
+func (c *CrashCollector) Run(ctx context.Context, in <-chan event.Event) error {
+	for {
+		select {
+		case <-ctx.Done():
+			log.From(ctx).Info("stopping crash collector", zap.Error(ctx.Err()))
+			return ctx.Err()
+		case e, ok := <-in:
+			if !ok {
+				log.From(ctx).Info("stopping crash collector")
+				return event.ErrInputClosed
+			}
+			if _, err := c.Handle(ctx, e); err != nil {
+				log.From(ctx).Error("collecting crash", zap.String("name", c.Watcher.Name), zap.Error(err))
+			}
+		}
+	}
+}
+
+// Handle a single event by keeping output lines and bundling crashes with an exit code or signal
+// Returns the path of the bundle if one got written
+func (c *CrashCollector) Handle(ctx context.Context, e event.Event) (string, error) {
+	r, ok := e.(*event.Record)
+	if !ok || r.Source.Component != event.ComponentWatcher {
+		return "", nil
+	}
+	switch r.Type {
+	case TypeStdOut, TypeStdErr:
+		c.keep(string(r.Type) + ": " + r.Payload)
+	case TypeCrash:
+		// crashes without exit code or signal are the watcher stopping, not the process failing
+		if r.Attributes["crash.exit_code"] == "" && r.Attributes["crash.signal"] == "" {
+			return "", nil
+		}
+		return c.Collect(ctx, Crash{
+			Name:     c.Watcher.Name,
+			Time:     r.Time,
+			Error:    r.Payload,
+			ExitCode: r.Attributes["crash.exit_code"],
+			Signal:   r.Attributes["crash.signal"],
+		})
+	}
+	return "", nil
+}
+
+// keep line of output, dropping the oldest once Lines are kept
+func (c *CrashCollector) keep(line string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.Lines <= 0 {
+		return
+	}
+	if len(c.output) < c.Lines {
+		c.output = append(c.output, line)
+		return
+	}
+	c.output[c.next] = line
+	c.next = (c.next + 1) % c.Lines
+}
+
+// Collect a bundle of crash, emitting an event pointing at it. Returns the path of the bundle
+func (c *CrashCollector) Collect(ctx context.Context, crash Crash) (string, error) {
+	c.m.Lock()
+	output := append(append([]string(nil), c.output[c.next:]...), c.output[:c.next]...)
+	c.output, c.next = nil, 0
+	since := c.since
+	c.since = time.Now()
+	c.m.Unlock()
+
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		crashBundles.With(c.Watcher.Name, "error").Inc()
+		return "", errors.Wrap(err, "creating bundle dir")
+	}
+	path := filepath.Join(c.Dir, c.prefix()+crash.Time.UTC().Format(bundleTime)+bundleExt)
+	if err := c.write(path, crash, output, since); err != nil {
+		crashBundles.With(c.Watcher.Name, "error").Inc()
+		return "", err
+	}
+	crashBundles.With(c.Watcher.Name, "ok").Inc()
+	log.From(ctx).Info("collected crash bundle", zap.String("name", c.Watcher.Name), zap.String("path", path))
+	c.Watcher.emitRecord(ctx, c.Watcher.event(TypeCrashBundle, path).
+		Set("crash.bundle", path).
+		Set("crash.exit_code", crash.ExitCode).
+		Set("crash.signal", crash.Signal))
+
+	if err := c.retain(); err != nil {
+		log.From(ctx).Error("removing old crash bundles", zap.String("name", c.Watcher.Name), zap.Error(err))
+	}
+	return path, nil
+}
+
+// write the bundle to path through a temporary file, so incomplete bundles never show up
+func (c *CrashCollector) write(path string, crash Crash, output []string, since time.Time) error {
+	tmp := filepath.Join(c.Dir, "."+filepath.Base(path)+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return errors.Wrap(err, "creating bundle")
+	}
+	defer os.Remove(tmp)
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(crash, "", "  ")
+	if err != nil {
+		f.Close()
+		return errors.Wrap(err, "encoding crash")
+	}
+	err = addData(tw, "crash.json", data, crash.Time)
+	if err == nil {
+		err = addData(tw, "output.log", []byte(strings.Join(output, "\n")+"\n"), crash.Time)
+	}
+	for _, pattern := range c.Logs {
+		if err != nil {
+			break
+		}
+		if file, _ := NewTailer(pattern).newest(); file != "" {
+			err = addFile(tw, "logs/"+filepath.Base(file), file)
+		}
+	}
+	for _, pattern := range c.Dumps {
+		if err != nil {
+			break
+		}
+		matches, _ := filepath.Glob(pattern)
+		for _, file := range matches {
+			info, statErr := os.Stat(file)
+			if statErr != nil || info.ModTime().Before(since) {
+				continue
+			}
+			if err = addFile(tw, "dumps/"+filepath.Base(file), file); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "writing bundle")
+	}
+	return errors.Wrap(os.Rename(tmp, path), "writing bundle")
+}
+
+const (
+	// bundleTime is the layout of the time of crashes in the names of bundles
+	bundleTime = "20060102-150405.000"
+	bundleExt  = ".tar.gz"
+)
+
+// retain the newest Keep bundles, removing older ones
+func (c *CrashCollector) retain() error {
+	if c.Keep <= 0 {
+		return nil
+	}
+	matches, err := filepath.Glob(filepath.Join(c.Dir, c.prefix()+"[0-9]*"+bundleExt))
+	if err != nil {
+		return err
+	}
+	// names of other watchers may start with the prefix as well, e.g. server-hc, so their bundles are told apart by
+	// the timestamp following it
+	var bundles []string
+	for _, m := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(m), c.prefix()), bundleExt)
+		if _, err := time.Parse(bundleTime, stamp); err == nil {
+			bundles = append(bundles, m)
+		}
+	}
+	// the timestamps in the names sort bundles chronologically
+	sort.Strings(bundles)
+	for len(bundles) > c.Keep {
+		if err := os.Remove(bundles[0]); err != nil {
+			return err
+		}
+		bundles = bundles[1:]
+	}
+	return nil
+}
+
+// prefix of the bundles of the watcher. Names of watchers are often paths of executables
+func (c *CrashCollector) prefix() string {
+	name := filepath.Base(c.Watcher.Name)
+	if name == "." || name == string(filepath.Separator) {
+		name = "process"
+	}
+	return name + "-"
+}
+
+func addData(tw *tar.Writer, name string, data []byte, mod time.Time) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: mod}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// addFile to the archive. Files still being written are added with the size they had when opened
+func addFile(tw *tar.Writer, name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: info.Size(), ModTime: info.ModTime()}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, io.LimitReader(f, info.Size()))
+	return err
+}
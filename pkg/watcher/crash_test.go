@@ -0,0 +1,168 @@
+package watcher_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+	"github.com/playnet-public/gorcon/pkg/watcher"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/seibert-media/golibs/log"
+)
+
+var _ = Describe("CrashCollector", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		tmp    string
+		c      *watcher.CrashCollector
+	)
+
+	write := func(name, data string, mod time.Time) {
+		path := filepath.Join(tmp, "profile", name)
+		Expect(ioutil.WriteFile(path, []byte(data), 0644)).To(BeNil())
+		Expect(os.Chtimes(path, mod, mod)).To(BeNil())
+	}
+	record := func(kind event.Kind, data string) *event.Record {
+		return event.New(event.Source{Server: "server", Component: event.ComponentWatcher}, kind, data)
+	}
+	// untar returns the contents of the bundle at path by name
+	untar := func(path string) map[string]string {
+		f, err := os.Open(path)
+		Expect(err).To(BeNil())
+		defer f.Close()
+		gz, err := gzip.NewReader(f)
+		Expect(err).To(BeNil())
+		tr := tar.NewReader(gz)
+		files := make(map[string]string)
+		for {
+			h, err := tr.Next()
+			if err != nil {
+				break
+			}
+			data, err := ioutil.ReadAll(tr)
+			Expect(err).To(BeNil())
+			files[h.Name] = string(data)
+		}
+		return files
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "crash")
+		Expect(err).To(BeNil())
+		Expect(os.Mkdir(filepath.Join(tmp, "profile"), 0755)).To(BeNil())
+		ctx, cancel = context.WithCancel(log.WithLogger(context.Background(), log.NewNop()))
+		w := watcher.NewWatcher(ctx, "/opt/arma3/arma3server")
+		w.Logs = []watcher.LogFile{{Pattern: filepath.Join(tmp, "profile", "*.rpt"), Format: watcher.FormatRPT}}
+		c = watcher.NewCrashCollector(w, filepath.Join(tmp, "crashes"))
+	})
+	AfterEach(func() {
+		cancel()
+		os.RemoveAll(tmp)
+	})
+
+	It("does bundle the evidence of crashes", func() {
+		old := time.Now().Add(-time.Hour)
+		write("arma3server_old.rpt", "old", old)
+		write("arma3server_new.rpt", "new", time.Now())
+		write("old.mdmp", "old dump", old)
+		write("arma3server.mdmp", "dump", time.Now())
+		write("arma3server.bidmp", "bidump", time.Now())
+		c.Lines = 2
+		for _, l := range []string{"first", "second", "third"} {
+			_, err := c.Handle(ctx, record(watcher.TypeStdOut, l))
+			Expect(err).To(BeNil())
+		}
+		_, err := c.Handle(ctx, record(watcher.TypeStdErr, "fatal"))
+		Expect(err).To(BeNil())
+
+		path, err := c.Handle(ctx, record(watcher.TypeCrash, "exit status 1").Set("crash.exit_code", "1"))
+		Expect(err).To(BeNil())
+		Expect(filepath.Dir(path)).To(Equal(filepath.Join(tmp, "crashes")))
+		Expect(filepath.Base(path)).To(HavePrefix("arma3server-"))
+
+		files := untar(path)
+		Expect(files).To(HaveLen(5))
+		Expect(files["crash.json"]).To(ContainSubstring(`"exit_code": "1"`))
+		Expect(files["output.log"]).To(Equal("StdOut: third\nStdErr: fatal\n"))
+		Expect(files["logs/arma3server_new.rpt"]).To(Equal("new"))
+		Expect(files["dumps/arma3server.mdmp"]).To(Equal("dump"))
+		Expect(files["dumps/arma3server.bidmp"]).To(Equal("bidump"))
+	})
+	It("does bundle the output of crashed processes", func() {
+		// the process crashes once and exits cleanly whenever it gets revived, even once tmp got removed
+		marker := filepath.Join(tmp, "crashed")
+		w := watcher.NewWatcher(ctx, "server")
+		w.Process = watcher.NewOSProcess("sh", "-c",
+			"if [ -e "+marker+" ] || ! touch "+marker+"; then exit 0; fi; echo hello; echo fail >&2; sleep 0.2; exit 3")
+		c := watcher.NewCrashCollector(w, filepath.Join(tmp, "crashes"))
+		in := make(chan event.Event)
+		go c.Run(ctx, in)
+		// the broker runs once the watcher got started, so the collector subscribes before the first run
+		var once sync.Once
+		w.Prepare = func(ctx context.Context) error {
+			once.Do(func() { w.Subscribe(ctx, in) })
+			return nil
+		}
+		w.KeepAlive(ctx)
+		go w.Start(ctx)
+
+		var bundles []string
+		Eventually(func() []string {
+			bundles, _ = filepath.Glob(filepath.Join(tmp, "crashes", "*"))
+			return bundles
+		}).Should(HaveLen(1))
+		files := untar(bundles[0])
+		Expect(files["crash.json"]).To(ContainSubstring(`"exit_code": "3"`))
+		Expect(files["output.log"]).To(ContainSubstring("StdOut: hello\n"))
+		Expect(files["output.log"]).To(ContainSubstring("StdErr: fail\n"))
+	})
+	It("does not bundle processes being stopped", func() {
+		path, err := c.Handle(ctx, record(watcher.TypeCrash, "context canceled"))
+		Expect(err).To(BeNil())
+		Expect(path).To(BeEmpty())
+	})
+	It("does remove old bundles", func() {
+		c.Keep = 2
+		var paths []string
+		for i := 0; i < 3; i++ {
+			r := record(watcher.TypeCrash, "signal: killed").Set("crash.signal", "killed")
+			r.Time = time.Date(2026, 1, 1, 0, 0, i, 0, time.UTC)
+			path, err := c.Handle(ctx, r)
+			Expect(err).To(BeNil())
+			paths = append(paths, path)
+		}
+		bundles, err := filepath.Glob(filepath.Join(tmp, "crashes", "*"))
+		Expect(err).To(BeNil())
+		Expect(bundles).To(Equal(paths[1:]))
+	})
+	It("does not remove bundles of other watchers", func() {
+		Expect(os.MkdirAll(filepath.Join(tmp, "crashes"), 0755)).To(BeNil())
+		other := []string{
+			filepath.Join(tmp, "crashes", "arma3server-hc-20260101-000000.000.tar.gz"),
+			filepath.Join(tmp, "crashes", "arma3server-2-20260101-000000.000.tar.gz"),
+		}
+		for _, path := range other {
+			Expect(ioutil.WriteFile(path, nil, 0644)).To(BeNil())
+		}
+		c.Keep = 1
+		for i := 0; i < 2; i++ {
+			r := record(watcher.TypeCrash, "signal: killed").Set("crash.signal", "killed")
+			r.Time = time.Date(2026, 1, 1, 0, 0, i, 0, time.UTC)
+			_, err := c.Handle(ctx, r)
+			Expect(err).To(BeNil())
+		}
+		for _, path := range other {
+			Expect(path).To(BeAnExistingFile())
+		}
+	})
+})
@@ -1,25 +1,37 @@
 package watcher
 
-import "time"
+import "github.com/playnet-public/gorcon/pkg/event"
 
-// Event describes a log event emitted by the process
-// TODO(kwiesmueller): rework this to a new Event interface used by all broker dependents
-type Event struct {
-	timestamp time.Time
-	kind      string
-	payload   string
-}
-
-// Timestamp when the event occurred
-func (e Event) Timestamp() time.Time {
-	return e.timestamp
-}
-
-// Kind of the event
-func (e Event) Kind() string {
-	return e.kind
-}
-
-// Data of the real event
-func (e Event) Data() string { return e.payload }
+const (
+	// TypeStdOut identifies lines written to stdout by the process
+	TypeStdOut = event.KindStdOut
+	// TypeStdErr identifies lines written to stderr by the process
+	TypeStdErr = event.KindStdErr
+	// TypeCrash identifies events emitted when the process exited unexpectedly
+	TypeCrash = event.KindCrash
+	// TypeRestart identifies events emitted when the process gets revived by KeepAlive
+	TypeRestart = event.KindRestart
+	// TypeLog identifies lines read from log files of the process
+	TypeLog = event.KindLog
+	// TypeScriptError identifies script errors read from log files of the process
+	TypeScriptError = event.KindScriptError
+	// TypeMission identifies missions starting or ending
+	TypeMission = event.KindMission
+	// TypeUsage identifies resource usage samples of the process
+	TypeUsage = event.KindUsage
+	// TypeAlert identifies resource usage exceeding a threshold
+	TypeAlert = event.KindAlert
+	// TypeHang identifies the process being killed by the Watchdog
+	TypeHang = event.KindHang
+	// TypeReady identifies the process of a Group member being ready
+	TypeReady = event.KindReady
+	// TypeShutdown identifies the steps of a graceful shutdown of the process
+	TypeShutdown = event.KindShutdown
+	// TypeCrashBundle identifies crash bundles written by the CrashCollector
+	TypeCrashBundle = event.KindCrashBundle
+)
 
+// Event describes a log event emitted by the process
+//
+// Deprecated: use event.Record
+type Event = event.Record
@@ -0,0 +1,302 @@
+package watcher
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/rcon"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// Member of a Group whose process is supervised using its Watcher
+type Member struct {
+	Watcher *Watcher
+	// After lists the names of the members which have to be ready before the process starts
+	After []string
+	// Linked processes are restarted whenever one of their After members exits, e.g. headless clients of a server
+	Linked bool
+	// Ready reports whether the started process is up, e.g. by probing rcon. Processes are ready once started if nil
+	Ready func(context.Context) error
+}
+
+// Group supervises processes depending on each other, e.g. a server with its headless clients and extensions
+// Every process is started once its dependencies are ready and restarted after it exited
+type Group struct {
+	Members []*Member
+	// ReadyInterval in which Ready of started processes is polled
+	ReadyInterval time.Duration
+	// RestartDelay before exited processes are started again
+	RestartDelay time.Duration
+
+	m       sync.Mutex
+	changed chan struct{}
+	states  map[string]*memberState
+}
+
+// memberState of a member
+type memberState struct {
+	ready bool
+	// exits counts the exits of the process, telling linked members to restart
+	exits int
+}
+
+// NewGroup supervising members
+func NewGroup(members ...*Member) *Group {
+	return &Group{
+		Members:       members,
+		ReadyInterval: time.Second,
+		RestartDelay:  5 * time.Second,
+	}
+}
+
+// Order returns the members in the order they get started
+// Members depending on unknown members or on each other are returned as error
+func (g *Group) Order() ([]*Member, error) {
+	byName := make(map[string]*Member)
+	for _, m := range g.Members {
+		if _, ok := byName[m.Watcher.Name]; ok {
+			return nil, errors.Errorf("duplicate member %s", m.Watcher.Name)
+		}
+		byName[m.Watcher.Name] = m
+	}
+
+	var order []*Member
+	visited := make(map[string]bool)
+	var visit func(m *Member, path []string) error
+	visit = func(m *Member, path []string) error {
+		name := m.Watcher.Name
+		for _, p := range path {
+			if p == name {
+				return errors.Errorf("dependency cycle %v", append(path, name))
+			}
+		}
+		if visited[name] {
+			return nil
+		}
+		for _, dep := range m.After {
+			d, ok := byName[dep]
+			if !ok {
+				return errors.Errorf("%s depends on unknown member %s", name, dep)
+			}
+			if err := visit(d, append(path, name)); err != nil {
+				return err
+			}
+		}
+		visited[name] = true
+		order = append(order, m)
+		return nil
+	}
+	for _, m := range g.Members {
+		if err := visit(m, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Ready reports whether the process of the member name is ready
+func (g *Group) Ready(name string) bool {
+	g.m.Lock()
+	defer g.m.Unlock()
+	s, ok := g.states[name]
+	return ok && s.ready
+}
+
+// Run the group starting the processes in order and restarting them until ctx is closed
+func (g *Group) Run(ctx context.Context) error {
+	order, err := g.Order()
+	if err != nil {
+		return err
+	}
+	g.m.Lock()
+	g.changed = make(chan struct{})
+	g.states = make(map[string]*memberState)
+	for _, m := range order {
+		g.states[m.Watcher.Name] = &memberState{}
+	}
+	g.m.Unlock()
+
+	var wg sync.WaitGroup
+	for _, m := range order {
+		m.Watcher.setup(ctx)
+		wg.Add(1)
+		go func(m *Member) {
+			defer wg.Done()
+			g.supervise(ctx, m)
+		}(m)
+	}
+	wg.Wait()
+	log.From(ctx).Info("stopping group", zap.Error(ctx.Err()))
+	return ctx.Err()
+}
+
+// supervise the process of m until ctx is closed
+func (g *Group) supervise(ctx context.Context, m *Member) {
+	w := m.Watcher
+	for starts := 0; ; starts++ {
+		var exits int
+		ok := g.wait(ctx, func() bool {
+			exits = 0
+			for _, dep := range m.After {
+				if !g.states[dep].ready {
+					return false
+				}
+				exits += g.states[dep].exits
+			}
+			return true
+		})
+		if !ok {
+			return
+		}
+		if starts > 0 {
+			processRestarts.With(w.Name).Inc()
+			w.emit(ctx, TypeRestart, "")
+		}
+
+		g.m.Lock()
+		gen := g.states[w.Name].exits
+		g.m.Unlock()
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		log.From(ctx).Info("starting process", zap.String("name", w.Name))
+		go func() { done <- w.run(ctx) }()
+		go g.ready(runCtx, m, gen)
+		linked := make(chan string, 1)
+		if m.Linked {
+			go g.link(runCtx, m, exits, linked)
+		}
+
+		err := <-done
+		cancel()
+		g.update(func() {
+			s := g.states[w.Name]
+			s.ready = false
+			s.exits++
+		})
+		processReady.With(w.Name).Set(0)
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case <-linked:
+			// the process got stopped and restarts as soon as its dependencies are ready again
+			continue
+		default:
+		}
+		log.From(ctx).Info("process exited", zap.String("name", w.Name), zap.Error(err))
+		if err != nil {
+			w.crash(ctx, err)
+		}
+		select {
+		case <-time.After(g.RestartDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// ready polls Ready of m until the process is up, marking it ready unless it exited since it got started as gen
+func (g *Group) ready(ctx context.Context, m *Member, gen int) {
+	w := m.Watcher
+	if m.Ready != nil {
+		tick := time.NewTicker(g.ReadyInterval)
+		defer tick.Stop()
+		for {
+			err := m.Ready(ctx)
+			if err == nil {
+				break
+			}
+			log.From(ctx).Debug("process not ready", zap.String("name", w.Name), zap.Error(err))
+			select {
+			case <-tick.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+	ready := false
+	g.update(func() {
+		s := g.states[w.Name]
+		if s.exits == gen {
+			s.ready, ready = true, true
+		}
+	})
+	if !ready {
+		return
+	}
+	log.From(ctx).Info("process ready", zap.String("name", w.Name))
+	processReady.With(w.Name).Set(1)
+	w.emit(ctx, TypeReady, "")
+}
+
+// link stops the process of m once one of its dependencies exits, which had exited exits times when it got started
+func (g *Group) link(ctx context.Context, m *Member, exits int, linked chan<- string) {
+	var dep string
+	ok := g.wait(ctx, func() bool {
+		n := 0
+		for _, d := range m.After {
+			n += g.states[d].exits
+			if n > exits && dep == "" {
+				dep = d
+			}
+		}
+		return n != exits
+	})
+	if !ok {
+		return
+	}
+	linked <- dep
+	log.From(ctx).Info("restarting linked process", zap.String("name", m.Watcher.Name), zap.String("dependency", dep))
+	if err := m.Watcher.Process.Stop(); err != nil {
+		log.From(ctx).Error("stopping linked process", zap.String("name", m.Watcher.Name), zap.Error(err))
+	}
+}
+
+// wait until cond holds or ctx is closed. cond is called holding the lock
+func (g *Group) wait(ctx context.Context, cond func() bool) bool {
+	for {
+		g.m.Lock()
+		ok := cond()
+		changed := g.changed
+		g.m.Unlock()
+		if ok {
+			return true
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// update the states using fn, waking up everyone waiting for a change
+func (g *Group) update(fn func()) {
+	g.m.Lock()
+	defer g.m.Unlock()
+	fn()
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
+
+// RconReady returns a Ready func probing the server through w with command, e.g. "players"
+func RconReady(w rcon.Writer, command string, timeout time.Duration) func(context.Context) error {
+	return func(ctx context.Context) error {
+		trm, err := w.Write(rcon.WithPriority(ctx, rcon.Scheduled), command)
+		if err != nil {
+			return err
+		}
+		select {
+		case <-trm.Done():
+			return nil
+		case <-time.After(timeout):
+			return errors.New("probe timed out")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package watcher_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+	"github.com/playnet-public/gorcon/pkg/mocks"
+	"github.com/playnet-public/gorcon/pkg/watcher"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/seibert-media/golibs/log"
+)
+
+var _ = Describe("Group", func() {
+	// member with a fake process running until it gets stopped or exit receives
+	member := func(ctx context.Context, name string, after ...string) (m *watcher.Member, p *mocks.Process, exit chan struct{}) {
+		p = &mocks.Process{}
+		exit = make(chan struct{}, 1)
+		p.RunStub = func() error {
+			<-exit
+			return nil
+		}
+		p.StopStub = func() error {
+			select {
+			case exit <- struct{}{}:
+			default:
+			}
+			return nil
+		}
+		w := watcher.NewWatcher(ctx, name)
+		w.Process = p
+		return &watcher.Member{Watcher: w, After: after}, p, exit
+	}
+	// run g until the returned cancel func gets called
+	run := func(ctx context.Context, g *watcher.Group) func() {
+		ctx, cancel := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() { done <- g.Run(ctx) }()
+		return func() {
+			cancel()
+			Eventually(done).Should(Receive(Equal(context.Canceled)))
+		}
+	}
+
+	var ctx context.Context
+	BeforeEach(func() {
+		ctx = log.WithLogger(context.Background(), log.NewNop())
+	})
+
+	Describe("Order", func() {
+		It("does order members by their dependencies", func() {
+			hc, _, _ := member(ctx, "hc", "server")
+			server, _, _ := member(ctx, "server", "extdb")
+			extdb, _, _ := member(ctx, "extdb")
+			g := watcher.NewGroup(hc, server, extdb)
+
+			order, err := g.Order()
+			Expect(err).To(BeNil())
+			Expect(order).To(Equal([]*watcher.Member{extdb, server, hc}))
+		})
+		It("does return error for invalid dependencies", func() {
+			a, _, _ := member(ctx, "a", "b")
+			b, _, _ := member(ctx, "b", "a")
+			_, err := watcher.NewGroup(a, b).Order()
+			Expect(err).NotTo(BeNil())
+
+			c, _, _ := member(ctx, "c", "missing")
+			_, err = watcher.NewGroup(c).Order()
+			Expect(err).NotTo(BeNil())
+			Expect(watcher.NewGroup(c).Run(ctx)).NotTo(BeNil())
+		})
+	})
+
+	Describe("Run", func() {
+		It("does start members once their dependencies are ready", func() {
+			server, sp, _ := member(ctx, "server")
+			var up int32
+			server.Ready = func(context.Context) error {
+				if atomic.LoadInt32(&up) == 0 {
+					return errors.New("rcon not reachable")
+				}
+				return nil
+			}
+			hc, hp, _ := member(ctx, "hc", "server")
+			g := watcher.NewGroup(server, hc)
+			g.ReadyInterval = 5 * time.Millisecond
+			stop := run(ctx, g)
+			defer stop()
+
+			Eventually(sp.RunCallCount).Should(Equal(1))
+			Consistently(hp.RunCallCount, 50*time.Millisecond).Should(Equal(0))
+			Expect(g.Ready("server")).To(BeFalse())
+
+			atomic.StoreInt32(&up, 1)
+			Eventually(hp.RunCallCount).Should(Equal(1))
+			Expect(g.Ready("server")).To(BeTrue())
+			Eventually(func() bool { return g.Ready("hc") }).Should(BeTrue())
+		})
+		It("does restart linked members with their dependencies", func() {
+			server, sp, exit := member(ctx, "server")
+			hc, hp, _ := member(ctx, "hc", "server")
+			hc.Linked = true
+			g := watcher.NewGroup(server, hc)
+			g.RestartDelay = 10 * time.Millisecond
+			stop := run(ctx, g)
+			defer stop()
+
+			Eventually(hp.RunCallCount).Should(Equal(1))
+			exit <- struct{}{}
+			Eventually(hp.StopCallCount).Should(Equal(1))
+			Eventually(sp.RunCallCount).Should(Equal(2))
+			Eventually(hp.RunCallCount).Should(Equal(2))
+		})
+		It("does not restart members which are not linked", func() {
+			extdb, ep, exit := member(ctx, "extdb")
+			server, sp, _ := member(ctx, "server", "extdb")
+			g := watcher.NewGroup(extdb, server)
+			g.RestartDelay = 10 * time.Millisecond
+			stop := run(ctx, g)
+			defer stop()
+
+			Eventually(sp.RunCallCount).Should(Equal(1))
+			exit <- struct{}{}
+			Eventually(ep.RunCallCount).Should(Equal(2))
+			Consistently(sp.StopCallCount, 50*time.Millisecond).Should(Equal(0))
+			Expect(sp.RunCallCount()).To(Equal(1))
+		})
+		It("does not emit crashes for processes exiting cleanly", func() {
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			server, sp, exit := member(ctx, "server")
+			g := watcher.NewGroup(server)
+			g.RestartDelay = 10 * time.Millisecond
+			stop := run(ctx, g)
+			defer stop()
+			events := make(chan event.Event, 8)
+			server.Watcher.Subscribe(ctx, events)
+
+			Eventually(sp.RunCallCount).Should(Equal(1))
+			exit <- struct{}{}
+			var kinds []string
+			Eventually(func() []string {
+				select {
+				case e := <-events:
+					kinds = append(kinds, e.Kind())
+				default:
+				}
+				return kinds
+			}).Should(ContainElement(string(watcher.TypeRestart)))
+			Expect(kinds).NotTo(ContainElement(string(watcher.TypeCrash)))
+		})
+	})
+})
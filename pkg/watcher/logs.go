@@ -0,0 +1,248 @@
+package watcher
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// LogFormat of a log file written by the process
+type LogFormat int
+
+const (
+	// FormatRPT of arma3server report files
+	FormatRPT LogFormat = iota
+	// FormatBattlEye of BattlEye log files
+	FormatBattlEye
+)
+
+// Severities of log lines
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// LogFile written by the process. The newest file matching Pattern is followed across rotations
+type LogFile struct {
+	Pattern string
+	Format  LogFormat
+}
+
+// TailLog follows l emitting structured events for each line until ctx is closed
+func (w *Watcher) TailLog(ctx context.Context, l LogFile) error {
+	p := newLogParser(l.Format, event.Source{Server: w.Name, Component: event.ComponentWatcher})
+	emit := func(records []*event.Record) {
+		for _, r := range records {
+			watcherEvents.With(w.Name, r.Kind()).Inc()
+			select {
+			case w.events <- r:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+	log.From(ctx).Debug("tailing log", zap.String("pattern", l.Pattern))
+	t := NewTailer(l.Pattern)
+	t.Polled = func() { emit(p.flush()) }
+	return t.Run(ctx, func(file, line string) { emit(p.parse(file, line)) })
+}
+
+var (
+	rptLinePattern = regexp.MustCompile(`^\s*(?:(\d{4})/(\d{1,2})/(\d{1,2}),\s*)?(\d{1,2}):(\d{2}):(\d{2})(?:\.(\d{1,3}))?\s+(.*)$`)
+	beLinePattern  = regexp.MustCompile(`^(?:(\d{1,2})\.(\d{1,2})\.(\d{4})\s+)?(\d{1,2}):(\d{2}):(\d{2}):\s*(.*)$`)
+
+	scriptFilePattern     = regexp.MustCompile(`^File (.+?)(?:\.\.\.)?, line (\d+)$`)
+	missionFilePattern    = regexp.MustCompile(`^Mission file: (.+?)(?: \(.*\))?$`)
+	missionWorldPattern   = regexp.MustCompile(`^Mission world: (.+)$`)
+	playerConnectedRPT    = regexp.MustCompile(`^Player (.+) connected \(id=(\w+)\)\.?$`)
+	playerDisconnectedRPT = regexp.MustCompile(`^Player (.+) disconnected\.?$`)
+	playerConnectedBE     = regexp.MustCompile(`^Player #(\d+) (.+) \(([0-9a-fA-F.:\[\]]+:\d+)\) connected$`)
+	playerDisconnectedBE  = regexp.MustCompile(`^Player #(\d+) (.+) disconnected$`)
+)
+
+// logParser turns log lines into records, keeping state spanning multiple lines
+type logParser struct {
+	format LogFormat
+	source event.Source
+	now    func() time.Time
+
+	mission, world string
+	// script error waiting for its remaining lines
+	script *event.Record
+	// continued is set if the script error got lines since the last flush
+	continued bool
+}
+
+func newLogParser(f LogFormat, src event.Source) *logParser {
+	return &logParser{format: f, source: src, now: time.Now}
+}
+
+// parse line of file returning the records completed by it
+func (p *logParser) parse(file, line string) []*event.Record {
+	ts, msg, stamped := p.timestamp(line)
+	if strings.TrimSpace(msg) == "" {
+		return nil
+	}
+
+	var out []*event.Record
+	if p.script != nil {
+		if p.continueScript(msg, stamped) {
+			if p.script.Attribute("script.line") == "" {
+				p.continued = true
+				return nil
+			}
+			out, p.script = append(out, p.script), nil
+			return out
+		}
+		out, p.script = append(out, p.script), nil
+	}
+
+	r := event.New(p.source, event.KindLog, msg)
+	r.Time = ts.UTC()
+	r.Set("log.file", filepath.Base(file)).Set("log.severity", severity(msg))
+
+	switch {
+	case p.format == FormatRPT && strings.HasPrefix(msg, "Error in expression <"):
+		r.Type = event.KindScriptError
+		r.Set("script.expression", strings.TrimSuffix(strings.TrimPrefix(msg, "Error in expression <"), ">"))
+		p.script, p.continued = r, true
+		return out
+	case strings.HasPrefix(msg, "Starting mission"):
+		p.mission, p.world = "", ""
+	case missionFilePattern.MatchString(msg):
+		p.mission = missionFilePattern.FindStringSubmatch(msg)[1]
+	case missionWorldPattern.MatchString(msg):
+		p.world = missionWorldPattern.FindStringSubmatch(msg)[1]
+	case msg == "Game started.":
+		r.Type = event.KindMission
+		p.setMission(r, "started")
+	case msg == "Game finished.":
+		r.Type = event.KindMission
+		p.setMission(r, "finished")
+	case playerConnectedRPT.MatchString(msg):
+		s := playerConnectedRPT.FindStringSubmatch(msg)
+		r.Type = event.KindPlayer
+		r.Set("player.event", "connected").Set("player.name", s[1]).Set("player.uid", s[2])
+	case playerConnectedBE.MatchString(msg):
+		s := playerConnectedBE.FindStringSubmatch(msg)
+		r.Type = event.KindPlayer
+		r.Set("player.event", "connected").Set("player.id", s[1]).Set("player.name", s[2]).Set("player.addr", s[3])
+	case playerDisconnectedBE.MatchString(msg):
+		s := playerDisconnectedBE.FindStringSubmatch(msg)
+		r.Type = event.KindPlayer
+		r.Set("player.event", "disconnected").Set("player.id", s[1]).Set("player.name", s[2])
+	case playerDisconnectedRPT.MatchString(msg):
+		s := playerDisconnectedRPT.FindStringSubmatch(msg)
+		r.Type = event.KindPlayer
+		r.Set("player.event", "disconnected").Set("player.name", s[1])
+	}
+	return append(out, r)
+}
+
+// flush returns the pending script error once no lines continued it since the last flush
+// It is called after every poll of the log, so script errors not followed by their file are not held back forever
+func (p *logParser) flush() []*event.Record {
+	if p.script == nil {
+		return nil
+	}
+	if p.continued {
+		p.continued = false
+		return nil
+	}
+	out := []*event.Record{p.script}
+	p.script = nil
+	return out
+}
+
+// continueScript adds msg to the pending script error returning false if it does not belong to it
+// Expressions may span multiple lines without timestamps, which are always part of the error
+func (p *logParser) continueScript(msg string, stamped bool) bool {
+	m := strings.TrimSpace(msg)
+	switch {
+	case !stamped:
+		expr := p.script.Attribute("script.expression")
+		if expr != "" {
+			expr += "\n"
+		}
+		p.script.Set("script.expression", expr+strings.TrimSuffix(msg, ">"))
+	case strings.HasPrefix(m, "Error position: <"):
+		p.script.Set("script.position", strings.TrimSuffix(strings.TrimPrefix(m, "Error position: <"), ">"))
+	case strings.HasPrefix(m, "Error "):
+		p.script.Set("script.error", strings.TrimPrefix(m, "Error "))
+	case scriptFilePattern.MatchString(m):
+		s := scriptFilePattern.FindStringSubmatch(m)
+		p.script.Set("script.file", s[1]).Set("script.line", s[2])
+	default:
+		return false
+	}
+	p.script.Payload += "\n" + m
+	return true
+}
+
+func (p *logParser) setMission(r *event.Record, state string) {
+	r.Set("mission.state", state)
+	if p.mission != "" {
+		r.Set("mission.name", p.mission)
+	}
+	if p.world != "" {
+		r.Set("mission.world", p.world)
+	}
+}
+
+// timestamp splits line into the time it got logged at and the message
+// Lines without date are assumed to be from the last 24 hours
+func (p *logParser) timestamp(line string) (time.Time, string, bool) {
+	now := p.now()
+	var year, month, day, hour, min, sec, msec int
+	switch p.format {
+	case FormatRPT:
+		s := rptLinePattern.FindStringSubmatch(line)
+		if s == nil {
+			return now, line, false
+		}
+		year, month, day = atoi(s[1]), atoi(s[2]), atoi(s[3])
+		hour, min, sec, msec = atoi(s[4]), atoi(s[5]), atoi(s[6]), atoi((s[7] + "000")[:3])
+		line = s[8]
+	case FormatBattlEye:
+		s := beLinePattern.FindStringSubmatch(line)
+		if s == nil {
+			return now, line, false
+		}
+		day, month, year = atoi(s[1]), atoi(s[2]), atoi(s[3])
+		hour, min, sec = atoi(s[4]), atoi(s[5]), atoi(s[6])
+		line = s[7]
+	}
+
+	if year == 0 {
+		ts := time.Date(now.Year(), now.Month(), now.Day(), hour, min, sec, msec*int(time.Millisecond), now.Location())
+		if ts.After(now.Add(time.Hour)) {
+			ts = ts.AddDate(0, 0, -1)
+		}
+		return ts, line, true
+	}
+	return time.Date(year, time.Month(month), day, hour, min, sec, msec*int(time.Millisecond), now.Location()), line, true
+}
+
+func severity(msg string) string {
+	switch {
+	case strings.HasPrefix(msg, "Error"), strings.HasPrefix(msg, "ERROR"), strings.Contains(msg, "ErrorMessage"):
+		return SeverityError
+	case strings.HasPrefix(msg, "Warning"), strings.HasPrefix(msg, "WARNING"):
+		return SeverityWarning
+	}
+	return SeverityInfo
+}
+
+func atoi(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
@@ -0,0 +1,181 @@
+package watcher
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("logParser", func() {
+	var (
+		p   *logParser
+		now = time.Date(2024, 3, 1, 0, 30, 0, 0, time.UTC)
+	)
+
+	parse := func(lines ...string) []*event.Record {
+		var out []*event.Record
+		for _, l := range lines {
+			out = append(out, p.parse("/srv/arma3server_x64_2024-03-01_00-00-00.rpt", l)...)
+		}
+		return out
+	}
+
+	Describe("RPT", func() {
+		BeforeEach(func() {
+			p = newLogParser(FormatRPT, event.Source{Server: "test", Component: event.ComponentWatcher})
+			p.now = func() time.Time { return now }
+		})
+
+		It("does parse timestamps and severities", func() {
+			out := parse(" 0:12:01 Warning Message: No entry 'bin\\config.bin/CfgVehicles.x'.")
+			Expect(out).To(HaveLen(1))
+			Expect(out[0].Type).To(Equal(event.KindLog))
+			Expect(out[0].Time).To(Equal(time.Date(2024, 3, 1, 0, 12, 1, 0, time.UTC)))
+			Expect(out[0].Payload).To(Equal("Warning Message: No entry 'bin\\config.bin/CfgVehicles.x'."))
+			Expect(out[0].Attribute("log.severity")).To(Equal(SeverityWarning))
+			Expect(out[0].Attribute("log.file")).To(Equal("arma3server_x64_2024-03-01_00-00-00.rpt"))
+			Expect(out[0].Server()).To(Equal("test"))
+		})
+		It("does assume times after now to be from the previous day", func() {
+			out := parse("23:59:59 Mission read.")
+			Expect(out[0].Time).To(Equal(time.Date(2024, 2, 29, 23, 59, 59, 0, time.UTC)))
+		})
+		It("does parse dates and milliseconds", func() {
+			out := parse("2024/02/28, 17:04:34.25 Host identity created.")
+			Expect(out[0].Time).To(Equal(time.Date(2024, 2, 28, 17, 4, 34, 250*int(time.Millisecond), time.UTC)))
+			Expect(out[0].Attribute("log.severity")).To(Equal(SeverityInfo))
+		})
+		It("does combine script errors spanning multiple lines", func() {
+			out := parse(
+				" 0:20:00 Error in expression <",
+				"_unit = _this select 0;",
+				"_unit setDa>",
+				" 0:20:00   Error position: <setDa>",
+				" 0:20:00   Error Undefined variable in expression: _unit",
+			)
+			Expect(out).To(BeEmpty())
+			out = parse(" 0:20:00 File mpmissions\\__cur_mp.Altis\\init.sqf..., line 12")
+			Expect(out).To(HaveLen(1))
+			Expect(out[0].Type).To(Equal(event.KindScriptError))
+			Expect(out[0].Attribute("log.severity")).To(Equal(SeverityError))
+			Expect(out[0].Attribute("script.expression")).To(Equal("_unit = _this select 0;\n_unit setDa"))
+			Expect(out[0].Attribute("script.position")).To(Equal("setDa"))
+			Expect(out[0].Attribute("script.error")).To(Equal("Undefined variable in expression: _unit"))
+			Expect(out[0].Attribute("script.file")).To(Equal("mpmissions\\__cur_mp.Altis\\init.sqf"))
+			Expect(out[0].Attribute("script.line")).To(Equal("12"))
+		})
+		It("does emit incomplete script errors with the next line", func() {
+			out := parse(" 0:20:00 Error in expression <a = b>", " 0:20:01 Game finished.")
+			Expect(out).To(HaveLen(2))
+			Expect(out[0].Type).To(Equal(event.KindScriptError))
+			Expect(out[0].Attribute("script.expression")).To(Equal("a = b"))
+			Expect(out[1].Type).To(Equal(event.KindMission))
+		})
+		It("does flush script errors not continued for a whole poll", func() {
+			Expect(parse(" 0:20:00 Error in expression <a = b>")).To(BeEmpty())
+			Expect(p.flush()).To(BeEmpty())
+			out := p.flush()
+			Expect(out).To(HaveLen(1))
+			Expect(out[0].Type).To(Equal(event.KindScriptError))
+			Expect(p.flush()).To(BeEmpty())
+		})
+		It("does keep script errors continued since the last poll", func() {
+			parse(" 0:20:00 Error in expression <a = b>")
+			Expect(p.flush()).To(BeEmpty())
+			Expect(parse(" 0:20:00   Error position: <b>")).To(BeEmpty())
+			Expect(p.flush()).To(BeEmpty())
+			out := parse(" 0:20:00 File init.sqf..., line 3")
+			Expect(out).To(HaveLen(1))
+			Expect(out[0].Attribute("script.position")).To(Equal("b"))
+			Expect(p.flush()).To(BeEmpty())
+		})
+		It("does emit mission start and end", func() {
+			out := parse(
+				" 0:10:00 Starting mission:",
+				" 0:10:00  Mission file: Altis_Life (__cur_mp)",
+				" 0:10:00  Mission world: Altis",
+				" 0:10:01 Game started.",
+				" 0:15:00 Game finished.",
+			)
+			Expect(out).To(HaveLen(5))
+			Expect(out[3].Type).To(Equal(event.KindMission))
+			Expect(out[3].Attributes).To(Equal(map[string]string{
+				"log.file":      "arma3server_x64_2024-03-01_00-00-00.rpt",
+				"log.severity":  SeverityInfo,
+				"mission.state": "started",
+				"mission.name":  "Altis_Life",
+				"mission.world": "Altis",
+			}))
+			Expect(out[4].Attribute("mission.state")).To(Equal("finished"))
+		})
+		It("does emit player connects", func() {
+			out := parse(" 0:10:00 Player Some Name connected (id=76561198000000000).", " 0:11:00 Player Some Name disconnected.")
+			Expect(out[0].Type).To(Equal(event.KindPlayer))
+			Expect(out[0].Attribute("player.event")).To(Equal("connected"))
+			Expect(out[0].Attribute("player.name")).To(Equal("Some Name"))
+			Expect(out[0].Attribute("player.uid")).To(Equal("76561198000000000"))
+			Expect(out[1].Attribute("player.event")).To(Equal("disconnected"))
+		})
+		It("does skip empty lines", func() {
+			Expect(parse("", " 0:10:00 ")).To(BeEmpty())
+		})
+	})
+
+	Describe("BattlEye", func() {
+		BeforeEach(func() {
+			p = newLogParser(FormatBattlEye, event.Source{Server: "test"})
+			p.now = func() time.Time { return now }
+		})
+
+		It("does parse timestamps", func() {
+			out := parse("01.03.2024 00:01:02: Some Name (1.2.3.4:2304) 0123456789abcdef0123456789abcdef - #0 \"x\"", "00:02:03: Other")
+			Expect(out[0].Time).To(Equal(time.Date(2024, 3, 1, 0, 1, 2, 0, time.UTC)))
+			Expect(out[0].Payload).To(HavePrefix("Some Name"))
+			Expect(out[1].Time).To(Equal(time.Date(2024, 3, 1, 0, 2, 3, 0, time.UTC)))
+		})
+		It("does emit player connects", func() {
+			out := parse("00:01:02: Player #3 Some Name (1.2.3.4:2304) connected", "00:05:00: Player #3 Some Name disconnected")
+			Expect(out[0].Type).To(Equal(event.KindPlayer))
+			Expect(out[0].Attributes).To(HaveKeyWithValue("player.id", "3"))
+			Expect(out[0].Attributes).To(HaveKeyWithValue("player.addr", "1.2.3.4:2304"))
+			Expect(out[1].Attributes).To(HaveKeyWithValue("player.event", "disconnected"))
+		})
+	})
+})
+
+var _ = Describe("TailLog", func() {
+	It("does emit parsed lines as events", func() {
+		dir, err := ioutil.TempDir("", "taillog")
+		Expect(err).To(BeNil())
+		defer os.RemoveAll(dir)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		w := NewWatcher(ctx, "server")
+
+		go w.TailLog(ctx, LogFile{Pattern: filepath.Join(dir, "*.rpt")})
+		// files present on the first poll are read from their end, so the line is appended until it got picked up
+		var ev event.Event
+		Eventually(func() bool {
+			f, err := os.OpenFile(filepath.Join(dir, "a.rpt"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+			Expect(err).To(BeNil())
+			_, err = f.WriteString(" 0:10:01 Game started.\n")
+			Expect(err).To(BeNil())
+			Expect(f.Close()).To(BeNil())
+			select {
+			case ev = <-w.events:
+				return true
+			case <-time.After(200 * time.Millisecond):
+				return false
+			}
+		}, 5*time.Second).Should(BeTrue())
+		Expect(ev.Kind()).To(Equal(string(TypeMission)))
+		Expect(ev.(*event.Record).Source).To(Equal(event.Source{Server: "server", Component: event.ComponentWatcher}))
+	})
+})
@@ -0,0 +1,23 @@
+package watcher
+
+import (
+	"github.com/playnet-public/gorcon/pkg/metrics"
+)
+
+var (
+	processStartTime = metrics.NewGaugeVec("gorcon_watcher_process_start_time_seconds", "Start time of the watched process in unix seconds.", "name")
+	processRunning   = metrics.NewGaugeVec("gorcon_watcher_process_running", "Whether the watched process is running.", "name")
+	processRestarts  = metrics.NewCounterVec("gorcon_watcher_restarts_total", "Restarts of the watched process.", "name")
+	processCrashes   = metrics.NewCounterVec("gorcon_watcher_crashes_total", "Unexpected exits of the watched process.", "name")
+	watcherEvents    = metrics.NewCounterVec("gorcon_watcher_events_total", "Events emitted by the watcher by kind.", "name", "kind")
+	watcherAlerts    = metrics.NewCounterVec("gorcon_watcher_alerts_total", "Thresholds exceeded by the watched process by metric.", "name", "metric")
+	processCPU       = metrics.NewGaugeVec("gorcon_watcher_process_cpu_cores", "CPU used by the watched process in cores.", "name")
+	processRSS       = metrics.NewGaugeVec("gorcon_watcher_process_resident_memory_bytes", "Resident memory of the watched process in bytes.", "name")
+	processThreads   = metrics.NewGaugeVec("gorcon_watcher_process_threads", "Threads of the watched process.", "name")
+	processFDs       = metrics.NewGaugeVec("gorcon_watcher_process_open_fds", "Open file descriptors of the watched process.", "name")
+	watcherHangs     = metrics.NewCounterVec("gorcon_watcher_hangs_total", "Hung processes killed by the watchdog.", "name")
+	serverFPS        = metrics.NewGaugeVec("gorcon_watcher_server_fps", "Server fps reported through #monitor.", "name")
+	processReady     = metrics.NewGaugeVec("gorcon_watcher_process_ready", "Whether the watched process is ready.", "name")
+	shutdowns        = metrics.NewCounterVec("gorcon_watcher_shutdowns_total", "Graceful shutdowns of the watched process by how it exited.", "name", "result")
+	crashBundles     = metrics.NewCounterVec("gorcon_watcher_crash_bundles_total", "Crash bundles written by result.", "name", "result")
+)
@@ -0,0 +1,297 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+	"github.com/playnet-public/gorcon/pkg/rcon"
+	"github.com/playnet-public/gorcon/pkg/rcon/battleye"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// ErrNotRunning is returned when sampling a process which is not running
+var ErrNotRunning = errors.New("process not running")
+
+// clockTicks per second used by procfs for cpu times (USER_HZ)
+const clockTicks = 100
+
+// Metrics sampled by the Monitor
+const (
+	MetricCPU     = "cpu"
+	MetricRSS     = "rss"
+	MetricThreads = "threads"
+	MetricFDs     = "fds"
+	MetricFPS     = "fps"
+)
+
+// Usage of a process sampled from procfs
+type Usage struct {
+	Time time.Time
+	PID  int
+	// CPUTime spent by the process in user and system mode
+	CPUTime time.Duration
+	// CPU used since the previous sample in cores
+	CPU float64
+	// RSS is the resident memory in bytes
+	RSS     uint64
+	Threads int
+	FDs     int
+	// FPS last reported by the server through #monitor. Zero if unknown
+	FPS float64
+}
+
+// value of metric
+func (u Usage) value(metric string) float64 {
+	switch metric {
+	case MetricCPU:
+		return u.CPU
+	case MetricRSS:
+		return float64(u.RSS)
+	case MetricThreads:
+		return float64(u.Threads)
+	case MetricFDs:
+		return float64(u.FDs)
+	case MetricFPS:
+		return u.FPS
+	}
+	return 0
+}
+
+// ReadUsage of pid from the procfs mounted at proc. CPU and FPS are left empty
+func ReadUsage(proc string, pid int) (Usage, error) {
+	u := Usage{Time: time.Now(), PID: pid}
+	dir := filepath.Join(proc, strconv.Itoa(pid))
+	stat, err := ioutil.ReadFile(filepath.Join(dir, "stat"))
+	if os.IsNotExist(err) {
+		return u, ErrNotRunning
+	}
+	if err != nil {
+		return u, errors.Wrap(err, "reading stat")
+	}
+	// the command name may contain spaces and parentheses, so fields are counted from its end
+	i := strings.LastIndexByte(string(stat), ')')
+	if i < 0 {
+		return u, errors.New("invalid stat")
+	}
+	f := strings.Fields(string(stat[i+1:]))
+	if len(f) < 22 {
+		return u, errors.New("invalid stat")
+	}
+	utime, _ := strconv.ParseUint(f[11], 10, 64)
+	stime, _ := strconv.ParseUint(f[12], 10, 64)
+	u.CPUTime = time.Duration(utime+stime) * time.Second / clockTicks
+	u.Threads, _ = strconv.Atoi(f[17])
+	rss, _ := strconv.ParseUint(f[21], 10, 64)
+	u.RSS = rss * uint64(os.Getpagesize())
+
+	fds, err := ioutil.ReadDir(filepath.Join(dir, "fd"))
+	if err != nil && !os.IsPermission(err) {
+		return u, errors.Wrap(err, "reading fds")
+	}
+	u.FDs = len(fds)
+	return u, nil
+}
+
+// Threshold raising an alert once Metric exceeded Limit for Samples consecutive samples
+// Thresholds for MetricFPS alert once the fps drop below Limit
+type Threshold struct {
+	Metric  string
+	Limit   float64
+	Samples int
+	// Restart the process once the alert is raised, e.g. for memory leaks
+	Restart bool
+}
+
+// exceeded reports whether u exceeds the threshold
+func (t Threshold) exceeded(u Usage) bool {
+	if t.Metric == MetricFPS {
+		// unknown fps must not raise alerts
+		return u.FPS > 0 && u.FPS < t.Limit
+	}
+	return u.value(t.Metric) > t.Limit
+}
+
+// Monitor samples the resource usage of the process of Watcher and the fps reported by the server
+type Monitor struct {
+	Watcher *Watcher
+	// Proc is the mount point of procfs
+	Proc     string
+	Interval time.Duration
+	// Thresholds checked on every sample
+	Thresholds []Threshold
+	// Rcon enables #monitor on the server if set. The reported fps are read from the events passed to Run
+	Rcon rcon.Writer
+
+	m        sync.Mutex
+	last     Usage
+	fps      float64
+	fpsAt    time.Time
+	exceeded []int
+}
+
+// NewMonitor sampling the process of w every 10 seconds
+func NewMonitor(w *Watcher, thresholds ...Threshold) *Monitor {
+	return &Monitor{
+		Watcher:    w,
+		Proc:       "/proc",
+		Interval:   10 * time.Second,
+		Thresholds: thresholds,
+	}
+}
+
+// Run the monitor sampling the process every interval and reading the fps from in until ctx is closed or in gets closed
+func (m *Monitor) Run(ctx context.Context, in <-chan event.Event) error {
+	if m.Rcon != nil {
+		interval := int(m.Interval / time.Second)
+		if interval < 1 {
+			interval = 1
+		}
+		if _, err := m.Rcon.Write(rcon.WithPriority(ctx, rcon.Scheduled), battleye.Monitor(interval)); err != nil {
+			log.From(ctx).Error("enabling server monitor", zap.Error(err))
+		}
+	}
+
+	tick := time.NewTicker(m.Interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.From(ctx).Info("stopping monitor", zap.Error(ctx.Err()))
+			return ctx.Err()
+		case <-tick.C:
+			if _, err := m.Sample(ctx); err != nil && err != ErrNotRunning {
+				log.From(ctx).Error("sampling process", zap.String("name", m.Watcher.Name), zap.Error(err))
+			}
+		case e, ok := <-in:
+			if !ok {
+				log.From(ctx).Info("stopping monitor")
+				return event.ErrInputClosed
+			}
+			m.Handle(e)
+		}
+	}
+}
+
+// Handle a single event by reading the fps from #monitor output
+func (m *Monitor) Handle(e event.Event) {
+	if e.Kind() != string(rcon.TypeEvent) {
+		return
+	}
+	l, err := battleye.ParseServerLoad(e.Data())
+	if err != nil {
+		return
+	}
+	serverFPS.With(m.Watcher.Name).Set(l.FPS)
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.fps, m.fpsAt = l.FPS, time.Now()
+}
+
+// Sample the usage of the process, emitting it and alerts for exceeded thresholds
+// Processes are stopped for KeepAlive to restart them if an exceeded threshold asks for it
+func (m *Monitor) Sample(ctx context.Context) (Usage, error) {
+	pid := m.Watcher.Process.PID()
+	if pid == 0 {
+		return Usage{}, ErrNotRunning
+	}
+	u, err := ReadUsage(m.Proc, pid)
+	if err != nil {
+		return u, err
+	}
+
+	m.m.Lock()
+	if m.last.PID == u.PID && u.Time.After(m.last.Time) {
+		u.CPU = float64(u.CPUTime-m.last.CPUTime) / float64(u.Time.Sub(m.last.Time))
+	}
+	// fps reports older than a few intervals are stale, e.g. after the server restarted
+	if time.Since(m.fpsAt) < 3*m.Interval {
+		u.FPS = m.fps
+	}
+	if m.last.PID != u.PID {
+		m.exceeded = nil
+	}
+	m.last = u
+	alerts := m.check(u)
+	m.m.Unlock()
+
+	processCPU.With(m.Watcher.Name).Set(u.CPU)
+	processRSS.With(m.Watcher.Name).Set(float64(u.RSS))
+	processThreads.With(m.Watcher.Name).Set(float64(u.Threads))
+	processFDs.With(m.Watcher.Name).Set(float64(u.FDs))
+	m.Watcher.emitRecord(ctx, m.Watcher.event(TypeUsage, fmt.Sprintf("cpu=%.2f rss=%d threads=%d fds=%d fps=%g", u.CPU, u.RSS, u.Threads, u.FDs, u.FPS)).
+		Set("usage.pid", strconv.Itoa(u.PID)).
+		Set("usage.cpu", strconv.FormatFloat(u.CPU, 'f', 2, 64)).
+		Set("usage.rss", strconv.FormatUint(u.RSS, 10)).
+		Set("usage.threads", strconv.Itoa(u.Threads)).
+		Set("usage.fds", strconv.Itoa(u.FDs)).
+		Set("usage.fps", strconv.FormatFloat(u.FPS, 'f', -1, 64)))
+
+	restart := false
+	for _, t := range alerts {
+		m.alert(ctx, t, u)
+		restart = restart || t.Restart
+	}
+	if restart {
+		log.From(ctx).Info("restarting process", zap.String("name", m.Watcher.Name), zap.Int("pid", u.PID))
+		if err := m.Watcher.Process.Stop(); err != nil {
+			return u, errors.Wrap(err, "stopping process")
+		}
+	}
+	return u, nil
+}
+
+// check u against the thresholds returning the ones reaching their sample count
+// Each threshold alerts once until it recovers. The caller has to hold the lock
+func (m *Monitor) check(u Usage) []Threshold {
+	if len(m.exceeded) != len(m.Thresholds) {
+		m.exceeded = make([]int, len(m.Thresholds))
+	}
+	var alerts []Threshold
+	for i, t := range m.Thresholds {
+		if !t.exceeded(u) {
+			m.exceeded[i] = 0
+			continue
+		}
+		m.exceeded[i]++
+		samples := t.Samples
+		if samples < 1 {
+			samples = 1
+		}
+		if m.exceeded[i] == samples {
+			alerts = append(alerts, t)
+		}
+	}
+	return alerts
+}
+
+func (m *Monitor) alert(ctx context.Context, t Threshold, u Usage) {
+	action := "none"
+	if t.Restart {
+		action = "restart"
+	}
+	value := u.value(t.Metric)
+	log.From(ctx).Warn("threshold exceeded",
+		zap.String("name", m.Watcher.Name),
+		zap.String("metric", t.Metric),
+		zap.Float64("value", value),
+		zap.Float64("limit", t.Limit),
+		zap.String("action", action),
+	)
+	watcherAlerts.With(m.Watcher.Name, t.Metric).Inc()
+	m.Watcher.emitRecord(ctx, m.Watcher.event(TypeAlert, fmt.Sprintf("%s %g exceeded limit %g", t.Metric, value, t.Limit)).
+		Set("alert.metric", t.Metric).
+		Set("alert.value", strconv.FormatFloat(value, 'f', -1, 64)).
+		Set("alert.limit", strconv.FormatFloat(t.Limit, 'f', -1, 64)).
+		Set("alert.action", action))
+}
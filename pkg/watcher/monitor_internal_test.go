@@ -0,0 +1,168 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+	"github.com/playnet-public/gorcon/pkg/rcon"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/seibert-media/golibs/log"
+)
+
+var _ = Describe("Monitor", func() {
+	var (
+		ctx  context.Context
+		proc string
+		p    *pidProcess
+		w    *Watcher
+		m    *Monitor
+	)
+
+	// stat writes a procfs stat file for pid 42 with cpu ticks and rss pages
+	stat := func(ticks, pages int) {
+		Expect(os.MkdirAll(filepath.Join(proc, "42", "fd"), 0755)).To(BeNil())
+		Expect(ioutil.WriteFile(filepath.Join(proc, "42", "stat"), []byte(fmt.Sprintf(
+			"42 (arma3 (server)) S 1 42 42 0 -1 4194560 100 0 0 0 %d 0 0 0 20 0 64 0 100 2000000 %d 18446744073709551615\n", ticks, pages)), 0644)).To(BeNil())
+	}
+	received := func() []*event.Record {
+		var out []*event.Record
+		for {
+			select {
+			case e := <-w.events:
+				out = append(out, e.(*event.Record))
+			case <-time.After(50 * time.Millisecond):
+				return out
+			}
+		}
+	}
+
+	BeforeEach(func() {
+		var err error
+		proc, err = ioutil.TempDir("", "proc")
+		Expect(err).To(BeNil())
+		ctx = log.WithLogger(context.Background(), log.NewNop())
+		p = &pidProcess{pid: 42}
+		w = NewWatcher(ctx, "server")
+		w.Process = p
+		m = NewMonitor(w)
+		m.Proc = proc
+	})
+	AfterEach(func() {
+		os.RemoveAll(proc)
+	})
+
+	Describe("ReadUsage", func() {
+		It("does read cpu time, threads, rss and fds", func() {
+			stat(250, 10)
+			for i := 0; i < 3; i++ {
+				Expect(ioutil.WriteFile(filepath.Join(proc, "42", "fd", strconv.Itoa(i)), nil, 0644)).To(BeNil())
+			}
+			u, err := ReadUsage(proc, 42)
+			Expect(err).To(BeNil())
+			Expect(u.PID).To(Equal(42))
+			Expect(u.CPUTime).To(Equal(2500 * time.Millisecond))
+			Expect(u.Threads).To(Equal(64))
+			Expect(u.RSS).To(Equal(uint64(10 * os.Getpagesize())))
+			Expect(u.FDs).To(Equal(3))
+		})
+		It("does read the current process", func() {
+			u, err := ReadUsage("/proc", os.Getpid())
+			if _, serr := os.Stat("/proc/self/stat"); serr != nil {
+				Skip("no procfs")
+			}
+			Expect(err).To(BeNil())
+			Expect(u.Threads).To(BeNumerically(">", 0))
+			Expect(u.RSS).To(BeNumerically(">", 0))
+			Expect(u.FDs).To(BeNumerically(">", 0))
+		})
+		It("does return ErrNotRunning for missing processes", func() {
+			_, err := ReadUsage(proc, 43)
+			Expect(err).To(Equal(ErrNotRunning))
+		})
+	})
+
+	Describe("Sample", func() {
+		It("does return ErrNotRunning without pid", func() {
+			p.pid = 0
+			_, err := m.Sample(ctx)
+			Expect(err).To(Equal(ErrNotRunning))
+		})
+		It("does emit usage events", func() {
+			stat(100, 1)
+			_, err := m.Sample(ctx)
+			Expect(err).To(BeNil())
+			events := received()
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].Type).To(Equal(TypeUsage))
+			Expect(events[0].Attribute("usage.pid")).To(Equal("42"))
+			Expect(events[0].Attribute("usage.threads")).To(Equal("64"))
+		})
+		It("does calculate cpu usage between samples", func() {
+			stat(100, 1)
+			m.Sample(ctx)
+			time.Sleep(100 * time.Millisecond)
+			stat(110, 1)
+			u, err := m.Sample(ctx)
+			Expect(err).To(BeNil())
+			Expect(u.CPU).To(BeNumerically("~", 1, 0.2))
+		})
+		It("does read fps from monitor output", func() {
+			stat(100, 1)
+			m.Handle(rcon.NewEvent(rcon.TypeEvent, "Server load: FPS 47, memory used: 1021 MB, out: 1055 Kbps, in: 283 Kbps, NG:0, G:1047, BE-NG:0, BE-G:0, Players: 30 (L:0, R:0, B:0, G:30, D:0)"))
+			u, _ := m.Sample(ctx)
+			Expect(u.FPS).To(Equal(47.0))
+		})
+		It("does alert once thresholds got exceeded for their samples", func() {
+			stat(100, 1000)
+			m.Thresholds = []Threshold{{Metric: MetricRSS, Limit: 1, Samples: 2}}
+			m.Sample(ctx)
+			Expect(received()).To(HaveLen(1))
+			m.Sample(ctx)
+			events := received()
+			Expect(events).To(HaveLen(2))
+			alert := events[0]
+			if alert.Type != TypeAlert {
+				alert = events[1]
+			}
+			Expect(alert.Type).To(Equal(TypeAlert))
+			Expect(alert.Attribute("alert.metric")).To(Equal(MetricRSS))
+			Expect(alert.Attribute("alert.action")).To(Equal("none"))
+			m.Sample(ctx)
+			Expect(received()).To(HaveLen(1))
+			Expect(atomic.LoadInt32(&p.stops)).To(BeEquivalentTo(0))
+		})
+		It("does alert on low fps only if known", func() {
+			stat(100, 1)
+			m.Thresholds = []Threshold{{Metric: MetricFPS, Limit: 20}}
+			m.Sample(ctx)
+			Expect(received()).To(HaveLen(1))
+			m.Handle(rcon.NewEvent(rcon.TypeEvent, "Server load: FPS 5, memory used: 1021 MB, out: 0 Kbps, in: 0 Kbps"))
+			m.Sample(ctx)
+			Expect(received()).To(HaveLen(2))
+		})
+		It("does stop the process for restarting thresholds", func() {
+			stat(100, 1000)
+			m.Thresholds = []Threshold{{Metric: MetricRSS, Limit: 1, Restart: true}}
+			m.Sample(ctx)
+			Expect(atomic.LoadInt32(&p.stops)).To(BeEquivalentTo(1))
+		})
+	})
+})
+
+type pidProcess struct {
+	nopProcess
+	pid   int
+	stops int32
+}
+
+func (p *pidProcess) PID() int    { return p.pid }
+func (p *pidProcess) Stop() error { atomic.AddInt32(&p.stops, 1); return nil }
@@ -0,0 +1,43 @@
+package watcher_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/mocks"
+	"github.com/playnet-public/gorcon/pkg/watcher"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/seibert-media/golibs/log"
+)
+
+var _ = Describe("Monitor", func() {
+	Describe("Run", func() {
+		It("does enable server monitoring", func() {
+			ctx, cancel := context.WithCancel(log.WithLogger(context.Background(), log.NewNop()))
+			r := &mocks.RconWriter{}
+			m := watcher.NewMonitor(watcher.NewWatcher(ctx, "server"))
+			m.Rcon = r
+			m.Interval = 5 * time.Second
+
+			cancel()
+			Expect(m.Run(ctx, nil)).To(Equal(context.Canceled))
+			Expect(r.WriteCallCount()).To(Equal(1))
+			_, cmd := r.WriteArgsForCall(0)
+			Expect(cmd).To(Equal("#monitor 5"))
+		})
+		It("does not fail while the process is not running", func() {
+			ctx, cancel := context.WithTimeout(log.WithLogger(context.Background(), log.NewNop()), 50*time.Millisecond)
+			defer cancel()
+			p := &mocks.Process{}
+			w := watcher.NewWatcher(ctx, "server")
+			w.Process = p
+			m := watcher.NewMonitor(w)
+			m.Interval = 10 * time.Millisecond
+
+			Expect(m.Run(ctx, nil)).To(Equal(context.DeadlineExceeded))
+			Expect(p.PIDCallCount()).To(BeNumerically(">", 0))
+		})
+	})
+})
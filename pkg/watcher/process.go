@@ -2,7 +2,10 @@ package watcher
 
 import (
 	"io"
+	"os"
 	"os/exec"
+	"sync/atomic"
+	"syscall"
 )
 
 // Process provides an interface for managing it
@@ -11,11 +14,14 @@ type Process interface {
 	SetOut(io.Writer, io.Writer)
 	Run() error
 	Stop() error
+	// PID of the running process or zero if it is not running
+	PID() int
 }
 
 // OSProcess implements process using default os processes
 type OSProcess struct {
 	Cmd *exec.Cmd
+	pid int32
 }
 
 // NewOSProcess with command and args
@@ -32,11 +38,49 @@ func (p *OSProcess) SetOut(stderr, stdout io.Writer) {
 }
 
 // Run a new process and return error once it ends
+// Cmd is used as template, so the process can be run again after it exited
 func (p *OSProcess) Run() error {
-	return p.Cmd.Run()
+	cmd := &exec.Cmd{
+		Path:        p.Cmd.Path,
+		Args:        append([]string(nil), p.Cmd.Args...),
+		Env:         p.Cmd.Env,
+		Dir:         p.Cmd.Dir,
+		Stdin:       p.Cmd.Stdin,
+		Stdout:      p.Cmd.Stdout,
+		Stderr:      p.Cmd.Stderr,
+		SysProcAttr: p.Cmd.SysProcAttr,
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	atomic.StoreInt32(&p.pid, int32(cmd.Process.Pid))
+	defer atomic.StoreInt32(&p.pid, 0)
+	return cmd.Wait()
 }
 
 // Stop the current process by sending a termination signal
 func (p *OSProcess) Stop() error {
-	return nil
+	pid := p.PID()
+	if pid == 0 {
+		return nil
+	}
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return proc.Signal(syscall.SIGTERM)
+}
+
+// PID of the running process or zero if it is not running
+func (p *OSProcess) PID() int {
+	return int(atomic.LoadInt32(&p.pid))
+}
+
+// killProcess pid by sending SIGKILL
+func killProcess(pid int) error {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return proc.Signal(syscall.SIGKILL)
 }
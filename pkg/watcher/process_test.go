@@ -25,4 +25,13 @@ var _ = Describe("Process", func() {
 			Expect(p.Cmd.Stdout).NotTo(BeNil())
 		})
 	})
+
+	Describe("Run", func() {
+		It("does run the process again after it exited", func() {
+			p = watcher.NewOSProcess("true")
+			Expect(p.Run()).To(BeNil())
+			Expect(p.Run()).To(BeNil())
+			Expect(p.PID()).To(BeZero())
+		})
+	})
 })
@@ -0,0 +1,201 @@
+package watcher
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+	"github.com/playnet-public/gorcon/pkg/rcon"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// ErrNotStarted is returned when probing a server which did not write the started line yet
+var ErrNotStarted = errors.New("server not started")
+
+// States of the server tracked by Readiness
+const (
+	StateStopped  = "stopped"
+	StateStarting = "starting"
+	StateReady    = "ready"
+)
+
+// Module started once the server is ready. Its ctx gets closed once the server is not ready anymore
+type Module func(ctx context.Context) error
+
+// Readiness declares a started server ready once it accepts rcon logins and starts the Modules depending on it
+type Readiness struct {
+	Watcher *Watcher
+	// Rcon is connected once the process started. The connection is kept open while the server is ready
+	Rcon *rcon.Rcon
+	// Started optionally has to match a line written by the process before logging in is attempted
+	Started *regexp.Regexp
+	// Interval of login attempts
+	Interval time.Duration
+	// Modules started once the server is ready, e.g. schedulers and moderation
+	Modules []Module
+
+	m       sync.Mutex
+	state   string
+	pid     int
+	started bool
+	cancel  context.CancelFunc
+}
+
+// NewReadiness of the server watched by w logging in using r every 5 seconds
+func NewReadiness(w *Watcher, r *rcon.Rcon, modules ...Module) *Readiness {
+	return &Readiness{
+		Watcher:  w,
+		Rcon:     r,
+		Interval: 5 * time.Second,
+		Modules:  modules,
+		state:    StateStopped,
+	}
+}
+
+// Run the readiness probe checking the server every interval and matching the events received on in until ctx is closed or in gets closed
+// in should receive the events of the watcher. Modules are stopped once Run returns
+func (r *Readiness) Run(ctx context.Context, in <-chan event.Event) error {
+	defer r.stop(ctx)
+	tick := time.NewTicker(r.Interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.From(ctx).Info("stopping readiness probe", zap.Error(ctx.Err()))
+			return ctx.Err()
+		case <-tick.C:
+			r.Check(ctx)
+		case e, ok := <-in:
+			if !ok {
+				log.From(ctx).Info("stopping readiness probe")
+				return event.ErrInputClosed
+			}
+			r.Handle(e)
+		}
+	}
+}
+
+// State of the server
+func (r *Readiness) State() string {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.state
+}
+
+// Handle a single event by matching lines of the process against Started
+func (r *Readiness) Handle(e event.Event) {
+	rec, ok := e.(*event.Record)
+	if !ok || rec.Source.Component != event.ComponentWatcher {
+		return
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	switch rec.Type {
+	case TypeCrash, TypeRestart:
+		// lines of the previous process must not count for the next one
+		r.started = false
+	case TypeStdOut, TypeStdErr, TypeLog:
+		if r.Started != nil && r.Started.MatchString(rec.Payload) {
+			r.started = true
+		}
+	}
+}
+
+// Check the state of the server, probing it while it is starting. Returns the resulting state
+func (r *Readiness) Check(ctx context.Context) string {
+	pid := r.Watcher.Process.PID()
+	r.m.Lock()
+	if pid != r.pid {
+		r.pid = pid
+		r.state = StateStarting
+		if pid == 0 {
+			r.state, r.started = StateStopped, false
+		}
+		r.m.Unlock()
+		r.stop(ctx)
+		r.m.Lock()
+	}
+	state := r.state
+	r.m.Unlock()
+	if state != StateStarting {
+		return state
+	}
+
+	if err := r.Probe(ctx); err != nil {
+		log.From(ctx).Debug("server not ready", zap.String("name", r.Watcher.Name), zap.Error(err))
+		return state
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.pid != pid || r.state != StateStarting {
+		return r.state
+	}
+	r.state = StateReady
+	log.From(ctx).Info("server ready", zap.String("name", r.Watcher.Name), zap.Int("pid", pid))
+	processReady.With(r.Watcher.Name).Set(1)
+	r.Watcher.emit(ctx, TypeReady, "")
+	r.start(ctx)
+	return r.state
+}
+
+// Probe the server by checking for the Started line and logging in to rcon
+// It can be used as Member.Ready for starting headless clients once the server is up
+func (r *Readiness) Probe(ctx context.Context) error {
+	r.m.Lock()
+	started := r.Started == nil || r.started
+	ready := r.state == StateReady
+	r.m.Unlock()
+	if ready {
+		return nil
+	}
+	if !started {
+		return ErrNotStarted
+	}
+	if r.Rcon == nil {
+		return nil
+	}
+	if r.Rcon.Connected() {
+		// connections from previous attempts or processes are stale
+		if err := r.Rcon.Disconnect(ctx); err != nil {
+			log.From(ctx).Debug("disconnecting stale rcon connection", zap.String("name", r.Watcher.Name), zap.Error(err))
+		}
+	}
+	return r.Rcon.Connect(ctx)
+}
+
+// start the Modules. The caller has to hold the lock
+func (r *Readiness) start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+	for _, m := range r.Modules {
+		go func(m Module) {
+			if err := m(ctx); err != nil && ctx.Err() == nil {
+				log.From(ctx).Error("running module", zap.String("name", r.Watcher.Name), zap.Error(err))
+			}
+		}(m)
+	}
+}
+
+// stop the Modules and disconnect from rcon if the server was ready
+func (r *Readiness) stop(ctx context.Context) {
+	r.m.Lock()
+	cancel := r.cancel
+	r.cancel = nil
+	r.m.Unlock()
+	if cancel == nil {
+		return
+	}
+	log.From(ctx).Info("server not ready anymore", zap.String("name", r.Watcher.Name))
+	processReady.With(r.Watcher.Name).Set(0)
+	cancel()
+	if r.Rcon != nil && r.Rcon.Connected() {
+		if err := r.Rcon.Disconnect(ctx); err != nil {
+			log.From(ctx).Error("disconnecting rcon", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package watcher_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sync/atomic"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+	"github.com/playnet-public/gorcon/pkg/mocks"
+	"github.com/playnet-public/gorcon/pkg/rcon"
+	"github.com/playnet-public/gorcon/pkg/watcher"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/seibert-media/golibs/log"
+)
+
+var _ = Describe("Readiness", func() {
+	var (
+		ctx     context.Context
+		p       *mocks.Process
+		con     *mocks.RconConnection
+		r       *watcher.Readiness
+		running int32
+		stopped int32
+	)
+
+	line := func(kind event.Kind, data string) *event.Record {
+		return event.New(event.Source{Server: "server", Component: event.ComponentWatcher}, kind, data)
+	}
+
+	BeforeEach(func() {
+		ctx = log.WithLogger(context.Background(), log.NewNop())
+		p = &mocks.Process{}
+		w := watcher.NewWatcher(ctx, "server")
+		w.Process = p
+		con = &mocks.RconConnection{}
+		client := &mocks.RconClient{}
+		client.NewConnectionReturns(con)
+		atomic.StoreInt32(&running, 0)
+		atomic.StoreInt32(&stopped, 0)
+		r = watcher.NewReadiness(w, &rcon.Rcon{Client: client}, func(ctx context.Context) error {
+			atomic.AddInt32(&running, 1)
+			<-ctx.Done()
+			atomic.AddInt32(&stopped, 1)
+			return ctx.Err()
+		})
+	})
+
+	Describe("Check", func() {
+		It("does stay stopped without process", func() {
+			Expect(r.Check(ctx)).To(Equal(watcher.StateStopped))
+			Expect(con.OpenCallCount()).To(Equal(0))
+		})
+		It("does declare the server ready once rcon accepts logins", func() {
+			p.PIDReturns(42)
+			con.OpenReturns(errors.New("connection refused"))
+			Expect(r.Check(ctx)).To(Equal(watcher.StateStarting))
+			Expect(r.Check(ctx)).To(Equal(watcher.StateStarting))
+			Consistently(func() int32 { return atomic.LoadInt32(&running) }).Should(BeZero())
+
+			con.OpenReturns(nil)
+			Expect(r.Check(ctx)).To(Equal(watcher.StateReady))
+			Expect(r.State()).To(Equal(watcher.StateReady))
+			Expect(con.OpenCallCount()).To(Equal(3))
+			Eventually(func() int32 { return atomic.LoadInt32(&running) }).Should(Equal(int32(1)))
+
+			Expect(r.Check(ctx)).To(Equal(watcher.StateReady))
+			Expect(con.OpenCallCount()).To(Equal(3))
+		})
+		It("does stop modules once the process exits", func() {
+			p.PIDReturns(42)
+			Expect(r.Check(ctx)).To(Equal(watcher.StateReady))
+			Eventually(func() int32 { return atomic.LoadInt32(&running) }).Should(Equal(int32(1)))
+
+			p.PIDReturns(0)
+			Expect(r.Check(ctx)).To(Equal(watcher.StateStopped))
+			Eventually(func() int32 { return atomic.LoadInt32(&stopped) }).Should(Equal(int32(1)))
+			Expect(con.CloseCallCount()).To(Equal(1))
+
+			p.PIDReturns(43)
+			Expect(r.Check(ctx)).To(Equal(watcher.StateReady))
+			Eventually(func() int32 { return atomic.LoadInt32(&running) }).Should(Equal(int32(2)))
+		})
+		It("does reconnect if closing the previous connection failed", func() {
+			p.PIDReturns(42)
+			Expect(r.Check(ctx)).To(Equal(watcher.StateReady))
+			con.CloseReturns(errors.New("test"))
+
+			p.PIDReturns(43)
+			Expect(r.Check(ctx)).To(Equal(watcher.StateReady))
+			Expect(con.OpenCallCount()).To(Equal(2))
+			Eventually(func() int32 { return atomic.LoadInt32(&stopped) }).Should(Equal(int32(1)))
+		})
+		It("does wait for the started line", func() {
+			r.Started = regexp.MustCompile(`Host identity created`)
+			p.PIDReturns(42)
+			Expect(r.Check(ctx)).To(Equal(watcher.StateStarting))
+			Expect(r.Probe(ctx)).To(Equal(watcher.ErrNotStarted))
+
+			r.Handle(line(watcher.TypeStdOut, "12:00:01 Host identity created."))
+			Expect(r.Check(ctx)).To(Equal(watcher.StateReady))
+		})
+		It("does forget the started line on restarts", func() {
+			r.Started = regexp.MustCompile(`Host identity created`)
+			r.Handle(line(watcher.TypeStdOut, "Host identity created."))
+			r.Handle(line(watcher.TypeRestart, ""))
+			p.PIDReturns(42)
+			Expect(r.Check(ctx)).To(Equal(watcher.StateStarting))
+			Expect(con.OpenCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("Run", func() {
+		It("does stop modules once it returns", func() {
+			p.PIDReturns(42)
+			Expect(r.Check(ctx)).To(Equal(watcher.StateReady))
+			in := make(chan event.Event)
+			close(in)
+			Expect(r.Run(ctx, in)).To(Equal(event.ErrInputClosed))
+			Eventually(func() int32 { return atomic.LoadInt32(&stopped) }).Should(Equal(int32(1)))
+		})
+	})
+})
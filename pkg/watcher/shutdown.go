@@ -0,0 +1,257 @@
+package watcher
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/rcon"
+	"github.com/playnet-public/gorcon/pkg/rcon/battleye"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// ErrShutdownRunning is returned when shutting down a process which is already shutting down
+var ErrShutdownRunning = errors.New("shutdown already running")
+
+// Warning sent to all players before a shutdown
+type Warning struct {
+	// Before the shutdown the warning is sent
+	Before  time.Duration
+	Message string
+}
+
+// DefaultWarnings sent by shutdowns with enough delay
+var DefaultWarnings = []Warning{
+	{Before: 5 * time.Minute, Message: "Server shuts down in 5 minutes"},
+	{Before: time.Minute, Message: "Server shuts down in 1 minute"},
+	{Before: 10 * time.Second, Message: "Server shuts down in 10 seconds"},
+}
+
+// Shutdown stops the process of Watcher gracefully by warning players, locking the server and shutting it down through rcon
+// Processes not exiting in time get terminated and killed. KeepAlive of the Watcher does not revive processes shut down
+type Shutdown struct {
+	Watcher *Watcher
+	// Rcon receives the commands of the shutdown. Only signals are used if nil
+	Rcon rcon.Writer
+	// Warnings sent before the shutdown if the delay allows it
+	Warnings []Warning
+	// Lock the server once all warnings got sent, so nobody joins while it shuts down
+	Lock bool
+	// Save commands sent after locking the server, e.g. for making the mission persist its state
+	Save []string
+	// SaveDelay waited for the save commands to finish
+	SaveDelay time.Duration
+	// RconShutdown sends #shutdown for a clean exit before terminating the process
+	RconShutdown bool
+	// StopTimeout waited for the process to exit after shutting it down through rcon and after terminating it
+	StopTimeout time.Duration
+	// Poll interval for checking whether the process exited
+	Poll time.Duration
+
+	running int32
+}
+
+// NewShutdown of the process of w locking and shutting down the server through r
+func NewShutdown(w *Watcher, r rcon.Writer) *Shutdown {
+	return &Shutdown{
+		Watcher:      w,
+		Rcon:         r,
+		Warnings:     DefaultWarnings,
+		Lock:         true,
+		RconShutdown: true,
+		StopTimeout:  w.StopTimeout,
+		Poll:         100 * time.Millisecond,
+	}
+}
+
+// Stop the process after warning the players for delay
+// Closing ctx cancels the shutdown without escalating it any further
+func (s *Shutdown) Stop(ctx context.Context, delay time.Duration) error {
+	if !atomic.CompareAndSwapInt32(&s.running, 0, 1) {
+		return ErrShutdownRunning
+	}
+	defer atomic.StoreInt32(&s.running, 0)
+
+	pid := s.Watcher.Process.PID()
+	if pid == 0 {
+		return ErrNotRunning
+	}
+	log.From(ctx).Info("shutting down process", zap.String("name", s.Watcher.Name), zap.Int("pid", pid), zap.Duration("delay", delay))
+
+	if err := s.warn(ctx, delay); err != nil {
+		log.From(ctx).Info("canceled shutdown", zap.String("name", s.Watcher.Name), zap.Error(err))
+		s.step(ctx, "canceled", "")
+		return err
+	}
+	atomic.StoreInt32(&s.Watcher.stopping, 1)
+	if err := s.stop(ctx, pid); err != nil {
+		// the process keeps running, so it has to be revived if it exits later on
+		atomic.StoreInt32(&s.Watcher.stopping, 0)
+		return err
+	}
+	return nil
+}
+
+// stop process pid of the warned players by escalating from rcon to signals until it exits
+func (s *Shutdown) stop(ctx context.Context, pid int) error {
+	if s.Lock {
+		s.command(ctx, "lock", battleye.Lock)
+	}
+	for _, cmd := range s.Save {
+		s.command(ctx, "save", cmd)
+	}
+	if len(s.Save) > 0 && !s.sleep(ctx, s.SaveDelay) {
+		return ctx.Err()
+	}
+
+	if s.RconShutdown && s.Rcon != nil {
+		s.command(ctx, "rcon", battleye.Shutdown)
+		if s.exited(ctx, pid) {
+			return s.done(ctx, "rcon")
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	s.step(ctx, "terminate", "")
+	if err := s.Watcher.Process.Stop(); err != nil {
+		log.From(ctx).Error("terminating process", zap.String("name", s.Watcher.Name), zap.Int("pid", pid), zap.Error(err))
+	}
+	if s.exited(ctx, pid) {
+		return s.done(ctx, "terminated")
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	s.step(ctx, "kill", "")
+	if err := killProcess(pid); err != nil {
+		log.From(ctx).Error("killing process", zap.String("name", s.Watcher.Name), zap.Int("pid", pid), zap.Error(err))
+	}
+	if s.exited(ctx, pid) {
+		return s.done(ctx, "killed")
+	}
+	shutdowns.With(s.Watcher.Name, "failed").Inc()
+	return errors.Errorf("process %d did not exit", pid)
+}
+
+// Handler starting shutdowns on POST requests, e.g. for exposing them through an api guarded by auth.Middleware
+// Shutdowns run in the background until ctx is closed. The delay parameter (e.g. 5m) defaults to the longest warning
+func (s *Shutdown) Handler(ctx context.Context) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		delay := s.longestWarning()
+		if d := r.URL.Query().Get("delay"); d != "" {
+			var err error
+			if delay, err = time.ParseDuration(d); err != nil || delay < 0 {
+				http.Error(w, "invalid delay", http.StatusBadRequest)
+				return
+			}
+		}
+		switch {
+		case s.Watcher.Process.PID() == 0:
+			http.Error(w, ErrNotRunning.Error(), http.StatusConflict)
+			return
+		case atomic.LoadInt32(&s.running) == 1:
+			http.Error(w, ErrShutdownRunning.Error(), http.StatusConflict)
+			return
+		}
+		log.From(r.Context()).Info("requested shutdown", zap.String("name", s.Watcher.Name), zap.Duration("delay", delay))
+		go func() {
+			if err := s.Stop(ctx, delay); err != nil {
+				log.From(ctx).Error("shutting down process", zap.String("name", s.Watcher.Name), zap.Error(err))
+			}
+		}()
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+func (s *Shutdown) longestWarning() time.Duration {
+	var d time.Duration
+	for _, w := range s.Warnings {
+		if w.Before > d {
+			d = w.Before
+		}
+	}
+	return d
+}
+
+// warn the players for delay using the Warnings fitting into it
+func (s *Shutdown) warn(ctx context.Context, delay time.Duration) error {
+	warnings := append([]Warning(nil), s.Warnings...)
+	sort.Slice(warnings, func(i, j int) bool { return warnings[i].Before > warnings[j].Before })
+	deadline := time.Now().Add(delay)
+	for _, w := range warnings {
+		if w.Before > delay {
+			continue
+		}
+		if !s.sleep(ctx, time.Until(deadline.Add(-w.Before))) {
+			return ctx.Err()
+		}
+		s.command(ctx, "warning", battleye.Say(battleye.Everyone, w.Message))
+	}
+	if !s.sleep(ctx, time.Until(deadline)) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// command sent to the server as step of the shutdown. Failures are logged only, as signals stop the process anyway
+func (s *Shutdown) command(ctx context.Context, step, cmd string) {
+	s.step(ctx, step, cmd)
+	if s.Rcon == nil {
+		return
+	}
+	if _, err := s.Rcon.Write(rcon.WithPriority(ctx, rcon.Scheduled), cmd); err != nil {
+		log.From(ctx).Error("sending shutdown command", zap.String("name", s.Watcher.Name), zap.String("step", step), zap.Error(err))
+	}
+}
+
+// step of the shutdown emitted as event
+func (s *Shutdown) step(ctx context.Context, step, payload string) {
+	s.Watcher.emitRecord(ctx, s.Watcher.event(TypeShutdown, payload).Set("shutdown.step", step))
+}
+
+// exited waits StopTimeout for process pid to exit
+func (s *Shutdown) exited(ctx context.Context, pid int) bool {
+	deadline := time.Now().Add(s.StopTimeout)
+	for {
+		if s.Watcher.Process.PID() != pid {
+			return true
+		}
+		if !time.Now().Before(deadline) || !s.sleep(ctx, s.Poll) {
+			return false
+		}
+	}
+}
+
+func (s *Shutdown) done(ctx context.Context, result string) error {
+	log.From(ctx).Info("shut down process", zap.String("name", s.Watcher.Name), zap.String("result", result))
+	shutdowns.With(s.Watcher.Name, result).Inc()
+	s.step(ctx, "exited", result)
+	return nil
+}
+
+// sleep for d returning false if ctx got closed before
+func (s *Shutdown) sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,188 @@
+package watcher_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/mocks"
+	"github.com/playnet-public/gorcon/pkg/rcon"
+	"github.com/playnet-public/gorcon/pkg/watcher"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/seibert-media/golibs/log"
+)
+
+var _ = Describe("Shutdown", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		r      *mocks.RconWriter
+	)
+
+	// start runs the command as process of a new watcher until it exits
+	start := func(cmd string, args ...string) (*watcher.Watcher, *watcher.OSProcess) {
+		p := watcher.NewOSProcess(cmd, args...)
+		w := watcher.NewWatcher(ctx, "server")
+		w.Process = p
+		go p.Run()
+		Eventually(p.PID).ShouldNot(BeZero())
+		return w, p
+	}
+	commands := func() []string {
+		var cmds []string
+		for i := 0; i < r.WriteCallCount(); i++ {
+			_, cmd := r.WriteArgsForCall(i)
+			cmds = append(cmds, cmd)
+		}
+		return cmds
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(log.WithLogger(context.Background(), log.NewNop()))
+		r = &mocks.RconWriter{}
+	})
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("does warn players, lock and shut down the server through rcon", func() {
+		w, p := start("sleep", "30")
+		r.WriteStub = func(_ context.Context, cmd string) (rcon.Transmission, error) {
+			if cmd == "#shutdown" {
+				p.Stop()
+			}
+			return nil, nil
+		}
+		s := watcher.NewShutdown(w, r)
+		s.Warnings = append(s.Warnings,
+			watcher.Warning{Before: 50 * time.Millisecond, Message: "restart soon"},
+			watcher.Warning{Before: 0, Message: "restart now"},
+		)
+		s.Save = []string{"#exec save"}
+
+		begin := time.Now()
+		Expect(s.Stop(ctx, 100*time.Millisecond)).To(BeNil())
+		Expect(time.Since(begin)).To(BeNumerically(">=", 100*time.Millisecond))
+		Expect(p.PID()).To(BeZero())
+		Expect(commands()).To(Equal([]string{"say -1 restart soon", "say -1 restart now", "#lock", "#exec save", "#shutdown"}))
+	})
+	It("does terminate the process if it ignores rcon", func() {
+		w, p := start("sleep", "30")
+		s := watcher.NewShutdown(w, r)
+		s.StopTimeout = 50 * time.Millisecond
+		s.Poll = 10 * time.Millisecond
+
+		Expect(s.Stop(ctx, 0)).To(BeNil())
+		Expect(p.PID()).To(BeZero())
+		Expect(commands()).To(Equal([]string{"#lock", "#shutdown"}))
+	})
+	It("does kill the process if it ignores termination", func() {
+		w, p := start("sh", "-c", `trap "" TERM; while :; do sleep 0.1; done`)
+		s := watcher.NewShutdown(w, nil)
+		s.StopTimeout = 200 * time.Millisecond
+		s.Poll = 10 * time.Millisecond
+
+		Expect(s.Stop(ctx, 0)).To(BeNil())
+		Expect(p.PID()).To(BeZero())
+	})
+	It("does cancel the shutdown while warning", func() {
+		p := &mocks.Process{}
+		p.PIDReturns(42)
+		w := watcher.NewWatcher(ctx, "server")
+		w.Process = p
+		s := watcher.NewShutdown(w, r)
+		s.Warnings = []watcher.Warning{{Before: 50 * time.Millisecond, Message: "restart soon"}}
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			cancel()
+		}()
+		Expect(s.Stop(ctx, 100*time.Millisecond)).To(Equal(context.Canceled))
+		Expect(r.WriteCallCount()).To(BeZero())
+		Expect(p.StopCallCount()).To(BeZero())
+	})
+	It("does return error without process", func() {
+		w := watcher.NewWatcher(ctx, "server")
+		w.Process = &mocks.Process{}
+		Expect(watcher.NewShutdown(w, r).Stop(ctx, 0)).To(Equal(watcher.ErrNotRunning))
+	})
+	It("does not let KeepAlive revive the process", func() {
+		p := watcher.NewOSProcess("sleep", "30")
+		w := watcher.NewWatcher(ctx, "server")
+		w.Process = p
+		w.KeepAlive(ctx)
+		go w.Start(ctx)
+		Eventually(p.PID).ShouldNot(BeZero())
+		s := watcher.NewShutdown(w, nil)
+		s.Poll = 10 * time.Millisecond
+
+		Expect(s.Stop(ctx, 0)).To(BeNil())
+		Consistently(p.PID, 200*time.Millisecond).Should(BeZero())
+	})
+
+	Describe("Handler", func() {
+		var (
+			p        *mocks.Process
+			s        *watcher.Shutdown
+			shutdown int32
+		)
+
+		BeforeEach(func() {
+			atomic.StoreInt32(&shutdown, 0)
+			p = &mocks.Process{}
+			// the process exits once it received #shutdown
+			p.PIDStub = func() int {
+				if atomic.LoadInt32(&shutdown) == 1 {
+					return 0
+				}
+				return 42
+			}
+			r.WriteStub = func(_ context.Context, cmd string) (rcon.Transmission, error) {
+				if cmd == "#shutdown" {
+					atomic.StoreInt32(&shutdown, 1)
+				}
+				return nil, nil
+			}
+			w := watcher.NewWatcher(ctx, "server")
+			w.Process = p
+			s = watcher.NewShutdown(w, r)
+			s.Poll = 10 * time.Millisecond
+		})
+
+		serve := func(method, target string) int {
+			rec := httptest.NewRecorder()
+			s.Handler(ctx).ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+			return rec.Code
+		}
+
+		It("does start shutdowns in the background", func() {
+			Expect(serve(http.MethodPost, "/shutdown?delay=0s")).To(Equal(http.StatusAccepted))
+			Eventually(commands).Should(Equal([]string{"#lock", "#shutdown"}))
+		})
+		It("does wait for the longest warning by default", func() {
+			s.Warnings = []watcher.Warning{{Before: 20 * time.Millisecond, Message: "restart soon"}}
+			Expect(serve(http.MethodPost, "/shutdown")).To(Equal(http.StatusAccepted))
+			Eventually(commands).Should(Equal([]string{"say -1 restart soon", "#lock", "#shutdown"}))
+		})
+		It("does only accept posts", func() {
+			Expect(serve(http.MethodGet, "/shutdown")).To(Equal(http.StatusMethodNotAllowed))
+		})
+		It("does reject invalid delays", func() {
+			Expect(serve(http.MethodPost, "/shutdown?delay=soon")).To(Equal(http.StatusBadRequest))
+			Expect(serve(http.MethodPost, "/shutdown?delay=-1m")).To(Equal(http.StatusBadRequest))
+		})
+		It("does reject shutdowns without process", func() {
+			atomic.StoreInt32(&shutdown, 1)
+			Expect(serve(http.MethodPost, "/shutdown")).To(Equal(http.StatusConflict))
+		})
+		It("does reject shutdowns while one is running", func() {
+			s.Warnings = []watcher.Warning{{Before: time.Second, Message: "restart soon"}}
+			Expect(serve(http.MethodPost, "/shutdown")).To(Equal(http.StatusAccepted))
+			Eventually(func() int { return serve(http.MethodPost, "/shutdown") }).Should(Equal(http.StatusConflict))
+		})
+	})
+})
@@ -0,0 +1,160 @@
+package watcher
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Tailer follows the newest file matching Pattern, switching to newer files once they appear
+// Lines written to the previous file before the switch are still read
+type Tailer struct {
+	// Pattern of the followed files as accepted by filepath.Glob
+	Pattern string
+	// Poll interval for new lines and files
+	Poll time.Duration
+	// FromStart reads the file found when starting from its beginning instead of its end
+	FromStart bool
+	// Polled is called after every poll once the lines read got passed on, e.g. for completing pending lines
+	Polled func()
+
+	file   *os.File
+	name   string
+	offset int64
+	buf    []byte
+}
+
+// NewTailer following the newest file matching pattern
+func NewTailer(pattern string) *Tailer {
+	return &Tailer{
+		Pattern: pattern,
+		Poll:    time.Second,
+	}
+}
+
+// Run calls fn with the name of the current file for every line written until ctx is closed
+func (t *Tailer) Run(ctx context.Context, fn func(file, line string)) error {
+	defer t.close()
+	tick := time.NewTicker(t.Poll)
+	defer tick.Stop()
+	for first := true; ; first = false {
+		if err := t.follow(first, fn); err != nil {
+			return err
+		}
+		if t.Polled != nil {
+			t.Polled()
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+		}
+	}
+}
+
+// follow reads new lines of the current file and switches to the newest one
+func (t *Tailer) follow(first bool, fn func(file, line string)) error {
+	newest, err := t.newest()
+	if err != nil {
+		return err
+	}
+	if t.file != nil {
+		if err := t.read(fn); err != nil {
+			return err
+		}
+	}
+	if newest == "" || newest == t.name {
+		return nil
+	}
+
+	if len(t.buf) > 0 {
+		fn(t.name, t.line(t.buf))
+	}
+	t.close()
+	f, err := os.Open(newest)
+	if os.IsNotExist(err) {
+		// rotated away in between, the next poll picks up its successor
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "opening log")
+	}
+	t.file, t.name, t.offset = f, newest, 0
+	if first && !t.FromStart {
+		if t.offset, err = f.Seek(0, io.SeekEnd); err != nil {
+			return errors.Wrap(err, "seeking log")
+		}
+	}
+	return t.read(fn)
+}
+
+// newest file matching the pattern or an empty string if there is none
+func (t *Tailer) newest() (string, error) {
+	matches, err := filepath.Glob(t.Pattern)
+	if err != nil {
+		return "", errors.Wrap(err, "matching logs")
+	}
+	var (
+		newest string
+		mod    time.Time
+	)
+	for _, m := range matches {
+		info, err := os.Stat(m)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if newest == "" || info.ModTime().After(mod) || (info.ModTime().Equal(mod) && m > newest) {
+			newest, mod = m, info.ModTime()
+		}
+	}
+	return newest, nil
+}
+
+// read all complete lines appended to the current file
+func (t *Tailer) read(fn func(file, line string)) error {
+	if info, err := t.file.Stat(); err == nil && info.Size() < t.offset {
+		// truncated, start over
+		if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+			return errors.Wrap(err, "seeking log")
+		}
+		t.offset, t.buf = 0, nil
+	}
+
+	chunk := make([]byte, 32*1024)
+	for {
+		n, err := t.file.Read(chunk)
+		t.offset += int64(n)
+		t.buf = append(t.buf, chunk[:n]...)
+		for {
+			i := bytes.IndexByte(t.buf, '\n')
+			if i < 0 {
+				break
+			}
+			fn(t.name, t.line(t.buf[:i]))
+			t.buf = t.buf[i+1:]
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "reading log")
+		}
+	}
+}
+
+func (t *Tailer) line(b []byte) string {
+	return strings.TrimPrefix(strings.TrimRight(string(b), "\r"), "\ufeff")
+}
+
+func (t *Tailer) close() {
+	if t.file != nil {
+		t.file.Close()
+	}
+	t.file, t.name, t.buf = nil, "", nil
+}
@@ -0,0 +1,110 @@
+package watcher_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/watcher"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tailer", func() {
+	var (
+		dir    string
+		ctx    context.Context
+		cancel context.CancelFunc
+		lines  chan string
+		t      *watcher.Tailer
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "tailer")
+		Expect(err).To(BeNil())
+		ctx, cancel = context.WithCancel(context.Background())
+		lines = make(chan string, 100)
+		t = watcher.NewTailer(filepath.Join(dir, "*.rpt"))
+		t.Poll = 10 * time.Millisecond
+	})
+	AfterEach(func() {
+		cancel()
+		os.RemoveAll(dir)
+	})
+
+	write := func(name, data string, mod time.Time) {
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		Expect(err).To(BeNil())
+		_, err = f.WriteString(data)
+		Expect(err).To(BeNil())
+		Expect(f.Close()).To(BeNil())
+		Expect(os.Chtimes(filepath.Join(dir, name), mod, mod)).To(BeNil())
+	}
+	run := func() {
+		go t.Run(ctx, func(file, line string) { lines <- filepath.Base(file) + ":" + line })
+	}
+
+	It("does skip existing lines by default", func() {
+		write("a.rpt", "old\n", time.Now())
+		run()
+		Consistently(lines, 50*time.Millisecond).ShouldNot(Receive())
+		write("a.rpt", "new\n", time.Now())
+		Eventually(lines).Should(Receive(Equal("a.rpt:new")))
+	})
+	It("does read existing lines from start", func() {
+		write("a.rpt", "old\r\n", time.Now())
+		t.FromStart = true
+		run()
+		Eventually(lines).Should(Receive(Equal("a.rpt:old")))
+	})
+	It("does wait for complete lines", func() {
+		write("a.rpt", "", time.Now())
+		run()
+		time.Sleep(30 * time.Millisecond)
+		write("a.rpt", "par", time.Now())
+		Consistently(lines, 50*time.Millisecond).ShouldNot(Receive())
+		write("a.rpt", "tial\n", time.Now())
+		Eventually(lines).Should(Receive(Equal("a.rpt:partial")))
+	})
+	It("does follow rotations to newer files", func() {
+		now := time.Now()
+		write("a.rpt", "", now.Add(-time.Minute))
+		run()
+		time.Sleep(30 * time.Millisecond)
+		write("a.rpt", "last\n", now.Add(-time.Minute))
+		write("b.rpt", "first\n", now)
+		Eventually(lines).Should(Receive(Equal("a.rpt:last")))
+		Eventually(lines).Should(Receive(Equal("b.rpt:first")))
+		write("a.rpt", "ignored\n", now.Add(-time.Minute))
+		write("b.rpt", "second\n", now)
+		Eventually(lines).Should(Receive(Equal("b.rpt:second")))
+	})
+	It("does start over on truncated files", func() {
+		write("a.rpt", "", time.Now())
+		run()
+		time.Sleep(30 * time.Millisecond)
+		write("a.rpt", "a long line\n", time.Now())
+		Eventually(lines).Should(Receive(Equal("a.rpt:a long line")))
+		Expect(os.Truncate(filepath.Join(dir, "a.rpt"), 0)).To(BeNil())
+		time.Sleep(30 * time.Millisecond)
+		write("a.rpt", "short\n", time.Now())
+		Eventually(lines).Should(Receive(Equal("a.rpt:short")))
+	})
+	It("does call Polled after passing on the lines of a poll", func() {
+		polled := make(chan int, 100)
+		read := lines
+		t.Polled = func() { polled <- len(read) }
+		run()
+		Eventually(polled).Should(Receive())
+		write("a.rpt", "first\nsecond\n", time.Now())
+		Eventually(polled).Should(Receive(Equal(2)))
+	})
+	It("does return error on invalid pattern", func() {
+		t.Pattern = "["
+		Expect(t.Run(ctx, func(string, string) {})).NotTo(BeNil())
+	})
+})
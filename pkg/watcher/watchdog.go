@@ -0,0 +1,268 @@
+package watcher
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+	"github.com/playnet-public/gorcon/pkg/rcon"
+
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// Signals of life observed by the Watchdog
+const (
+	// SignalOutput is any output or log line written by the process
+	SignalOutput = "output"
+	// SignalRcon is any rcon event received from the server or a probe being answered
+	SignalRcon = "rcon"
+	// SignalPingback is a keepalive pingback received from the server
+	SignalPingback = "pingback"
+)
+
+// Pingbacker counts the keepalive pingbacks received from a server, e.g. battleye.Connection
+type Pingbacker interface {
+	Pingback() int64
+}
+
+// Watchdog kills processes which are alive but hung, so KeepAlive restarts them
+// A process is hung once none of the observed signals showed a sign of life for Grace
+type Watchdog struct {
+	Watcher *Watcher
+	// Grace period without any sign of life after which the process is considered hung
+	Grace time.Duration
+	// Interval of checks and probes
+	Interval time.Duration
+	// KillTimeout after which hung processes not stopping get killed
+	KillTimeout time.Duration
+
+	// Rcon receives a Probe command every interval if set. Answers count as sign of life
+	Rcon  rcon.Writer
+	Probe string
+	// ProbeTimeout for answers to probe commands
+	ProbeTimeout time.Duration
+	// Pingbacks count as sign of life if set
+	Pingbacks Pingbacker
+
+	m         sync.Mutex
+	pid       int
+	signals   map[string]*signal
+	pingbacks int64
+	probing   bool
+	now       func() time.Time
+}
+
+// signal of life and the evidence collected while it is missing
+type signal struct {
+	last     time.Time
+	failures int
+}
+
+// NewWatchdog restarting the process of w after two minutes without sign of life
+func NewWatchdog(w *Watcher) *Watchdog {
+	return &Watchdog{
+		Watcher:      w,
+		Grace:        2 * time.Minute,
+		Interval:     10 * time.Second,
+		KillTimeout:  10 * time.Second,
+		Probe:        "players",
+		ProbeTimeout: 10 * time.Second,
+		signals:      make(map[string]*signal),
+		now:          time.Now,
+	}
+}
+
+// Run the watchdog checking the process every interval and observing the events received on in until ctx is closed or in gets closed
+// in should receive the events of the watcher and the rcon connection of the server
+func (d *Watchdog) Run(ctx context.Context, in <-chan event.Event) error {
+	tick := time.NewTicker(d.Interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.From(ctx).Info("stopping watchdog", zap.Error(ctx.Err()))
+			return ctx.Err()
+		case <-tick.C:
+			d.Check(ctx)
+		case e, ok := <-in:
+			if !ok {
+				log.From(ctx).Info("stopping watchdog")
+				return event.ErrInputClosed
+			}
+			d.Handle(e)
+		}
+	}
+}
+
+// Handle a single event by recording it as sign of life
+func (d *Watchdog) Handle(e event.Event) {
+	r, ok := e.(*event.Record)
+	if !ok {
+		return
+	}
+	switch r.Source.Component {
+	case event.ComponentRcon:
+		d.alive(SignalRcon)
+	case event.ComponentWatcher:
+		switch r.Type {
+		case TypeStdOut, TypeStdErr, TypeLog, TypeScriptError, TypeMission, event.KindPlayer:
+			d.alive(SignalOutput)
+		}
+	}
+}
+
+// Check the signals of the process, killing it if it is hung. Returns whether it got killed
+func (d *Watchdog) Check(ctx context.Context) bool {
+	pid := d.Watcher.Process.PID()
+	now := d.now()
+
+	d.m.Lock()
+	if pid == 0 || pid != d.pid {
+		// give new processes the full grace period to start up
+		d.pid = pid
+		d.signals = make(map[string]*signal)
+	}
+	if pid == 0 {
+		d.m.Unlock()
+		return false
+	}
+	if d.Pingbacks != nil {
+		if n := d.Pingbacks.Pingback(); n != d.pingbacks {
+			d.pingbacks = n
+			d.touch(SignalPingback, now)
+		}
+	}
+	for _, name := range d.observed() {
+		if _, ok := d.signals[name]; !ok {
+			d.signals[name] = &signal{last: now}
+		}
+	}
+	probe := d.Rcon != nil && !d.probing
+	d.probing = d.probing || probe
+
+	hung := len(d.signals) > 0
+	for _, s := range d.signals {
+		hung = hung && now.Sub(s.last) >= d.Grace
+	}
+	var evidence *event.Record
+	if hung {
+		evidence = d.evidence(now)
+		d.signals = make(map[string]*signal)
+	}
+	d.m.Unlock()
+
+	if probe {
+		go d.probe(ctx)
+	}
+	if !hung {
+		return false
+	}
+
+	log.From(ctx).Warn("process hung", zap.String("name", d.Watcher.Name), zap.Int("pid", pid), zap.String("evidence", evidence.Payload))
+	watcherHangs.With(d.Watcher.Name).Inc()
+	d.Watcher.emitRecord(ctx, evidence)
+	go d.kill(ctx, pid)
+	return true
+}
+
+// observed returns the names of all signals being observed
+func (d *Watchdog) observed() []string {
+	names := []string{SignalOutput}
+	if d.Rcon != nil {
+		names = append(names, SignalRcon)
+	}
+	if d.Pingbacks != nil {
+		names = append(names, SignalPingback)
+	}
+	return names
+}
+
+func (d *Watchdog) alive(name string) {
+	d.m.Lock()
+	defer d.m.Unlock()
+	d.touch(name, d.now())
+}
+
+// touch records a sign of life. The caller has to hold the lock
+func (d *Watchdog) touch(name string, at time.Time) {
+	s, ok := d.signals[name]
+	if !ok {
+		s = &signal{}
+		d.signals[name] = s
+	}
+	s.last, s.failures = at, 0
+}
+
+// probe the server with a command waiting for its answer
+func (d *Watchdog) probe(ctx context.Context) {
+	defer func() {
+		d.m.Lock()
+		defer d.m.Unlock()
+		d.probing = false
+	}()
+	trm, err := d.Rcon.Write(rcon.WithPriority(ctx, rcon.Scheduled), d.Probe)
+	if err == nil {
+		select {
+		case <-trm.Done():
+			d.alive(SignalRcon)
+			return
+		case <-time.After(d.ProbeTimeout):
+		case <-ctx.Done():
+			return
+		}
+	}
+	log.From(ctx).Debug("probe failed", zap.String("name", d.Watcher.Name), zap.Error(err))
+	d.m.Lock()
+	defer d.m.Unlock()
+	if s, ok := d.signals[SignalRcon]; ok {
+		s.failures++
+	}
+}
+
+// evidence of the hang as event. The caller has to hold the lock
+func (d *Watchdog) evidence(now time.Time) *event.Record {
+	r := d.Watcher.event(TypeHang, "").Set("hang.pid", strconv.Itoa(d.pid))
+	var names []string
+	for name := range d.signals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var notes []string
+	for _, name := range names {
+		s := d.signals[name]
+		silent := now.Sub(s.last).Truncate(time.Second)
+		r.Set("hang."+name+".last", s.last.UTC().Format(time.RFC3339))
+		note := name + " silent for " + silent.String()
+		if name == SignalRcon && s.failures > 0 {
+			r.Set("hang.rcon.failures", strconv.Itoa(s.failures))
+			note += " with " + strconv.Itoa(s.failures) + " unanswered probes"
+		}
+		notes = append(notes, note)
+	}
+	r.Payload = "no sign of life: " + strings.Join(notes, ", ")
+	return r
+}
+
+// kill the hung process pid by stopping it and sending SIGKILL if it does not exit within KillTimeout
+func (d *Watchdog) kill(ctx context.Context, pid int) {
+	if err := d.Watcher.Process.Stop(); err != nil {
+		log.From(ctx).Error("stopping hung process", zap.Int("pid", pid), zap.Error(err))
+	}
+	select {
+	case <-time.After(d.KillTimeout):
+	case <-ctx.Done():
+		return
+	}
+	if d.Watcher.Process.PID() != pid {
+		return
+	}
+	log.From(ctx).Warn("killing hung process", zap.String("name", d.Watcher.Name), zap.Int("pid", pid))
+	if err := killProcess(pid); err != nil {
+		log.From(ctx).Error("killing hung process", zap.Int("pid", pid), zap.Error(err))
+	}
+}
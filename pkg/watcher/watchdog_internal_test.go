@@ -0,0 +1,132 @@
+package watcher
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+	"github.com/playnet-public/gorcon/pkg/rcon"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/seibert-media/golibs/log"
+)
+
+var _ = Describe("Watchdog", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		p      *pidProcess
+		w      *Watcher
+		d      *Watchdog
+		now    time.Time
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(log.WithLogger(context.Background(), log.NewNop()))
+		p = &pidProcess{pid: 42}
+		w = NewWatcher(ctx, "server")
+		w.Process = p
+		d = NewWatchdog(w)
+		d.Grace = time.Minute
+		d.KillTimeout = time.Hour
+		now = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+		d.now = func() time.Time { return now }
+	})
+	AfterEach(func() {
+		cancel()
+	})
+
+	output := func() event.Event { return w.event(TypeStdOut, "line") }
+
+	It("does not kill processes before the grace period passed", func() {
+		Expect(d.Check(ctx)).To(BeFalse())
+		now = now.Add(59 * time.Second)
+		Expect(d.Check(ctx)).To(BeFalse())
+	})
+	It("does kill silent processes after the grace period", func() {
+		d.Check(ctx)
+		now = now.Add(time.Minute)
+		Expect(d.Check(ctx)).To(BeTrue())
+
+		var e event.Event
+		Eventually(w.events).Should(Receive(&e))
+		r := e.(*event.Record)
+		Expect(r.Type).To(Equal(TypeHang))
+		Expect(r.Attribute("hang.pid")).To(Equal("42"))
+		Expect(r.Attribute("hang.output.last")).To(Equal("2024-03-01T00:00:00Z"))
+		Expect(r.Payload).To(Equal("no sign of life: output silent for 1m0s"))
+		Eventually(func() int32 { return atomic.LoadInt32(&p.stops) }).Should(BeEquivalentTo(1))
+	})
+	It("does treat output as sign of life", func() {
+		d.Check(ctx)
+		now = now.Add(50 * time.Second)
+		d.Handle(output())
+		now = now.Add(50 * time.Second)
+		Expect(d.Check(ctx)).To(BeFalse())
+	})
+	It("does ignore output of other components", func() {
+		d.Check(ctx)
+		now = now.Add(50 * time.Second)
+		d.Handle(event.New(event.Source{Component: "other"}, TypeStdOut, "line"))
+		now = now.Add(50 * time.Second)
+		Expect(d.Check(ctx)).To(BeTrue())
+	})
+	It("does require all signals to be silent", func() {
+		d.Pingbacks = &pingbacks{}
+		d.Check(ctx)
+		now = now.Add(50 * time.Second)
+		atomic.AddInt64(&d.Pingbacks.(*pingbacks).n, 1)
+		d.Check(ctx)
+		now = now.Add(50 * time.Second)
+		Expect(d.Check(ctx)).To(BeFalse())
+		now = now.Add(10 * time.Second)
+		Expect(d.Check(ctx)).To(BeTrue())
+	})
+	It("does count unanswered rcon probes", func() {
+		r := &probeWriter{}
+		d.Rcon = r
+		d.ProbeTimeout = time.Millisecond
+		d.Check(ctx)
+		Eventually(func() int32 { return atomic.LoadInt32(&r.writes) }).Should(BeEquivalentTo(1))
+		Eventually(func() bool { d.m.Lock(); defer d.m.Unlock(); return d.probing }).Should(BeFalse())
+		d.Check(ctx)
+		Eventually(func() bool { d.m.Lock(); defer d.m.Unlock(); return d.signals[SignalRcon].failures == 2 }).Should(BeTrue())
+
+		d.Handle(rcon.NewServerEvent("server", rcon.TypeEvent, "hi"))
+		now = now.Add(time.Minute)
+		d.Handle(rcon.NewServerEvent("server", rcon.TypeEvent, "hi"))
+		Expect(d.Check(ctx)).To(BeFalse())
+	})
+	It("does give restarted processes the full grace period", func() {
+		d.Check(ctx)
+		now = now.Add(50 * time.Second)
+		p.pid = 43
+		Expect(d.Check(ctx)).To(BeFalse())
+		now = now.Add(50 * time.Second)
+		Expect(d.Check(ctx)).To(BeFalse())
+	})
+	It("does not check stopped processes", func() {
+		p.pid = 0
+		d.Check(ctx)
+		now = now.Add(time.Hour)
+		Expect(d.Check(ctx)).To(BeFalse())
+	})
+})
+
+type pingbacks struct{ n int64 }
+
+func (p *pingbacks) Pingback() int64 { return atomic.LoadInt64(&p.n) }
+
+// probeWriter never answers commands
+type probeWriter struct{ writes int32 }
+
+func (w *probeWriter) Write(context.Context, string) (rcon.Transmission, error) {
+	atomic.AddInt32(&w.writes, 1)
+	return &silentTransmission{}, nil
+}
+
+type silentTransmission struct{ rcon.Transmission }
+
+func (t *silentTransmission) Done() <-chan bool { return nil }
@@ -5,6 +5,10 @@ import (
 	"context"
 	"errors"
 	"io"
+	"os/exec"
+	"strconv"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/playnet-public/gorcon/pkg/event"
@@ -15,14 +19,24 @@ import (
 
 // Watcher is responsible for starting and keeping a process alive
 type Watcher struct {
+	// Name identifies the process in metrics
+	Name      string
 	Process   Process
 	closeFunc func()
 	close     chan error
 
 	*event.Broker
 	events chan event.Event
+	// stopping is set while the process is being stopped on purpose, so it does not get revived
+	stopping int32
 
 	StopTimeout time.Duration
+
+	// Logs written by the process which are followed once started
+	Logs []LogFile
+	// Prepare is called before every start of the process, e.g. for deploying updates
+	// Errors are logged and do not prevent the process from starting
+	Prepare func(context.Context) error
 }
 
 // ErrStopEvent is being sent to the process when the watcher is being ordered to stop
@@ -31,11 +45,15 @@ var ErrStopEvent = errors.New("received external stop event")
 // NewWatcher responsible for starting and keeping a process alive, restarting if necessary
 func NewWatcher(ctx context.Context, path string, args ...string) *Watcher {
 	w := &Watcher{
+		Name:        path,
 		Process:     nil,
 		close:       make(chan error),
 		events:      make(chan event.Event),
 		StopTimeout: 5 * time.Second,
 	}
+	// created up front, so subscriptions can be set up before starting the process
+	w.Broker = event.NewBroker(ctx, w.events)
+	w.Broker.Name = "watcher"
 
 	return w
 }
@@ -51,17 +69,37 @@ func (w *Watcher) Start(ctx context.Context) error {
 		go func() { w.close <- ctx.Err() }()
 	}()
 
+	errs := w.setup(ctx)
+	go func() {
+		if err := <-errs; err != nil {
+			w.close <- err
+		}
+	}()
+
+	log.From(ctx).Debug("running process")
+	if err := w.run(ctx); err != nil {
+		log.From(ctx).Error("running process", zap.Error(err))
+		w.exited(err)
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// setup the output handlers, the broker and the log tailers of the process, stopping it once ctx is closed
+// The returned channel receives the error the broker stopped with
+func (w *Watcher) setup(ctx context.Context) <-chan error {
 	rerr, stderr := io.Pipe()
 	rout, stdout := io.Pipe()
 
-	go w.OutputHandler(ctx, rerr, "StdErr")
+	go w.OutputHandler(ctx, rerr, TypeStdErr)()
 	go func() {
 		log.From(ctx).Debug("waiting for ctx to close", zap.String("span", "OutputHandler.StdErr"))
 		<-ctx.Done()
 		log.From(ctx).Debug("handling ctx close", zap.String("span", "OutputHandler.StdErr"))
 		stderr.CloseWithError(ctx.Err())
 	}()
-	go w.OutputHandler(ctx, rout, "StdOut")
+	go w.OutputHandler(ctx, rout, TypeStdOut)()
 	go func() {
 		log.From(ctx).Debug("waiting for ctx to close", zap.String("span", "OutputHandler.StdOut"))
 		<-ctx.Done()
@@ -69,15 +107,24 @@ func (w *Watcher) Start(ctx context.Context) error {
 		stdout.CloseWithError(ctx.Err())
 	}()
 
+	errs := make(chan error, 1)
 	go func() {
-		w.Broker = event.NewBroker(ctx, w.events)
 		log.From(ctx).Debug("running broker")
-		if err := w.Broker.Run(ctx); err != nil {
+		err := w.Broker.Run(ctx)
+		if err != nil {
 			log.From(ctx).Error("running broker", zap.Error(err))
-			w.close <- err
 		}
+		errs <- err
 	}()
 
+	for _, l := range w.Logs {
+		go func(l LogFile) {
+			if err := w.TailLog(ctx, l); err != nil && err != ctx.Err() {
+				log.From(ctx).Error("tailing log", zap.String("pattern", l.Pattern), zap.Error(err))
+			}
+		}(l)
+	}
+
 	w.Process.SetOut(stderr, stdout)
 	go func() {
 		log.From(ctx).Debug("waiting for ctx to close", zap.String("span", "Process.Stop"))
@@ -87,15 +134,7 @@ func (w *Watcher) Start(ctx context.Context) error {
 			log.From(ctx).Error("stopping process", zap.Error(err))
 		}
 	}()
-
-	log.From(ctx).Debug("running process")
-	if err := w.Process.Run(); err != nil {
-		log.From(ctx).Error("running process", zap.Error(err))
-		w.close <- err
-		return err
-	}
-
-	return ctx.Err()
+	return errs
 }
 
 // Stop the underlying process and all event handling routines
@@ -108,20 +147,92 @@ func (w *Watcher) KeepAlive(ctx context.Context) {
 	go func() {
 		if err := <-w.close; err != ErrStopEvent {
 			log.From(ctx).Info("handling close event", zap.Error(err))
+			w.crash(ctx, err)
 			w.KeepAlive(ctx)
 			go func() {
 				log.From(ctx).Debug("running process")
-				if err := w.Process.Run(); err != nil {
+				processRestarts.With(w.Name).Inc()
+				w.emit(ctx, TypeRestart, "")
+				if err := w.run(ctx); err != nil {
 					log.From(ctx).Error("running process", zap.Error(err))
-					w.close <- err
+					w.exited(err)
 				}
 			}()
 		}
 	}()
 }
 
+// exited passes err of the process exiting to KeepAlive
+// ErrStopEvent is passed instead if the process got stopped on purpose, so it is not revived
+func (w *Watcher) exited(err error) {
+	if atomic.SwapInt32(&w.stopping, 0) == 1 {
+		err = ErrStopEvent
+	}
+	if err != nil {
+		w.close <- err
+	}
+}
+
+// run the process while keeping track of its state in metrics
+func (w *Watcher) run(ctx context.Context) error {
+	if w.Prepare != nil {
+		if err := w.Prepare(ctx); err != nil {
+			log.From(ctx).Error("preparing process", zap.String("name", w.Name), zap.Error(err))
+		}
+	}
+	processStartTime.With(w.Name).Set(float64(time.Now().Unix()))
+	processRunning.With(w.Name).Set(1)
+	defer processRunning.With(w.Name).Set(0)
+	return w.Process.Run()
+}
+
+// crash of the process exiting with err emitted as event
+// Exit codes and signals of processes exiting with failure are added as attributes
+func (w *Watcher) crash(ctx context.Context, err error) {
+	processCrashes.With(w.Name).Inc()
+	e := w.event(TypeCrash, errString(err))
+	if exit, ok := err.(*exec.ExitError); ok {
+		if status, ok := exit.Sys().(syscall.WaitStatus); ok {
+			if status.Signaled() {
+				e.Set("crash.signal", status.Signal().String())
+			} else {
+				e.Set("crash.exit_code", strconv.Itoa(status.ExitStatus()))
+			}
+		}
+	}
+	w.emitRecord(ctx, e)
+}
+
+// emit a new event of kind with payload without blocking the caller
+func (w *Watcher) emit(ctx context.Context, kind event.Kind, payload string) {
+	w.emitRecord(ctx, w.event(kind, payload))
+}
+
+// emitRecord e without blocking the caller
+func (w *Watcher) emitRecord(ctx context.Context, e *event.Record) {
+	watcherEvents.With(w.Name, e.Kind()).Inc()
+	go func() {
+		select {
+		case w.events <- e:
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// event of kind with payload originating from the watched process
+func (w *Watcher) event(kind event.Kind, payload string) *event.Record {
+	return event.New(event.Source{Server: w.Name, Component: event.ComponentWatcher}, kind, payload)
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
 // OutputHandler returns a function reading from io.Reader and creating events
-func (w *Watcher) OutputHandler(ctx context.Context, r io.Reader, eventType string) func() error {
+func (w *Watcher) OutputHandler(ctx context.Context, r io.Reader, eventType event.Kind) func() error {
 	return func() error {
 		scn := bufio.NewScanner(r)
 		for {
@@ -130,11 +241,8 @@ func (w *Watcher) OutputHandler(ctx context.Context, r io.Reader, eventType stri
 				return ctx.Err()
 			default:
 				if scn.Scan() {
-					w.events <- &Event{
-						timestamp: time.Now(),
-						kind:      eventType,
-						payload:   scn.Text(),
-					}
+					watcherEvents.With(w.Name, string(eventType)).Inc()
+					w.events <- w.event(eventType, scn.Text())
 					continue
 				}
 				return errors.New("end of stream")
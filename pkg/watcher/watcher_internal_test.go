@@ -2,8 +2,10 @@ package watcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"os/exec"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -36,6 +38,37 @@ var _ = Describe("Watcher", func() {
 		// })
 	})
 
+	Describe("KeepAlive", func() {
+		It("does emit crash and restart events", func() {
+			ctx, w := setup()
+			w.Process = &nopProcess{}
+
+			w.KeepAlive(ctx)
+			w.close <- errors.New("test crash")
+			kinds := map[string]string{}
+			for i := 0; i < 2; i++ {
+				ev := <-w.events
+				kinds[ev.Kind()] = ev.Data()
+			}
+			Expect(kinds).To(HaveKeyWithValue(string(TypeCrash), "test crash"))
+			Expect(kinds).To(HaveKey(string(TypeRestart)))
+		})
+		It("does emit the exit code of crashed processes", func() {
+			ctx, w := setup()
+			w.Process = &nopProcess{}
+
+			w.KeepAlive(ctx)
+			w.close <- exec.Command("sh", "-c", "exit 3").Run()
+			for {
+				ev := <-w.events
+				if ev.Kind() == string(TypeCrash) {
+					Expect(ev.(*Event).Attributes).To(HaveKeyWithValue("crash.exit_code", "3"))
+					break
+				}
+			}
+		})
+	})
+
 	Describe("OutputHandler", func() {
 		var (
 			re *io.PipeReader
@@ -68,3 +101,10 @@ var _ = Describe("Watcher", func() {
 		})
 	})
 })
+
+type nopProcess struct{}
+
+func (p *nopProcess) SetOut(io.Writer, io.Writer) {}
+func (p *nopProcess) Run() error                  { return nil }
+func (p *nopProcess) Stop() error                 { return nil }
+func (p *nopProcess) PID() int                    { return 0 }
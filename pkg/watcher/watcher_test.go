@@ -3,9 +3,11 @@ package watcher_test
 import (
 	"context"
 	"errors"
+	"io"
 	"testing"
 	"time"
 
+	"github.com/playnet-public/gorcon/pkg/event"
 	"github.com/playnet-public/gorcon/pkg/mocks"
 	"github.com/playnet-public/gorcon/pkg/watcher"
 
@@ -62,6 +64,37 @@ var _ = Describe("Watcher", func() {
 			w.Stop(ctx)
 			w.Start(ctx)
 		})
+		It("does publish process output", func() {
+			ctx, w, p := setup("Start.does publish process output")
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			release := make(chan struct{})
+			p.RunStub = func() error {
+				<-release
+				stderr, stdout := p.SetOutArgsForCall(0)
+				io.WriteString(stdout, "hello\n")
+				io.WriteString(stderr, "failed\n")
+				<-ctx.Done()
+				return nil
+			}
+
+			go w.Start(ctx)
+			c := make(chan event.Event, 2)
+			w.Subscribe(ctx, c)
+			close(release)
+
+			// both outputs are handled independently, so their order is not defined
+			lines := map[string]string{}
+			for i := 0; i < 2; i++ {
+				var e event.Event
+				Eventually(c).Should(Receive(&e))
+				lines[e.Kind()] = e.Data()
+			}
+			Expect(lines).To(Equal(map[string]string{
+				string(watcher.TypeStdOut): "hello",
+				string(watcher.TypeStdErr): "failed",
+			}))
+		})
 		It("does exit all functions on ctx close", func() {
 			ctx, w, p := setup("Start.does exit all functions on ctx close")
 			ctx, close := context.WithCancel(ctx)
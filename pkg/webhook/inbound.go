@@ -0,0 +1,120 @@
+package webhook
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/playnet-public/gorcon/pkg/rcon"
+	"github.com/playnet-public/gorcon/pkg/rcon/battleye"
+	"github.com/playnet-public/gorcon/pkg/trace"
+
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// Inbound is a http.Handler receiving webhook posts and sending them to all players on the server
+// It accepts json bodies using Discord (content, username) or Slack (text, user_name) fields as well as form posts
+type Inbound struct {
+	Rcon rcon.Writer
+	// Token required as token parameter or bearer authorization. Requests are not authenticated if empty
+	Token string
+	// Prefix is put in front of every message sent to the server
+	Prefix string
+}
+
+type inboundMessage struct {
+	Content  string `json:"content"`
+	Text     string `json:"text"`
+	Username string `json:"username"`
+	UserName string `json:"user_name"`
+	Token    string `json:"token"`
+}
+
+// NewInbound handler sending messages to r
+func NewInbound(r rcon.Writer, token string) *Inbound {
+	return &Inbound{
+		Rcon:   r,
+		Token:  token,
+		Prefix: "[Web]",
+	}
+}
+
+// ServeHTTP handles a single webhook post continuing the trace of the caller if present
+func (i *Inbound) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.Start(trace.Extract(r.Context(), r.Header), "webhook.Inbound")
+	defer span.End()
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var msg inboundMessage
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "invalid form", http.StatusBadRequest)
+			return
+		}
+		msg.Content = r.PostForm.Get("content")
+		msg.Text = r.PostForm.Get("text")
+		msg.Username = r.PostForm.Get("username")
+		msg.UserName = r.PostForm.Get("user_name")
+		msg.Token = r.PostForm.Get("token")
+	}
+
+	if !i.authorized(r, msg.Token) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	text := strings.TrimSpace(first(msg.Content, msg.Text))
+	if text == "" {
+		http.Error(w, "empty message", http.StatusBadRequest)
+		return
+	}
+	// messages must not span multiple lines on the server
+	text = strings.Join(strings.Fields(text), " ")
+	if user := first(msg.Username, msg.UserName); user != "" {
+		text = fmt.Sprintf("%s: %s", user, text)
+	}
+	if i.Prefix != "" {
+		text = i.Prefix + " " + text
+	}
+
+	if _, err := i.Rcon.Write(ctx, battleye.Say(battleye.Everyone, text)); err != nil {
+		span.RecordError(err)
+		log.From(ctx).Error("sending inbound message", zap.Error(err))
+		http.Error(w, "sending message failed", http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (i *Inbound) authorized(r *http.Request, token string) bool {
+	if i.Token == "" {
+		return true
+	}
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	if token == "" {
+		token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(i.Token)) == 1
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package webhook_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/playnet-public/gorcon/pkg/mocks"
+	"github.com/playnet-public/gorcon/pkg/webhook"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Inbound", func() {
+	var (
+		r   *mocks.RconWriter
+		in  *webhook.Inbound
+		srv *httptest.Server
+	)
+
+	BeforeEach(func() {
+		r = &mocks.RconWriter{}
+		in = webhook.NewInbound(r, "secret")
+		srv = httptest.NewServer(in)
+	})
+
+	AfterEach(func() {
+		srv.Close()
+	})
+
+	postJSON := func(path, body string) *http.Response {
+		resp, err := http.Post(srv.URL+path, "application/json", strings.NewReader(body))
+		Expect(err).To(BeNil())
+		return resp
+	}
+
+	It("does send discord style messages to everyone", func() {
+		resp := postJSON("?token=secret", `{"content":"hello","username":"Admin"}`)
+		Expect(resp.StatusCode).To(BeEquivalentTo(http.StatusNoContent))
+		_, cmd := r.WriteArgsForCall(0)
+		Expect(cmd).To(BeEquivalentTo("say -1 [Web] Admin: hello"))
+	})
+	It("does accept slack style form posts", func() {
+		resp, err := http.PostForm(srv.URL, url.Values{"text": {"hi"}, "user_name": {"bob"}, "token": {"secret"}})
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(BeEquivalentTo(http.StatusNoContent))
+		_, cmd := r.WriteArgsForCall(0)
+		Expect(cmd).To(BeEquivalentTo("say -1 [Web] bob: hi"))
+	})
+	It("does accept bearer tokens", func() {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(`{"text":"hi"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(BeEquivalentTo(http.StatusNoContent))
+	})
+	It("does flatten multi line messages", func() {
+		postJSON("?token=secret", `{"content":"hello\nworld"}`)
+		_, cmd := r.WriteArgsForCall(0)
+		Expect(cmd).To(BeEquivalentTo("say -1 [Web] hello world"))
+	})
+	It("does reject invalid tokens", func() {
+		resp := postJSON("?token=wrong", `{"content":"hello"}`)
+		Expect(resp.StatusCode).To(BeEquivalentTo(http.StatusUnauthorized))
+		Expect(r.WriteCallCount()).To(BeEquivalentTo(0))
+	})
+	It("does reject empty messages", func() {
+		resp := postJSON("?token=secret", `{"content":"  "}`)
+		Expect(resp.StatusCode).To(BeEquivalentTo(http.StatusBadRequest))
+	})
+	It("does reject invalid bodies", func() {
+		resp := postJSON("?token=secret", `{`)
+		Expect(resp.StatusCode).To(BeEquivalentTo(http.StatusBadRequest))
+	})
+	It("does reject other methods", func() {
+		resp, err := http.Get(srv.URL)
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(BeEquivalentTo(http.StatusMethodNotAllowed))
+	})
+	It("does report failing rcon", func() {
+		r.WriteReturns(nil, errors.New("test"))
+		resp := postJSON("?token=secret", `{"content":"hello"}`)
+		Expect(resp.StatusCode).To(BeEquivalentTo(http.StatusBadGateway))
+	})
+	It("does not require a token if none is configured", func() {
+		in.Token = ""
+		resp := postJSON("", `{"content":"hello"}`)
+		Expect(resp.StatusCode).To(BeEquivalentTo(http.StatusNoContent))
+	})
+})
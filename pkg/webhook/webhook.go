@@ -0,0 +1,318 @@
+// Package webhook bridges events to chat platforms like Discord or Slack and allows posting messages back to the server
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+	"github.com/playnet-public/gorcon/pkg/rcon"
+	"github.com/playnet-public/gorcon/pkg/rcon/battleye"
+	"github.com/playnet-public/gorcon/pkg/trace"
+	"github.com/playnet-public/gorcon/pkg/watcher"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// Format of the payload posted to a webhook
+type Format string
+
+const (
+	// Discord posts {"content": text}
+	Discord Format = "discord"
+	// Slack posts {"text": text}
+	Slack Format = "slack"
+	// Generic posts the rendered text alongside the raw event fields
+	Generic Format = "generic"
+)
+
+// DefaultTemplate renders the event kind followed by its data
+var DefaultTemplate = template.Must(template.New("default").Parse("[{{.Kind}}] {{.Data}}"))
+
+// Filter selects the events being posted to a hook
+type Filter func(event.Event) bool
+
+// Kinds selects events of any of kinds
+func Kinds(kinds ...event.Kind) Filter {
+	return func(e event.Event) bool {
+		for _, k := range kinds {
+			if e.Kind() == string(k) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Any selects events matching at least one of filters
+func Any(filters ...Filter) Filter {
+	return func(e event.Event) bool {
+		for _, f := range filters {
+			if f(e) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Chat selects chat messages
+var Chat = Kinds(rcon.TypeChat)
+
+// Kicks selects players being kicked for reasons other than bans
+var Kicks Filter = func(e event.Event) bool {
+	p, ok := kicked(e)
+	return ok && !strings.Contains(p.Reason, "Ban")
+}
+
+// Bans selects players being kicked because of a ban
+var Bans Filter = func(e event.Event) bool {
+	p, ok := kicked(e)
+	return ok && strings.Contains(p.Reason, "Ban")
+}
+
+// Restarts selects processes being restarted by the watcher
+var Restarts = Kinds(watcher.TypeRestart)
+
+// Crashes selects processes exiting unexpectedly
+var Crashes = Kinds(watcher.TypeCrash)
+
+func kicked(e event.Event) (*battleye.PlayerEvent, bool) {
+	if e.Kind() != string(rcon.TypePlayer) {
+		return nil, false
+	}
+	p, err := battleye.ParsePlayerEvent(e.Data())
+	if err != nil || p.Kind != battleye.PlayerKicked {
+		return nil, false
+	}
+	return p, true
+}
+
+// Message is passed to templates when rendering events
+type Message struct {
+	Timestamp time.Time
+	Kind      string
+	Data      string
+}
+
+// NewMessage from e resolving rcon kinds to readable names
+func NewMessage(e event.Event) Message {
+	return Message{
+		Timestamp: e.Timestamp(),
+		Kind:      rcon.KindName(e.Kind()),
+		Data:      e.Data(),
+	}
+}
+
+// Hook posts events selected by Filter to URL
+type Hook struct {
+	URL    string
+	Format Format
+	// Filter selects the events being posted. All events are posted if nil
+	Filter Filter
+	// Template renders the text being posted. DefaultTemplate is used if nil
+	Template *template.Template
+	// Username overrides the name of the posting bot for formats supporting it
+	Username string
+}
+
+// host of URL identifying the hook in logs and traces, as the path of webhook urls often contains their token
+func (h *Hook) host() string {
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// Payload renders e to the json body posted to the hook
+func (h *Hook) Payload(e event.Event) ([]byte, error) {
+	tpl := h.Template
+	if tpl == nil {
+		tpl = DefaultTemplate
+	}
+	msg := NewMessage(e)
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, msg); err != nil {
+		return nil, errors.Wrap(err, "rendering template")
+	}
+	text := buf.String()
+
+	var body interface{}
+	switch h.Format {
+	case Discord:
+		body = struct {
+			Content  string `json:"content"`
+			Username string `json:"username,omitempty"`
+		}{text, h.Username}
+	case Slack:
+		body = struct {
+			Text     string `json:"text"`
+			Username string `json:"username,omitempty"`
+		}{text, h.Username}
+	default:
+		body = struct {
+			Timestamp time.Time `json:"timestamp"`
+			Kind      string    `json:"kind"`
+			Data      string    `json:"data"`
+			Text      string    `json:"text"`
+		}{msg.Timestamp, msg.Kind, msg.Data, text}
+	}
+	return json.Marshal(body)
+}
+
+// Notifier posts events to hooks while respecting rate limits and retrying failed posts
+type Notifier struct {
+	Hooks  []*Hook
+	Client *http.Client
+
+	// Interval is the minimum time between two posts to the same hook
+	Interval time.Duration
+	// Retries of failed posts before dropping the event
+	Retries int
+	// Backoff between retries. It doubles with every attempt unless the hook requested a specific delay
+	Backoff time.Duration
+	// QueueSize per hook. Events exceeding it are dropped
+	QueueSize int
+}
+
+// NewNotifier posting to hooks
+func NewNotifier(hooks ...*Hook) *Notifier {
+	return &Notifier{
+		Hooks:     hooks,
+		Client:    http.DefaultClient,
+		Interval:  time.Second,
+		Retries:   3,
+		Backoff:   time.Second,
+		QueueSize: 100,
+	}
+}
+
+// Run the notifier posting all matching events received on in until ctx is closed or in gets closed
+func (n *Notifier) Run(ctx context.Context, in <-chan event.Event) error {
+	queues := make([]chan event.Event, len(n.Hooks))
+	for i, h := range n.Hooks {
+		queues[i] = make(chan event.Event, n.QueueSize)
+		go n.worker(ctx, h, queues[i])
+	}
+	defer func() {
+		for _, q := range queues {
+			close(q)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.From(ctx).Info("stopping notifier", zap.Error(ctx.Err()))
+			return ctx.Err()
+		case e, ok := <-in:
+			if !ok {
+				log.From(ctx).Info("stopping notifier")
+				return event.ErrInputClosed
+			}
+			for i, h := range n.Hooks {
+				if h.Filter != nil && !h.Filter(e) {
+					continue
+				}
+				select {
+				case queues[i] <- e:
+				default:
+					log.From(ctx).Warn("dropping event", zap.String("hook", h.host()), zap.String("reason", "queue full"))
+				}
+			}
+		}
+	}
+}
+
+func (n *Notifier) worker(ctx context.Context, h *Hook, queue <-chan event.Event) {
+	for e := range queue {
+		if err := n.Post(ctx, h, e); err != nil {
+			log.From(ctx).Error("posting event", zap.String("hook", h.host()), zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(n.Interval):
+		}
+	}
+}
+
+// Post e to h retrying on failures and rate limit responses
+func (n *Notifier) Post(ctx context.Context, h *Hook, e event.Event) (err error) {
+	ctx, span := trace.Start(ctx, "webhook.Post")
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	span.SetAttribute("webhook.host", h.host())
+
+	body, err := h.Payload(e)
+	if err != nil {
+		return err
+	}
+
+	backoff := n.Backoff
+	for attempt := 0; ; attempt++ {
+		wait, err := n.post(ctx, h.URL, body)
+		if err == nil {
+			return nil
+		}
+		if attempt >= n.Retries {
+			return errors.Wrapf(err, "giving up after %d attempts", attempt+1)
+		}
+		if wait <= 0 {
+			wait = backoff
+			backoff *= 2
+		}
+		log.From(ctx).Debug("retrying post", zap.String("hook", h.host()), zap.Duration("wait", wait), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// post body to url returning the delay requested by the server on rate limits
+func (n *Notifier) post(ctx context.Context, target string, body []byte) (time.Duration, error) {
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, errors.Wrap(redact(err, ""), "building request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	trace.Inject(ctx, req.Header)
+	resp, err := n.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, errors.Wrap(redact(err, req.URL.Host), "posting")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return 0, nil
+	}
+	var wait time.Duration
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if s, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64); err == nil {
+			wait = time.Duration(s * float64(time.Second))
+		}
+	}
+	return wait, errors.Errorf("unexpected status %d", resp.StatusCode)
+}
+
+// redact the url of err to host, as it contains the token of the hook
+func redact(err error, host string) error {
+	if uerr, ok := err.(*url.Error); ok {
+		uerr.URL = host
+	}
+	return err
+}
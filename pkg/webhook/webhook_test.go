@@ -0,0 +1,209 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+	"github.com/playnet-public/gorcon/pkg/rcon"
+	"github.com/playnet-public/gorcon/pkg/watcher"
+	"github.com/playnet-public/gorcon/pkg/webhook"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestWebhook(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Webhook Suite")
+}
+
+type fakeEvent struct {
+	kind string
+	data string
+}
+
+func (f *fakeEvent) Timestamp() time.Time { return time.Time{} }
+func (f *fakeEvent) Kind() string         { return f.kind }
+func (f *fakeEvent) Data() string         { return f.data }
+
+// recorder is a local stand-in for webhook endpoints
+type recorder struct {
+	m        sync.Mutex
+	bodies   []map[string]interface{}
+	statuses []int
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	raw, _ := ioutil.ReadAll(req.Body)
+	body := map[string]interface{}{}
+	json.Unmarshal(raw, &body)
+	r.bodies = append(r.bodies, body)
+	status := http.StatusNoContent
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	if status == http.StatusTooManyRequests {
+		w.Header().Set("Retry-After", "0.001")
+	}
+	w.WriteHeader(status)
+}
+
+func (r *recorder) count() int {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return len(r.bodies)
+}
+
+func (r *recorder) body(i int) map[string]interface{} {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.bodies[i]
+}
+
+const kickMsg = "Player #1 Test (0123456789abcdef0123456789abcdef) has been kicked by BattlEye: Admin Kick"
+const banMsg = "Player #1 Test (0123456789abcdef0123456789abcdef) has been kicked by BattlEye: Admin Ban (cheating)"
+
+var _ = Describe("Filters", func() {
+	It("does select chat", func() {
+		Expect(webhook.Chat(rcon.NewEvent(rcon.TypeChat, "(Global) Test: hi"))).To(BeTrue())
+		Expect(webhook.Chat(rcon.NewEvent(rcon.TypeEvent, "test"))).To(BeFalse())
+	})
+	It("does select kicks", func() {
+		Expect(webhook.Kicks(rcon.NewEvent(rcon.TypePlayer, kickMsg))).To(BeTrue())
+		Expect(webhook.Kicks(rcon.NewEvent(rcon.TypePlayer, banMsg))).To(BeFalse())
+		Expect(webhook.Kicks(rcon.NewEvent(rcon.TypePlayer, "Player #1 Test disconnected"))).To(BeFalse())
+	})
+	It("does select bans", func() {
+		Expect(webhook.Bans(rcon.NewEvent(rcon.TypePlayer, banMsg))).To(BeTrue())
+		Expect(webhook.Bans(rcon.NewEvent(rcon.TypePlayer, kickMsg))).To(BeFalse())
+	})
+	It("does select watcher events", func() {
+		Expect(webhook.Crashes(&fakeEvent{kind: string(watcher.TypeCrash)})).To(BeTrue())
+		Expect(webhook.Restarts(&fakeEvent{kind: string(watcher.TypeRestart)})).To(BeTrue())
+	})
+	It("does combine filters", func() {
+		f := webhook.Any(webhook.Crashes, webhook.Chat)
+		Expect(f(&fakeEvent{kind: string(watcher.TypeCrash)})).To(BeTrue())
+		Expect(f(&fakeEvent{kind: string(watcher.TypeStdOut)})).To(BeFalse())
+	})
+})
+
+var _ = Describe("Hook", func() {
+	Describe("Payload", func() {
+		It("does render discord payloads", func() {
+			h := &webhook.Hook{Format: webhook.Discord, Username: "gorcon"}
+			b, err := h.Payload(rcon.NewEvent(rcon.TypeChat, "(Global) Test: hi"))
+			Expect(err).To(BeNil())
+			Expect(string(b)).To(MatchJSON(`{"content":"[chat] (Global) Test: hi","username":"gorcon"}`))
+		})
+		It("does render slack payloads", func() {
+			h := &webhook.Hook{Format: webhook.Slack}
+			b, _ := h.Payload(&fakeEvent{kind: "Crash", data: "exit status 1"})
+			Expect(string(b)).To(MatchJSON(`{"text":"[Crash] exit status 1"}`))
+		})
+		It("does render generic payloads", func() {
+			h := &webhook.Hook{Format: webhook.Generic}
+			b, _ := h.Payload(&fakeEvent{kind: "Crash", data: "exit"})
+			body := map[string]interface{}{}
+			json.Unmarshal(b, &body)
+			Expect(body).To(HaveKeyWithValue("kind", "Crash"))
+			Expect(body).To(HaveKeyWithValue("data", "exit"))
+			Expect(body).To(HaveKeyWithValue("text", "[Crash] exit"))
+		})
+		It("does use custom templates", func() {
+			h := &webhook.Hook{Format: webhook.Slack, Template: template.Must(template.New("").Parse("server crashed: {{.Data}}"))}
+			b, _ := h.Payload(&fakeEvent{kind: "Crash", data: "exit"})
+			Expect(string(b)).To(MatchJSON(`{"text":"server crashed: exit"}`))
+		})
+		It("does return error on failing templates", func() {
+			h := &webhook.Hook{Template: template.Must(template.New("").Parse("{{.Missing}}"))}
+			_, err := h.Payload(&fakeEvent{})
+			Expect(err).NotTo(BeNil())
+		})
+	})
+})
+
+var _ = Describe("Notifier", func() {
+	var (
+		ctx context.Context
+		rec *recorder
+		srv *httptest.Server
+		h   *webhook.Hook
+		n   *webhook.Notifier
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		rec = &recorder{}
+		srv = httptest.NewServer(rec)
+		h = &webhook.Hook{URL: srv.URL, Format: webhook.Discord}
+		n = webhook.NewNotifier(h)
+		n.Interval = time.Millisecond
+		n.Backoff = time.Millisecond
+	})
+
+	AfterEach(func() {
+		srv.Close()
+	})
+
+	Describe("Post", func() {
+		It("does post payload", func() {
+			Expect(n.Post(ctx, h, &fakeEvent{kind: "Crash", data: "exit"})).To(BeNil())
+			Expect(rec.body(0)).To(HaveKeyWithValue("content", "[Crash] exit"))
+		})
+		It("does retry failed posts", func() {
+			rec.statuses = []int{http.StatusInternalServerError, http.StatusTooManyRequests}
+			Expect(n.Post(ctx, h, &fakeEvent{})).To(BeNil())
+			Expect(rec.count()).To(BeEquivalentTo(3))
+		})
+		It("does give up after retries", func() {
+			n.Retries = 1
+			rec.statuses = []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusNoContent}
+			Expect(n.Post(ctx, h, &fakeEvent{})).NotTo(BeNil())
+			Expect(rec.count()).To(BeEquivalentTo(2))
+		})
+		It("does not return the token of the url in errors", func() {
+			n.Retries = 0
+			h.URL = srv.URL + "/api/webhooks/1/token"
+			srv.Close()
+			err := n.Post(ctx, h, &fakeEvent{})
+			Expect(err).NotTo(BeNil())
+			Expect(err.Error()).NotTo(ContainSubstring("token"))
+		})
+	})
+
+	Describe("Run", func() {
+		It("does exit on closed context", func() {
+			ctx, close := context.WithCancel(ctx)
+			close()
+			Expect(n.Run(ctx, make(chan event.Event))).To(BeEquivalentTo(context.Canceled))
+		})
+		It("does return error on closed input", func() {
+			in := make(chan event.Event)
+			close(in)
+			Expect(n.Run(ctx, in)).To(BeEquivalentTo(event.ErrInputClosed))
+		})
+		It("does only post selected events", func() {
+			ctx, close := context.WithCancel(ctx)
+			defer close()
+			h.Filter = webhook.Crashes
+			in := make(chan event.Event)
+			go n.Run(ctx, in)
+			in <- &fakeEvent{kind: "StdOut", data: "line"}
+			in <- &fakeEvent{kind: string(watcher.TypeCrash), data: "exit"}
+			Eventually(rec.count).Should(BeEquivalentTo(1))
+			Consistently(rec.count, 20*time.Millisecond).Should(BeEquivalentTo(1))
+			Expect(rec.body(0)).To(HaveKeyWithValue("content", "[Crash] exit"))
+		})
+	})
+})
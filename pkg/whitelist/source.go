@@ -0,0 +1,128 @@
+package whitelist
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Source decides whether a player guid is allowed to join the server
+//go:generate counterfeiter -o ../mocks/whitelist_source.go --fake-name WhitelistSource . Source
+type Source interface {
+	Allowed(context.Context, string) (bool, error)
+}
+
+// List is a local Source holding all allowed guids in memory
+type List struct {
+	m     sync.RWMutex
+	guids map[string]struct{}
+}
+
+// NewList containing guids
+func NewList(guids ...string) *List {
+	l := &List{guids: make(map[string]struct{})}
+	for _, g := range guids {
+		l.Add(g)
+	}
+	return l
+}
+
+// ReadList from r containing one guid per line. Empty lines and lines starting with # or // are ignored
+func ReadList(r io.Reader) (*List, error) {
+	l := NewList()
+	scn := bufio.NewScanner(r)
+	for scn.Scan() {
+		line := strings.TrimSpace(scn.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		l.Add(strings.Fields(line)[0])
+	}
+	if err := scn.Err(); err != nil {
+		return nil, errors.Wrap(err, "reading list")
+	}
+	return l, nil
+}
+
+// LoadList from the file at path
+func LoadList(path string) (*List, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening list")
+	}
+	defer f.Close()
+	return ReadList(f)
+}
+
+// Add guid to the list
+func (l *List) Add(guid string) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	l.guids[strings.ToLower(guid)] = struct{}{}
+}
+
+// Remove guid from the list
+func (l *List) Remove(guid string) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	delete(l.guids, strings.ToLower(guid))
+}
+
+// Allowed returns true if guid is on the list
+func (l *List) Allowed(ctx context.Context, guid string) (bool, error) {
+	l.m.RLock()
+	defer l.m.RUnlock()
+	_, ok := l.guids[strings.ToLower(guid)]
+	return ok, nil
+}
+
+// HTTPSource is an external Source asking a http endpoint for every guid
+// The guid gets passed as query parameter and the endpoint has to respond with 200 for allowed or 404 for unknown guids
+type HTTPSource struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewHTTPSource requesting rawurl
+func NewHTTPSource(rawurl string) *HTTPSource {
+	return &HTTPSource{
+		URL:    rawurl,
+		Client: http.DefaultClient,
+	}
+}
+
+// Allowed returns true if the endpoint responded with 200 for guid
+func (s *HTTPSource) Allowed(ctx context.Context, guid string) (bool, error) {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return false, errors.Wrap(err, "parsing url")
+	}
+	q := u.Query()
+	q.Set("guid", guid)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return false, errors.Wrap(err, "building request")
+	}
+	resp, err := s.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return false, errors.Wrap(err, "requesting source")
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, errors.Errorf("unexpected status %d", resp.StatusCode)
+}
@@ -0,0 +1,109 @@
+package whitelist_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/playnet-public/gorcon/pkg/whitelist"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Source", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	Describe("List", func() {
+		It("does allow listed guids", func() {
+			ok, err := whitelist.NewList(guid).Allowed(ctx, guid)
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeTrue())
+		})
+		It("does ignore guid case", func() {
+			ok, _ := whitelist.NewList(strings.ToUpper(guid)).Allowed(ctx, guid)
+			Expect(ok).To(BeTrue())
+		})
+		It("does not allow unknown guids", func() {
+			ok, _ := whitelist.NewList(guid).Allowed(ctx, vipGUID)
+			Expect(ok).To(BeFalse())
+		})
+		It("does not allow removed guids", func() {
+			l := whitelist.NewList(guid)
+			l.Remove(guid)
+			ok, _ := l.Allowed(ctx, guid)
+			Expect(ok).To(BeFalse())
+		})
+		It("does read guids skipping comments", func() {
+			l, err := whitelist.ReadList(strings.NewReader("# admins\n" + guid + " some admin\n\n// " + vipGUID + "\n"))
+			Expect(err).To(BeNil())
+			ok, _ := l.Allowed(ctx, guid)
+			Expect(ok).To(BeTrue())
+			ok, _ = l.Allowed(ctx, vipGUID)
+			Expect(ok).To(BeFalse())
+		})
+		It("does load lists from file", func() {
+			dir, err := ioutil.TempDir("", "whitelist")
+			Expect(err).To(BeNil())
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "whitelist.txt")
+			Expect(ioutil.WriteFile(path, []byte(guid+"\n"), 0644)).To(BeNil())
+			l, err := whitelist.LoadList(path)
+			Expect(err).To(BeNil())
+			ok, _ := l.Allowed(ctx, guid)
+			Expect(ok).To(BeTrue())
+		})
+		It("does return error on missing file", func() {
+			_, err := whitelist.LoadList("/does/not/exist")
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
+	Describe("HTTPSource", func() {
+		var srv *httptest.Server
+
+		BeforeEach(func() {
+			srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Query().Get("guid") {
+				case guid:
+					w.WriteHeader(http.StatusOK)
+				case vipGUID:
+					w.WriteHeader(http.StatusInternalServerError)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+		})
+
+		AfterEach(func() {
+			srv.Close()
+		})
+
+		It("does allow guids known to the endpoint", func() {
+			ok, err := whitelist.NewHTTPSource(srv.URL).Allowed(ctx, guid)
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeTrue())
+		})
+		It("does not allow unknown guids", func() {
+			ok, err := whitelist.NewHTTPSource(srv.URL).Allowed(ctx, "unknown")
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeFalse())
+		})
+		It("does return error on unexpected status", func() {
+			_, err := whitelist.NewHTTPSource(srv.URL).Allowed(ctx, vipGUID)
+			Expect(err).NotTo(BeNil())
+		})
+		It("does return error on unreachable endpoint", func() {
+			_, err := whitelist.NewHTTPSource("http://127.0.0.1:1").Allowed(ctx, guid)
+			Expect(err).NotTo(BeNil())
+		})
+	})
+})
@@ -0,0 +1,178 @@
+// Package whitelist enforces allowlists and reserved slots on BattlEye servers based on guid verification events
+package whitelist
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+	"github.com/playnet-public/gorcon/pkg/rcon"
+	"github.com/playnet-public/gorcon/pkg/rcon/battleye"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// Whitelist kicks players not allowed by Source and frees reserved slots for prioritized players
+type Whitelist struct {
+	Rcon   rcon.Writer
+	Source Source
+
+	// KickMessage is sent to players not being whitelisted
+	KickMessage string
+	// FailClosed kicks players if the Source returns an error, otherwise they are allowed to stay
+	FailClosed bool
+
+	// Slots is the number of public slots on the server. Reserved slots are not enforced if zero
+	Slots int
+	// Reserved maps lowercase guids to their priority. Players not listed have priority zero
+	Reserved map[string]int
+	// ReservedMessage is sent to players being kicked for freeing a reserved slot
+	ReservedMessage string
+
+	m       sync.Mutex
+	players map[int]*player
+}
+
+type player struct {
+	id       int
+	name     string
+	guid     string
+	priority int
+	joined   time.Time
+}
+
+// New Whitelist sending kicks to r and checking guids against src. A nil src disables the allowlist
+func New(r rcon.Writer, src Source) *Whitelist {
+	return &Whitelist{
+		Rcon:            r,
+		Source:          src,
+		KickMessage:     "Not whitelisted",
+		Reserved:        make(map[string]int),
+		ReservedMessage: "Slot reserved",
+		players:         make(map[int]*player),
+	}
+}
+
+// Run the whitelist handling all events received on in until ctx is closed or in gets closed
+func (w *Whitelist) Run(ctx context.Context, in <-chan event.Event) error {
+	for {
+		select {
+		case <-ctx.Done():
+			log.From(ctx).Info("stopping whitelist", zap.Error(ctx.Err()))
+			return ctx.Err()
+		case e, ok := <-in:
+			if !ok {
+				log.From(ctx).Info("stopping whitelist")
+				return event.ErrInputClosed
+			}
+			if err := w.Handle(ctx, e); err != nil {
+				log.From(ctx).Error("handling event", zap.String("data", e.Data()), zap.Error(err))
+			}
+		}
+	}
+}
+
+// Handle a single event by tracking players and enforcing the whitelist on guid verification
+func (w *Whitelist) Handle(ctx context.Context, e event.Event) error {
+	if e.Kind() != string(rcon.TypePlayer) {
+		return nil
+	}
+	p, err := battleye.ParsePlayerEvent(e.Data())
+	if err != nil {
+		return errors.Wrap(err, "parsing player event")
+	}
+
+	switch p.Kind {
+	case battleye.PlayerConnected:
+		w.join(p.ID, p.Name)
+	case battleye.PlayerGUIDVerified:
+		return w.verify(ctx, p)
+	case battleye.PlayerDisconnected, battleye.PlayerKicked:
+		w.leave(p.ID)
+	}
+	return nil
+}
+
+// Count returns the number of players currently tracked
+func (w *Whitelist) Count() int {
+	w.m.Lock()
+	defer w.m.Unlock()
+	return len(w.players)
+}
+
+func (w *Whitelist) join(id int, name string) *player {
+	w.m.Lock()
+	defer w.m.Unlock()
+	p, ok := w.players[id]
+	if !ok {
+		p = &player{id: id, name: name, joined: time.Now()}
+		w.players[id] = p
+	}
+	return p
+}
+
+func (w *Whitelist) leave(id int) {
+	w.m.Lock()
+	defer w.m.Unlock()
+	delete(w.players, id)
+}
+
+func (w *Whitelist) verify(ctx context.Context, e *battleye.PlayerEvent) error {
+	p := w.join(e.ID, e.Name)
+
+	if w.Source != nil {
+		allowed, err := w.Source.Allowed(ctx, e.GUID)
+		if err != nil {
+			log.From(ctx).Error("checking whitelist", zap.String("guid", e.GUID), zap.Error(err))
+			allowed = !w.FailClosed
+		}
+		if !allowed {
+			log.From(ctx).Info("kicking player", zap.Int("id", e.ID), zap.String("guid", e.GUID), zap.String("reason", "not whitelisted"))
+			return w.kick(ctx, e.ID, w.KickMessage)
+		}
+	}
+
+	w.m.Lock()
+	p.guid = e.GUID
+	p.priority = w.Reserved[strings.ToLower(e.GUID)]
+	victim := w.lowest(p)
+	w.m.Unlock()
+
+	if victim == nil {
+		return nil
+	}
+	log.From(ctx).Info("kicking player", zap.Int("id", victim.id), zap.String("guid", victim.guid), zap.String("reason", "reserved slot"), zap.String("for", e.GUID))
+	return w.kick(ctx, victim.id, w.ReservedMessage)
+}
+
+// lowest returns the player to kick for freeing a slot for p or nil if no kick is required
+// The player with the lowest priority below p's priority gets chosen, preferring the one who joined last
+// The caller has to hold the lock
+func (w *Whitelist) lowest(p *player) *player {
+	if w.Slots < 1 || p.priority < 1 || len(w.players) <= w.Slots {
+		return nil
+	}
+	var victim *player
+	for _, c := range w.players {
+		if c == p || c.priority >= p.priority {
+			continue
+		}
+		if victim == nil || c.priority < victim.priority ||
+			(c.priority == victim.priority && c.joined.After(victim.joined)) {
+			victim = c
+		}
+	}
+	return victim
+}
+
+func (w *Whitelist) kick(ctx context.Context, id int, reason string) error {
+	w.leave(id)
+	if _, err := w.Rcon.Write(rcon.WithPriority(ctx, rcon.Moderation), battleye.Kick(id, reason)); err != nil {
+		return errors.Wrap(err, "kicking player")
+	}
+	return nil
+}
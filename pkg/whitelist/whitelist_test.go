@@ -0,0 +1,178 @@
+package whitelist_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/playnet-public/gorcon/pkg/event"
+	"github.com/playnet-public/gorcon/pkg/mocks"
+	"github.com/playnet-public/gorcon/pkg/rcon"
+	"github.com/playnet-public/gorcon/pkg/whitelist"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestWhitelist(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Whitelist Suite")
+}
+
+const (
+	guid    = "0123456789abcdef0123456789abcdef"
+	vipGUID = "fedcba9876543210fedcba9876543210"
+)
+
+func connected(id int) event.Event {
+	return rcon.NewEvent(rcon.TypePlayer, fmt.Sprintf("Player #%d Test%d (127.0.0.1:2304) connected", id, id))
+}
+
+func verified(id int, guid string) event.Event {
+	return rcon.NewEvent(rcon.TypePlayer, fmt.Sprintf("Verified GUID (%s) of player #%d Test%d", guid, id, id))
+}
+
+func disconnected(id int) event.Event {
+	return rcon.NewEvent(rcon.TypePlayer, fmt.Sprintf("Player #%d Test%d disconnected", id, id))
+}
+
+var _ = Describe("Whitelist", func() {
+	var (
+		ctx context.Context
+		r   *mocks.RconWriter
+		src *mocks.WhitelistSource
+		w   *whitelist.Whitelist
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		r = &mocks.RconWriter{}
+		src = &mocks.WhitelistSource{}
+		src.AllowedReturns(true, nil)
+		w = whitelist.New(r, src)
+	})
+
+	Describe("Run", func() {
+		It("does exit on closed context", func() {
+			ctx, close := context.WithCancel(ctx)
+			close()
+			Expect(w.Run(ctx, make(chan event.Event))).To(BeEquivalentTo(context.Canceled))
+		})
+		It("does return error on closed input", func() {
+			in := make(chan event.Event)
+			close(in)
+			Expect(w.Run(ctx, in)).To(BeEquivalentTo(event.ErrInputClosed))
+		})
+		It("does handle incoming events", func() {
+			in := make(chan event.Event)
+			go w.Run(ctx, in)
+			src.AllowedReturns(false, nil)
+			in <- verified(1, guid)
+			Eventually(r.WriteCallCount).Should(BeEquivalentTo(1))
+		})
+	})
+
+	Describe("Handle", func() {
+		It("does ignore non player events", func() {
+			Expect(w.Handle(ctx, rcon.NewEvent(rcon.TypeChat, "(Global) Test: hi"))).To(BeNil())
+			Expect(src.AllowedCallCount()).To(BeEquivalentTo(0))
+		})
+		It("does track connected players", func() {
+			Expect(w.Handle(ctx, connected(1))).To(BeNil())
+			Expect(w.Count()).To(BeEquivalentTo(1))
+		})
+		It("does forget disconnected players", func() {
+			w.Handle(ctx, connected(1))
+			w.Handle(ctx, disconnected(1))
+			Expect(w.Count()).To(BeEquivalentTo(0))
+		})
+		It("does check verified guids against source", func() {
+			w.Handle(ctx, verified(1, guid))
+			Expect(src.AllowedCallCount()).To(BeEquivalentTo(1))
+			_, g := src.AllowedArgsForCall(0)
+			Expect(g).To(BeEquivalentTo(guid))
+		})
+		It("does not kick whitelisted players", func() {
+			Expect(w.Handle(ctx, verified(1, guid))).To(BeNil())
+			Expect(r.WriteCallCount()).To(BeEquivalentTo(0))
+		})
+		It("does kick players not whitelisted with configured message", func() {
+			src.AllowedReturns(false, nil)
+			w.KickMessage = "go away"
+			Expect(w.Handle(ctx, verified(1, guid))).To(BeNil())
+			Expect(r.WriteCallCount()).To(BeEquivalentTo(1))
+			_, cmd := r.WriteArgsForCall(0)
+			Expect(cmd).To(BeEquivalentTo("kick 1 go away"))
+			Expect(w.Count()).To(BeEquivalentTo(0))
+		})
+		It("does return error if kicking fails", func() {
+			src.AllowedReturns(false, nil)
+			r.WriteReturns(nil, errors.New("test"))
+			Expect(w.Handle(ctx, verified(1, guid))).NotTo(BeNil())
+		})
+		It("does allow players if source fails", func() {
+			src.AllowedReturns(false, errors.New("test"))
+			w.Handle(ctx, verified(1, guid))
+			Expect(r.WriteCallCount()).To(BeEquivalentTo(0))
+		})
+		It("does kick players if source fails when failing closed", func() {
+			src.AllowedReturns(false, errors.New("test"))
+			w.FailClosed = true
+			w.Handle(ctx, verified(1, guid))
+			Expect(r.WriteCallCount()).To(BeEquivalentTo(1))
+		})
+		It("does not check guids without source", func() {
+			w.Source = nil
+			Expect(w.Handle(ctx, verified(1, guid))).To(BeNil())
+			Expect(r.WriteCallCount()).To(BeEquivalentTo(0))
+		})
+	})
+
+	Describe("Reserved", func() {
+		BeforeEach(func() {
+			w.Slots = 2
+			w.Reserved[vipGUID] = 10
+			w.Handle(ctx, connected(0))
+			w.Handle(ctx, verified(0, guid))
+			w.Handle(ctx, connected(1))
+			w.Handle(ctx, verified(1, guid))
+		})
+		It("does not kick if slots are left", func() {
+			w.Slots = 3
+			w.Handle(ctx, connected(2))
+			w.Handle(ctx, verified(2, vipGUID))
+			Expect(r.WriteCallCount()).To(BeEquivalentTo(0))
+		})
+		It("does not kick for players without priority", func() {
+			w.Handle(ctx, connected(2))
+			w.Handle(ctx, verified(2, guid))
+			Expect(r.WriteCallCount()).To(BeEquivalentTo(0))
+		})
+		It("does kick the player who joined last for a vip", func() {
+			w.ReservedMessage = "reserved"
+			w.Handle(ctx, connected(2))
+			w.Handle(ctx, verified(2, vipGUID))
+			Expect(r.WriteCallCount()).To(BeEquivalentTo(1))
+			_, cmd := r.WriteArgsForCall(0)
+			Expect(cmd).To(BeEquivalentTo("kick 1 reserved"))
+			Expect(w.Count()).To(BeEquivalentTo(2))
+		})
+		It("does kick the player with the lowest priority", func() {
+			w.Reserved[guid] = 5
+			w.Handle(ctx, verified(1, guid))
+			w.Handle(ctx, connected(2))
+			w.Handle(ctx, verified(2, vipGUID))
+			_, cmd := r.WriteArgsForCall(0)
+			Expect(cmd).To(HavePrefix("kick 0"))
+		})
+		It("does not kick players with equal priority", func() {
+			w.Reserved[guid] = 10
+			w.Handle(ctx, verified(0, guid))
+			w.Handle(ctx, verified(1, guid))
+			w.Handle(ctx, connected(2))
+			w.Handle(ctx, verified(2, vipGUID))
+			Expect(r.WriteCallCount()).To(BeEquivalentTo(0))
+		})
+	})
+})
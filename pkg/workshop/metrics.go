@@ -0,0 +1,10 @@
+package workshop
+
+import (
+	"github.com/playnet-public/gorcon/pkg/metrics"
+)
+
+var (
+	workshopSyncs   = metrics.NewCounterVec("gorcon_workshop_syncs_total", "Workshop synchronizations by result.", "result")
+	workshopUpdates = metrics.NewCounterVec("gorcon_workshop_updates_total", "Workshop items updated by item.", "item")
+)
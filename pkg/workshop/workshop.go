@@ -0,0 +1,379 @@
+// Package workshop keeps server mods in sync with items of the Steam Workshop using steamcmd
+package workshop
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/playnet-public/gorcon/pkg/fileutil"
+	"github.com/playnet-public/gorcon/pkg/watcher"
+
+	"github.com/pkg/errors"
+	"github.com/seibert-media/golibs/log"
+	"go.uber.org/zap"
+)
+
+// AppID of Arma 3 in the Steam Workshop
+const AppID = "107410"
+
+// Item of the Workshop to keep in sync
+type Item struct {
+	ID string
+	// Name of the mod directory without @. Defaults to ID
+	Name string
+	// Server mods are loaded using -serverMod instead of -mod
+	Server bool
+}
+
+// dir name of the mod
+func (i Item) dir() string {
+	name := i.Name
+	if name == "" {
+		name = i.ID
+	}
+	return "@" + strings.ToLower(name)
+}
+
+// Result of a synchronization
+type Result struct {
+	// Changed contains the mod directories which got updated
+	Changed []string
+	// Failed contains the download errors of items keeping their previous version
+	Failed map[string]string
+}
+
+// Syncer downloads the Workshop Items using steamcmd and installs them as mods with lowercase file names
+// Keys of items which got removed or no longer ship them are removed from KeysDir
+type Syncer struct {
+	// SteamCMD is the executable invoked for downloads. Any executable accepting the steamcmd args works
+	SteamCMD string
+	// Login used by steamcmd. Arma 3 items can be downloaded anonymously
+	Login string
+	AppID string
+	// Dir steamcmd downloads the items to
+	Dir string
+	// ModsDir receiving the mods as @name
+	ModsDir string
+	// KeysDir receiving the bikeys of all mods
+	KeysDir string
+	Items   []Item
+	// Process receives the -mod and -serverMod args of the Items on every start if set
+	Process *watcher.OSProcess
+
+	m sync.Mutex
+}
+
+// NewSyncer of items installing mods to modsDir and their keys to keysDir
+func NewSyncer(steamcmd, modsDir, keysDir string, items ...Item) *Syncer {
+	return &Syncer{
+		SteamCMD: steamcmd,
+		Login:    "anonymous",
+		AppID:    AppID,
+		Dir:      filepath.Join(modsDir, ".steamcmd"),
+		ModsDir:  modsDir,
+		KeysDir:  keysDir,
+		Items:    items,
+	}
+}
+
+var (
+	downloadedPattern = regexp.MustCompile(`Success\. Downloaded item (\d+) to "([^"]*)"`)
+	failedPattern     = regexp.MustCompile(`ERROR! Download item (\d+) failed \(([^)]*)\)`)
+)
+
+// Sync downloads all Items and installs the ones which changed since the previous sync
+// Items failing to download keep their installed version and are reported in the Result
+func (s *Syncer) Sync(ctx context.Context) (*Result, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	res := &Result{Failed: make(map[string]string)}
+	if len(s.Items) == 0 {
+		// nothing to download, but the keys of previously synced items have to go
+		state, err := s.load()
+		if err != nil || len(state) == 0 {
+			return res, err
+		}
+		s.prune(ctx, state)
+		return res, s.save(state)
+	}
+
+	out, err := s.download(ctx)
+	if err != nil {
+		workshopSyncs.With("error").Inc()
+		return res, err
+	}
+	content := make(map[string]string)
+	for _, m := range downloadedPattern.FindAllStringSubmatch(out, -1) {
+		content[m[1]] = m[2]
+	}
+	for _, m := range failedPattern.FindAllStringSubmatch(out, -1) {
+		res.Failed[m[1]] = m[2]
+	}
+
+	state, err := s.load()
+	if err != nil {
+		workshopSyncs.With("error").Inc()
+		return res, err
+	}
+	for _, i := range s.Items {
+		if _, ok := res.Failed[i.ID]; ok {
+			continue
+		}
+		src, ok := content[i.ID]
+		if !ok {
+			src = filepath.Join(s.Dir, "steamapps", "workshop", "content", s.AppID, i.ID)
+		}
+		sum, err := fingerprint(src)
+		if err != nil {
+			res.Failed[i.ID] = err.Error()
+			continue
+		}
+		target := filepath.Join(s.ModsDir, i.dir())
+		prev := state[i.ID]
+		if _, err := os.Stat(target); err == nil && prev.Sum == sum {
+			continue
+		}
+		keys, err := s.install(src, target)
+		if err != nil {
+			res.Failed[i.ID] = err.Error()
+			continue
+		}
+		state[i.ID] = installed{Sum: sum, Keys: keys}
+		s.removeKeys(ctx, state, prev.Keys)
+		res.Changed = append(res.Changed, i.dir())
+		workshopUpdates.With(i.ID).Inc()
+		log.From(ctx).Info("updated workshop item", zap.String("id", i.ID), zap.String("mod", i.dir()))
+	}
+	s.prune(ctx, state)
+	if err := s.save(state); err != nil {
+		workshopSyncs.With("error").Inc()
+		return res, err
+	}
+
+	if len(res.Failed) > 0 {
+		workshopSyncs.With("failed").Inc()
+		var ids []string
+		for id, reason := range res.Failed {
+			ids = append(ids, id+" ("+reason+")")
+		}
+		sort.Strings(ids)
+		return res, errors.Errorf("syncing items %s", strings.Join(ids, ", "))
+	}
+	workshopSyncs.With("ok").Inc()
+	return res, nil
+}
+
+// download all items using steamcmd returning its output
+func (s *Syncer) download(ctx context.Context) (string, error) {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return "", errors.Wrap(err, "creating download dir")
+	}
+	args := []string{"+force_install_dir", s.Dir, "+login", s.Login}
+	for _, i := range s.Items {
+		args = append(args, "+workshop_download_item", s.AppID, i.ID, "validate")
+	}
+	args = append(args, "+quit")
+
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.SteamCMD, args...)
+	cmd.Stdout, cmd.Stderr = &out, &out
+	log.From(ctx).Debug("running steamcmd", zap.Strings("args", args))
+	if err := cmd.Run(); err != nil {
+		return out.String(), errors.Wrapf(err, "running steamcmd: %s", tail(out.String()))
+	}
+	return out.String(), nil
+}
+
+// install the mod in src to target, replacing the previous version. The names of the installed keys are returned
+func (s *Syncer) install(src, target string) ([]string, error) {
+	tmp := target + ".tmp"
+	os.RemoveAll(tmp)
+	// Arma on Linux expects lowercase names
+	if err := fileutil.CopyTree(src, tmp, fileutil.Options{Rename: strings.ToLower, Replace: true}); err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+	keys, err := s.keys(tmp)
+	if err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+	if err := os.RemoveAll(target); err != nil {
+		return nil, errors.Wrap(err, "removing previous version")
+	}
+	return keys, errors.Wrap(os.Rename(tmp, target), "installing mod")
+}
+
+// keys copies the bikeys of the mod in dir to KeysDir returning their names
+func (s *Syncer) keys(dir string) ([]string, error) {
+	if s.KeysDir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(s.KeysDir, 0755); err != nil {
+		return nil, errors.Wrap(err, "creating keys dir")
+	}
+	var keys []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".bikey" {
+			return err
+		}
+		keys = append(keys, info.Name())
+		return fileutil.CopyFile(path, filepath.Join(s.KeysDir, info.Name()), fileutil.Options{Replace: true})
+	})
+	return keys, err
+}
+
+// prune the state of items which are no longer synced, removing their keys. The caller has to hold the lock
+func (s *Syncer) prune(ctx context.Context, state map[string]installed) {
+	synced := make(map[string]bool)
+	for _, i := range s.Items {
+		synced[i.ID] = true
+	}
+	for id, i := range state {
+		if synced[id] {
+			continue
+		}
+		delete(state, id)
+		s.removeKeys(ctx, state, i.Keys)
+	}
+}
+
+// removeKeys from KeysDir unless they are still installed by one of the items in state
+// Otherwise clients could keep joining with mods the server no longer runs
+func (s *Syncer) removeKeys(ctx context.Context, state map[string]installed, keys []string) {
+	used := make(map[string]bool)
+	for _, i := range state {
+		for _, k := range i.Keys {
+			used[k] = true
+		}
+	}
+	for _, k := range keys {
+		if used[k] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.KeysDir, k)); err != nil && !os.IsNotExist(err) {
+			log.From(ctx).Error("removing stale key", zap.String("key", k), zap.Error(err))
+			continue
+		}
+		log.From(ctx).Info("removed stale key", zap.String("key", k))
+	}
+}
+
+// ModArg returns the -mod arg loading all Items which are no server mods
+func (s *Syncer) ModArg() string {
+	return s.arg("-mod=", false)
+}
+
+// ServerModArg returns the -serverMod arg loading all server mods of Items
+func (s *Syncer) ServerModArg() string {
+	return s.arg("-serverMod=", true)
+}
+
+func (s *Syncer) arg(prefix string, server bool) string {
+	var mods []string
+	for _, i := range s.Items {
+		if i.Server == server {
+			mods = append(mods, filepath.Join(s.ModsDir, i.dir()))
+		}
+	}
+	if len(mods) == 0 {
+		return ""
+	}
+	return prefix + strings.Join(mods, ";")
+}
+
+// Args returns args with the -mod and -serverMod args replaced by the ones of Items
+func (s *Syncer) Args(args []string) []string {
+	var out []string
+	for _, a := range args {
+		l := strings.ToLower(a)
+		if strings.HasPrefix(l, "-mod=") || strings.HasPrefix(l, "-servermod=") {
+			continue
+		}
+		out = append(out, a)
+	}
+	for _, a := range []string{s.ModArg(), s.ServerModArg()} {
+		if a != "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// Prepare syncs the Items and sets the mod args of Process. It is meant to be used as Watcher.Prepare
+// The args are set even if the sync failed, so the server still starts using the installed versions
+func (s *Syncer) Prepare(ctx context.Context) error {
+	_, err := s.Sync(ctx)
+	if s.Process != nil {
+		s.Process.Cmd.Args = s.Args(s.Process.Cmd.Args)
+	}
+	return err
+}
+
+// installed item as recorded in the state
+type installed struct {
+	// Sum is the fingerprint of the installed files
+	Sum string `json:"sum"`
+	// Keys copied to KeysDir
+	Keys []string `json:"keys,omitempty"`
+}
+
+// load the installed items by id. The caller has to hold the lock
+func (s *Syncer) load() (map[string]installed, error) {
+	state := make(map[string]installed)
+	data, err := ioutil.ReadFile(filepath.Join(s.ModsDir, ".workshop.json"))
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading state")
+	}
+	return state, errors.Wrap(json.Unmarshal(data, &state), "decoding state")
+}
+
+// save the installed items by id. The caller has to hold the lock
+func (s *Syncer) save(state map[string]installed) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "encoding state")
+	}
+	return errors.Wrap(ioutil.WriteFile(filepath.Join(s.ModsDir, ".workshop.json"), data, 0644), "writing state")
+}
+
+// fingerprint of the files in dir by their names, sizes and modification times
+func fingerprint(dir string) (string, error) {
+	h := sha256.New()
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		fmt.Fprintf(h, "%s %d %d %t\n", filepath.ToSlash(rel), info.Size(), info.ModTime().UnixNano(), info.IsDir())
+		return nil
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "reading item")
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// tail returns the last lines of steamcmd output for errors
+func tail(out string) string {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) > 3 {
+		lines = lines[len(lines)-3:]
+	}
+	return strings.Join(lines, " ")
+}
@@ -0,0 +1,179 @@
+package workshop_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/playnet-public/gorcon/pkg/watcher"
+	"github.com/playnet-public/gorcon/pkg/workshop"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/seibert-media/golibs/log"
+)
+
+func TestWorkshop(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Workshop Suite")
+}
+
+// fakeSteamCMD downloads items by copying them from the upstream dir, failing for items without one
+const fakeSteamCMD = `#!/bin/sh
+dir=""
+while [ $# -gt 0 ]; do
+	case "$1" in
+	+force_install_dir) dir="$2"; shift 2 ;;
+	+login) shift 2 ;;
+	+workshop_download_item)
+		target="$dir/steamapps/workshop/content/$2/$3"
+		if [ -d "UPSTREAM/$3" ]; then
+			rm -rf "$target" && mkdir -p "$target" && cp -pR "UPSTREAM/$3/." "$target"
+			echo "Success. Downloaded item $3 to \"$target\" (42 bytes)"
+		else
+			echo "ERROR! Download item $3 failed (File Not Found)."
+		fi
+		shift 4 ;;
+	*) shift ;;
+	esac
+done
+`
+
+var _ = Describe("Syncer", func() {
+	var (
+		ctx      context.Context
+		tmp      string
+		upstream string
+		s        *workshop.Syncer
+	)
+
+	write := func(path, data string) {
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(BeNil())
+		Expect(ioutil.WriteFile(path, []byte(data), 0644)).To(BeNil())
+	}
+	read := func(path string) string {
+		data, err := ioutil.ReadFile(path)
+		Expect(err).To(BeNil())
+		return string(data)
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "workshop")
+		Expect(err).To(BeNil())
+		upstream = filepath.Join(tmp, "upstream")
+		steamcmd := filepath.Join(tmp, "steamcmd.sh")
+		Expect(ioutil.WriteFile(steamcmd, []byte(strings.Replace(fakeSteamCMD, "UPSTREAM", upstream, -1)), 0755)).To(BeNil())
+
+		write(filepath.Join(upstream, "450814997", "Addons", "CBA_Main.pbo"), "cba")
+		write(filepath.Join(upstream, "450814997", "Keys", "CBA_A3.bikey"), "cba key")
+		write(filepath.Join(upstream, "1375890861", "addons", "Server.pbo"), "server")
+
+		ctx = log.WithLogger(context.Background(), log.NewNop())
+		s = workshop.NewSyncer(steamcmd, filepath.Join(tmp, "mods"), filepath.Join(tmp, "keys"),
+			workshop.Item{ID: "450814997", Name: "CBA_A3"},
+			workshop.Item{ID: "1375890861", Server: true},
+		)
+	})
+	AfterEach(func() {
+		os.RemoveAll(tmp)
+	})
+
+	Describe("Sync", func() {
+		It("does install items with lowercase names and keys", func() {
+			res, err := s.Sync(ctx)
+			Expect(err).To(BeNil())
+			Expect(res.Changed).To(Equal([]string{"@cba_a3", "@1375890861"}))
+			Expect(read(filepath.Join(tmp, "mods", "@cba_a3", "addons", "cba_main.pbo"))).To(Equal("cba"))
+			Expect(read(filepath.Join(tmp, "mods", "@1375890861", "addons", "server.pbo"))).To(Equal("server"))
+			Expect(read(filepath.Join(tmp, "keys", "cba_a3.bikey"))).To(Equal("cba key"))
+		})
+		It("does only install changed items", func() {
+			_, err := s.Sync(ctx)
+			Expect(err).To(BeNil())
+			res, err := s.Sync(ctx)
+			Expect(err).To(BeNil())
+			Expect(res.Changed).To(BeEmpty())
+
+			write(filepath.Join(upstream, "450814997", "Addons", "CBA_Main.pbo"), "cba updated")
+			res, err = s.Sync(ctx)
+			Expect(err).To(BeNil())
+			Expect(res.Changed).To(Equal([]string{"@cba_a3"}))
+			Expect(read(filepath.Join(tmp, "mods", "@cba_a3", "addons", "cba_main.pbo"))).To(Equal("cba updated"))
+		})
+		It("does keep installed versions of failed items", func() {
+			_, err := s.Sync(ctx)
+			Expect(err).To(BeNil())
+			Expect(os.RemoveAll(filepath.Join(upstream, "450814997"))).To(BeNil())
+			write(filepath.Join(upstream, "1375890861", "addons", "Server.pbo"), "server updated")
+
+			res, err := s.Sync(ctx)
+			Expect(err).NotTo(BeNil())
+			Expect(res.Failed).To(Equal(map[string]string{"450814997": "File Not Found"}))
+			Expect(res.Changed).To(Equal([]string{"@1375890861"}))
+			Expect(read(filepath.Join(tmp, "mods", "@cba_a3", "addons", "cba_main.pbo"))).To(Equal("cba"))
+		})
+		It("does remove keys no longer shipped by updated items", func() {
+			_, err := s.Sync(ctx)
+			Expect(err).To(BeNil())
+			Expect(os.Remove(filepath.Join(upstream, "450814997", "Keys", "CBA_A3.bikey"))).To(BeNil())
+			write(filepath.Join(upstream, "450814997", "Keys", "CBA_A3_v2.bikey"), "cba key v2")
+
+			_, err = s.Sync(ctx)
+			Expect(err).To(BeNil())
+			Expect(filepath.Join(tmp, "keys", "cba_a3.bikey")).NotTo(BeAnExistingFile())
+			Expect(read(filepath.Join(tmp, "keys", "cba_a3_v2.bikey"))).To(Equal("cba key v2"))
+		})
+		It("does remove keys of removed items", func() {
+			_, err := s.Sync(ctx)
+			Expect(err).To(BeNil())
+			s.Items = s.Items[1:]
+
+			_, err = s.Sync(ctx)
+			Expect(err).To(BeNil())
+			Expect(filepath.Join(tmp, "keys", "cba_a3.bikey")).NotTo(BeAnExistingFile())
+			s.Items = nil
+			_, err = s.Sync(ctx)
+			Expect(err).To(BeNil())
+		})
+		It("does keep keys shipped by other items", func() {
+			write(filepath.Join(upstream, "1375890861", "keys", "cba_a3.bikey"), "cba key")
+			_, err := s.Sync(ctx)
+			Expect(err).To(BeNil())
+			s.Items = s.Items[1:]
+
+			_, err = s.Sync(ctx)
+			Expect(err).To(BeNil())
+			Expect(read(filepath.Join(tmp, "keys", "cba_a3.bikey"))).To(Equal("cba key"))
+		})
+		It("does return errors of steamcmd", func() {
+			s.SteamCMD = filepath.Join(tmp, "missing")
+			_, err := s.Sync(ctx)
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
+	Describe("Prepare", func() {
+		It("does set the mod args of the process", func() {
+			p := watcher.NewOSProcess("arma3server", "-port=2302", "-mod=old", "-serverMod=old")
+			s.Process = p
+			Expect(s.Prepare(ctx)).To(BeNil())
+			Expect(p.Cmd.Args).To(Equal([]string{
+				"arma3server", "-port=2302",
+				"-mod=" + filepath.Join(tmp, "mods", "@cba_a3"),
+				"-serverMod=" + filepath.Join(tmp, "mods", "@1375890861"),
+			}))
+		})
+	})
+
+	Describe("ModArg", func() {
+		It("does join the mods in order", func() {
+			s.Items = append(s.Items, workshop.Item{ID: "463939057", Name: "ACE"})
+			Expect(s.ModArg()).To(Equal("-mod=" + filepath.Join(tmp, "mods", "@cba_a3") + ";" + filepath.Join(tmp, "mods", "@ace")))
+			Expect(s.ServerModArg()).To(Equal("-serverMod=" + filepath.Join(tmp, "mods", "@1375890861")))
+		})
+	})
+})